@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	pathpkg "path"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -15,43 +16,9 @@ var (
 	addType string
 	addID   int
 	addName string
+	addDraft bool
 )
 
-// Asset type mapping based on ID
-var assetTypeMap = map[int]string{
-	1000624: "flash_image",
-	1030002: "string",
-	1010042: "loading_screen",
-	1000083: "xml_treasure",
-	1000087: "xml_zone_transition",
-	1000090: "xml_resurrection",
-	1000635: "usm_video",
-	1000636: "image",
-	1070003: "playfield",
-	1010013: "map",
-	1010210: "image",
-	1010211: "image",
-	1000623: "text",
-	1066603: "texture",
-	1020001: "unknown",
-	1020002: "sound_effect",
-	1020005: "music",
-	1020006: "sound_tone",
-	1010207: "particle_effect",
-	1000010: "file_index",
-	1000007: "physx_xml",
-	1020003: "dialog_audio",
-	1010008: "misc_image",
-}
-
-// getAssetTypeFromID returns the asset type for a given ID
-func getAssetTypeFromID(id int) string {
-	if assetType, exists := assetTypeMap[id]; exists {
-		return assetType
-	}
-	return "unknown"
-}
-
 // addCmd represents the add command
 var addCmd = &cobra.Command{
 	Use:   "add",
@@ -60,10 +27,12 @@ var addCmd = &cobra.Command{
 
 If metadata is missing, can create meta.json with the specified type, id, and name.
 Asset type is automatically determined from the folder ID if not specified.
+Draft assets are excluded from 'rdb build' unless --include-drafts is given.
 
 Examples:
   rdb add .\assets\1030002\ --id 1030002 --name "DialogLine_Intro"
-  rdb add .\assets\42001\music.mp3 --id 42001`,
+  rdb add .\assets\42001\music.mp3 --id 42001
+  rdb add .\assets\1030002\ --draft`,
 	RunE: runAdd,
 }
 
@@ -74,6 +43,7 @@ func init() {
 	addCmd.Flags().StringVar(&addType, "type", "", "asset type (optional, auto-determined from ID)")
 	addCmd.Flags().IntVar(&addID, "id", 0, "asset ID")
 	addCmd.Flags().StringVar(&addName, "name", "", "asset name")
+	addCmd.Flags().BoolVar(&addDraft, "draft", false, "mark the asset as a draft (--draft=false marks it ready)")
 }
 
 func runAdd(cmd *cobra.Command, args []string) error {
@@ -107,6 +77,7 @@ func runAdd(cmd *cobra.Command, args []string) error {
 	}
 	
 	// Process each path, expanding glob patterns
+	ignore := r.NewIgnoreMatcher()
 	var allPaths []string
 	for _, pattern := range args {
 		matches, err := filepath.Glob(pattern)
@@ -125,8 +96,10 @@ func runAdd(cmd *cobra.Command, args []string) error {
 				fmt.Printf("Warning: could not resolve path %s: %v\n", match, err)
 				continue
 			}
-			if isAssetPath(r.Path, absMatch) {
+			if isAssetPath(r, absMatch, ignore) {
 				allPaths = append(allPaths, match)
+			} else if isAssetPath(r, absMatch, nil) {
+				fmt.Printf("Skipping ignored path: %s\n", match)
 			} else {
 				fmt.Printf("Skipping non-asset path: %s\n", match)
 			}
@@ -139,16 +112,40 @@ func runAdd(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 	
+	// Hold the index for the whole update so concurrent rdb runs do not lose changes
+	lock, err := r.LockIndex()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	
+	// Load staging index
+	idx, err := r.LoadIndex()
+	if err != nil {
+		return fmt.Errorf("failed to load index: %w", err)
+	}
+	
+	// Only touch draft status when --draft was given
+	var draft *bool
+	if cmd.Flags().Changed("draft") {
+		draft = &addDraft
+	}
+	
 	for _, path := range allPaths {
-		if err := addPath(r, path, addType, addID, addName); err != nil {
+		if err := addPath(r, idx, path, addType, addID, addName, draft); err != nil {
 			return fmt.Errorf("failed to add %s: %w", path, err)
 		}
 	}
 	
+	// Persist staging index
+	if err := r.SaveIndex(idx); err != nil {
+		return fmt.Errorf("failed to save index: %w", err)
+	}
+	
 	return nil
 }
 
-func addPath(r *repo.Repository, path, assetType string, assetID int, assetName string) error {
+func addPath(r *repo.Repository, idx *repo.Index, path, assetType string, assetID int, assetName string, draft *bool) error {
 	// Convert to absolute path
 	absPath, err := filepath.Abs(path)
 	if err != nil {
@@ -163,14 +160,15 @@ func addPath(r *repo.Repository, path, assetType string, assetID int, assetName
 	
 	// Determine asset type and ID from path if not specified
 	if assetType == "" || assetID == 0 {
-		relPath, err := filepath.Rel(r.Path, absPath)
+		relPath, err := r.RelPath(absPath)
 		if err != nil {
-			return fmt.Errorf("failed to get relative path: %w", err)
+			return err
 		}
+		treePath := r.Layout().TreePath(relPath)
 		
 		// Parse path to extract type and ID
-		// Expected format: assets/<id>/...
-		parts := strings.Split(relPath, string(filepath.Separator))
+		// Expected format: assets/<id>/... (assets/<id>.<file> in the flat layout)
+		parts := strings.Split(treePath, "/")
 		if len(parts) >= 2 && parts[0] == "assets" {
 			if assetID == 0 {
 				if id, err := strconv.Atoi(parts[1]); err == nil {
@@ -181,10 +179,9 @@ func addPath(r *repo.Repository, path, assetType string, assetID int, assetName
 		
 		// If we still don't have type/ID, try to find the containing asset directory
 		if assetType == "" || assetID == 0 {
-			assetDir := findAssetDirectory(r.Path, absPath)
+			assetDir := findAssetDirectory(r, treePath)
 			if assetDir != "" {
-				relAssetPath, _ := filepath.Rel(r.Path, assetDir)
-				assetParts := strings.Split(relAssetPath, string(filepath.Separator))
+				assetParts := strings.Split(assetDir, "/")
 				if len(assetParts) >= 2 && assetParts[0] == "assets" {
 					if assetID == 0 {
 						if id, err := strconv.Atoi(assetParts[1]); err == nil {
@@ -198,7 +195,11 @@ func addPath(r *repo.Repository, path, assetType string, assetID int, assetName
 	
 	// Automatically determine asset type from ID if not specified
 	if assetType == "" && assetID != 0 {
-		assetType = getAssetTypeFromID(assetID)
+		registry, err := r.LoadTypes()
+		if err != nil {
+			return err
+		}
+		assetType = registry.Slug(assetID)
 	}
 	
 	// Validate asset type
@@ -207,130 +208,246 @@ func addPath(r *repo.Repository, path, assetType string, assetID int, assetName
 	}
 	
 	// Create or update metadata
-	if err := createOrUpdateMetadata(r, absPath, assetType, assetID, assetName); err != nil {
+	assetDir, err := createOrUpdateMetadata(r, absPath, assetType, assetID, assetName)
+	if err != nil {
 		return fmt.Errorf("failed to create/update metadata: %w", err)
 	}
 	
-	fmt.Printf("Added %s (type: %s, id: %d)\n", path, assetType, assetID)
+	// Apply draft status only when the flag was given explicitly
+	if draft != nil {
+		changed, err := repo.SetAssetDraftFile(r.AssetMetaPath(assetDir), *draft)
+		if err != nil {
+			return fmt.Errorf("failed to update draft status: %w", err)
+		}
+		if changed && *draft {
+			fmt.Printf("Marked %s as draft\n", assetDir)
+		} else if changed {
+			fmt.Printf("Marked %s as ready\n", assetDir)
+		}
+	}
+	
+	// Stage payload files together with the asset's meta.json
+	staged, err := stagePath(r, idx, absPath, assetDir)
+	if err != nil {
+		return fmt.Errorf("failed to stage files: %w", err)
+	}
+	
+	fmt.Printf("Added %s (type: %s, id: %d, %d file(s) staged)\n", path, assetType, assetID, staged)
 	
 	return nil
 }
 
-func createOrUpdateMetadata(r *repo.Repository, path, assetType string, assetID int, assetName string) error {
-	// Determine the asset directory
-	_, err := filepath.Rel(r.Path, path)
+// createOrUpdateMetadata writes or merges meta.json for the asset containing path
+// and returns the asset's canonical directory (for example "assets/1030002")
+func createOrUpdateMetadata(r *repo.Repository, path, assetType string, assetID int, assetName string) (string, error) {
+	info, err := os.Stat(path)
 	if err != nil {
-		return fmt.Errorf("failed to get relative path: %w", err)
+		return "", fmt.Errorf("failed to stat path: %w", err)
 	}
-	
-	// If path is a file, find its containing asset directory
-	_, err = os.Stat(path)
+	relPath, err := r.RelPath(path)
 	if err != nil {
-		return fmt.Errorf("failed to stat path: %w", err)
+		return "", err
 	}
 	
-	var assetDir string
-	// For now, assume it's a directory
-	assetDir = path
-	
-	// Find the asset directory containing this file
-	// Look for a directory with meta.json or create one
-	dir := filepath.Dir(path)
-	for {
-		if dir == r.Path {
-			break
-		}
-		
-		metaPath := filepath.Join(dir, "meta.json")
-		if _, err := os.Stat(metaPath); err == nil {
-			assetDir = dir
-			break
-		}
-		
-		parent := filepath.Dir(dir)
-		if parent == dir {
-			break
-		}
-		dir = parent
+	// Find the asset directory containing this path; a directory may itself be the asset
+	probe := r.Layout().TreePath(relPath)
+	if info.IsDir() {
+		probe += "/" + repo.MetaFileName
 	}
+	assetDir := findAssetDirectory(r, probe)
 	
 	if assetDir == "" {
 		// Create asset directory based on ID
-		assetDir = filepath.Join(r.Path, "assets", strconv.Itoa(assetID))
-		if err := os.MkdirAll(assetDir, 0755); err != nil {
-			return fmt.Errorf("failed to create asset directory: %w", err)
+		if assetID == 0 {
+			return "", fmt.Errorf("could not determine asset directory for %s", path)
 		}
+		assetDir = "assets/" + strconv.Itoa(assetID)
 	}
 	
-	// Create or update meta.json
-	metaPath := filepath.Join(assetDir, "meta.json")
+	metaPath := r.AssetMetaPath(assetDir)
+	written, err := repo.UpdateAssetMetaFile(metaPath, assetType, assetID, assetName)
+	if err != nil {
+		return "", err
+	}
+	if written {
+		fmt.Printf("Updated metadata at %s\n", metaPath)
+	}
 	
-	// TODO: Implement metadata creation/update logic
-	// For now, just create a basic metadata file
-	metadata := map[string]interface{}{
-		"type": assetType,
-		"id":   assetID,
+	return assetDir, nil
+}
+
+// stagePath hashes every file at or below path into the object store and records
+// it in the index, skipping paths matched by .rdbignore. Index entries under a
+// staged directory whose files no longer exist, or are now ignored, are removed. It returns the number of files staged.
+func stagePath(r *repo.Repository, idx *repo.Index, path, assetDir string) (int, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat path: %w", err)
 	}
 	
-	if assetName != "" {
-		metadata["name"] = assetName
+	ignore := r.NewIgnoreMatcher()
+	var files []string
+	if info.IsDir() {
+		err = filepath.WalkDir(path, func(p string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			relPath, err := r.RelPath(p)
+			if err != nil {
+				return err
+			}
+			if p != path && ignore.Match(relPath, d.IsDir()) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.IsDir() {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to walk %s: %w", path, err)
+		}
+	} else {
+		files = append(files, path)
+	}
+	
+	// The asset's metadata must be valid and allow every payload file
+	metaPath := r.AssetMetaPath(assetDir)
+	data, err := os.ReadFile(metaPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", metaPath, err)
+	}
+	if err := validateMeta(r, metaPath, data); err != nil {
+		return 0, err
+	}
+	if err := checkExtensions(r, metaPath, files); err != nil {
+		return 0, err
 	}
 	
-	// TODO: Write metadata to file
-	fmt.Printf("Would create/update metadata at %s\n", metaPath)
+	// Always include the asset's metadata
+	files = append(files, metaPath)
 	
-	return nil
-}
-
-// findAssetDirectory finds the containing asset directory for a given path
-func findAssetDirectory(repoPath, filePath string) string {
-	dir := filepath.Dir(filePath)
-	for {
-		if dir == repoPath {
-			break
+	seen := make(map[string]bool)
+	for _, file := range files {
+		relPath, err := r.RelPath(file)
+		if err != nil {
+			return 0, err
 		}
-		
-		// Check if this directory has a meta.json file
-		metaPath := filepath.Join(dir, "meta.json")
-		if _, err := os.Stat(metaPath); err == nil {
-			return dir
+		if seen[relPath] {
+			continue
 		}
+		seen[relPath] = true
 		
-		// Check if this directory follows the assets/<id> pattern
-		relPath, err := filepath.Rel(repoPath, dir)
-		if err == nil {
-			parts := strings.Split(relPath, string(filepath.Separator))
-			if len(parts) >= 2 && parts[0] == "assets" {
-				if _, err := strconv.Atoi(parts[1]); err == nil {
-					return dir
-				}
+		entry, err := r.StageFile(relPath)
+		if err != nil {
+			return 0, err
+		}
+		idx.Add(entry)
+	}
+	
+	// Drop entries for files deleted from a staged directory
+	if info.IsDir() {
+		relDir, err := r.RelPath(path)
+		if err != nil {
+			return 0, err
+		}
+		for _, entry := range idx.EntriesUnder(relDir) {
+			if !seen[entry.Path] {
+				idx.Remove(entry.Path)
+				fmt.Printf("Removed %s\n", entry.Path)
 			}
 		}
-		
-		parent := filepath.Dir(dir)
-		if parent == dir {
-			break
+	}
+	
+	return len(seen), nil
+}
+
+// findAssetDirectory finds the canonical directory of the asset containing the canonical treePath
+func findAssetDirectory(r *repo.Repository, treePath string) string {
+	// Files under assets/<id>/, including subfolders, belong to that asset
+	if dir := repo.AssetDir(treePath); dir != "" {
+		return dir
+	}
+	
+	// Elsewhere, look for the nearest directory with a meta.json file
+	dir := pathpkg.Dir(treePath)
+	for dir != "." && dir != "/" {
+		if _, err := os.Stat(r.AssetMetaPath(dir)); err == nil {
+			return dir
 		}
-		dir = parent
+		dir = pathpkg.Dir(dir)
 	}
 	
 	return ""
 }
 
-// isAssetPath checks if a path is within the assets directory structure
-func isAssetPath(repoPath, filePath string) bool {
-	relPath, err := filepath.Rel(repoPath, filePath)
+// checkExtensions verifies that every file is allowed by the registered type of the asset described by metaPath
+func checkExtensions(r *repo.Repository, metaPath string, files []string) error {
+	asset, err := repo.ReadAssetMetaFile(metaPath)
+	if err != nil {
+		return err
+	}
+	registry, err := r.LoadTypes()
+	if err != nil {
+		return err
+	}
+	assetType, ok := registry.Resolve(asset)
+	if !ok {
+		return nil
+	}
+	
+	var rejected []string
+	for _, file := range files {
+		relPath, err := r.RelPath(file)
+		if err != nil {
+			return err
+		}
+		if !assetType.AllowsFile(r.Layout().TreePath(relPath)) {
+			rejected = append(rejected, relPath)
+		}
+	}
+	if len(rejected) > 0 {
+		return fmt.Errorf("type %s (%d) only allows %s files: %s",
+			assetType.Slug, assetType.ID, strings.Join(assetType.Extensions, ", "), strings.Join(rejected, ", "))
+	}
+	
+	return nil
+}
+
+// validateMeta checks meta.json content against the base and type schemas
+func validateMeta(r *repo.Repository, metaPath string, data []byte) error {
+	registry, err := r.LoadTypes()
+	if err != nil {
+		return err
+	}
+	relPath, err := r.RelPath(metaPath)
+	if err != nil {
+		return err
+	}
+	if errs := r.ValidateAssetMeta(registry, relPath, data); len(errs) > 0 {
+		return repo.ValidationErrors(errs)
+	}
+	return nil
+}
+
+// isAssetPath checks if a path is within the assets directory structure and,
+// when ignore is given, not excluded by .rdbignore rules
+func isAssetPath(r *repo.Repository, filePath string, ignore *repo.IgnoreMatcher) bool {
+	relPath, err := r.RelPath(filePath)
 	if err != nil {
 		return false
 	}
 	
-	parts := strings.Split(relPath, string(filepath.Separator))
+	parts := strings.Split(r.Layout().TreePath(relPath), "/")
 	
 	if len(parts) < 2 {
 		return false
 	}
 	
-	// Must be under assets/<id>/...
+	// Must be under assets/<id>/... (assets/<id>.<file> in the flat layout)
 	if parts[0] != "assets" {
 		return false
 	}
@@ -340,5 +457,13 @@ func isAssetPath(repoPath, filePath string) bool {
 		return false
 	}
 	
+	// Check ignore rules
+	if ignore != nil {
+		info, err := os.Stat(filePath)
+		if ignore.Match(relPath, err == nil && info.IsDir()) {
+			return false
+		}
+	}
+	
 	return true
 } 
\ No newline at end of file
@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/rdb/cli/internal/repo"
+	"github.com/spf13/cobra"
+)
+
+var (
+	branchDelete      bool
+	branchForceDelete bool
+	branchMove        bool
+)
+
+// branchCmd represents the branch command
+var branchCmd = &cobra.Command{
+	Use:   "branch [<name> [<start-commit>]]",
+	Short: "List, create, rename or delete branches",
+	Long: `List, create, rename or delete branches.
+
+Without arguments, lists all branches and marks the current one with *.
+With a name, creates a new branch at the current commit (or at start-commit).
+
+Examples:
+  rdb branch
+  rdb branch winter-event
+  rdb branch -m winter-event winter-2024
+  rdb branch -d winter-2024
+  rdb branch -D abandoned-experiment`,
+	RunE: runBranch,
+}
+
+func init() {
+	rootCmd.AddCommand(branchCmd)
+	
+	// Local flags
+	branchCmd.Flags().BoolVarP(&branchDelete, "delete", "d", false, "delete a fully merged branch")
+	branchCmd.Flags().BoolVarP(&branchForceDelete, "force-delete", "D", false, "delete a branch even if it is not merged")
+	branchCmd.Flags().BoolVarP(&branchMove, "move", "m", false, "rename a branch")
+}
+
+func runBranch(cmd *cobra.Command, args []string) error {
+	// Always use current working directory
+	repoPath := "."
+	
+	// Convert to absolute path
+	absPath, err := filepath.Abs(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve path: %w", err)
+	}
+	
+	// Safety check: prevent operations in system directories
+	if strings.Contains(strings.ToLower(absPath), "c:\\windows\\system32") {
+		return fmt.Errorf("cannot operate on RDB repository in system directory: %s", absPath)
+	}
+	
+	// Check if repository exists
+	if !repo.IsRepository(absPath) {
+		return fmt.Errorf("not an RDB repository: %s", absPath)
+	}
+	
+	// Open repository
+	r, err := repo.OpenRepository(absPath)
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+	
+	switch {
+	case branchDelete || branchForceDelete:
+		if len(args) == 0 {
+			return fmt.Errorf("branch name required")
+		}
+		for _, name := range args {
+			if err := deleteBranch(r, name, branchForceDelete); err != nil {
+				return err
+			}
+		}
+		return nil
+		
+	case branchMove:
+		var oldName, newName string
+		switch len(args) {
+		case 1:
+			oldName, err = r.GetCurrentBranch()
+			if err != nil {
+				return fmt.Errorf("failed to get current branch: %w", err)
+			}
+			newName = args[0]
+		case 2:
+			oldName, newName = args[0], args[1]
+		default:
+			return fmt.Errorf("usage: rdb branch -m [<old>] <new>")
+		}
+		if err := r.RenameBranch(oldName, newName); err != nil {
+			return fmt.Errorf("failed to rename branch: %w", err)
+		}
+		fmt.Printf("Renamed branch %s to %s\n", oldName, newName)
+		return nil
+		
+	case len(args) > 0:
+		if len(args) > 2 {
+			return fmt.Errorf("too many arguments")
+		}
+		startCommit, err := r.GetCurrentCommit()
+		if err != nil {
+			return fmt.Errorf("failed to get current commit: %w", err)
+		}
+		if len(args) == 2 {
+			startCommit, err = r.ResolveRevision(args[1])
+			if err != nil {
+				return err
+			}
+		}
+		if err := r.CreateBranch(args[0], startCommit); err != nil {
+			return fmt.Errorf("failed to create branch: %w", err)
+		}
+		fmt.Printf("Created branch %s at %s\n", args[0], startCommit[:8])
+		return nil
+	}
+	
+	return listBranches(r)
+}
+
+func listBranches(r *repo.Repository) error {
+	branches, err := r.ListBranches()
+	if err != nil {
+		return err
+	}
+	
+	current, _ := r.GetCurrentBranch()
+	for _, name := range branches {
+		marker := " "
+		if name == current {
+			marker = "*"
+		}
+		
+		tip, err := r.ReadBranch(name)
+		if err != nil {
+			return err
+		}
+		
+		summary := ""
+		if commit, err := r.ReadCommit(tip); err == nil {
+			summary = commit.Message
+		}
+		
+		fmt.Printf("%s %-20s %s %s\n", marker, name, tip[:8], summary)
+	}
+	
+	return nil
+}
+
+func deleteBranch(r *repo.Repository, name string, force bool) error {
+	tip, err := r.ReadBranch(name)
+	if err != nil {
+		return err
+	}
+	
+	if !force {
+		head, err := r.GetCurrentCommit()
+		if err != nil {
+			return fmt.Errorf("failed to get current commit: %w", err)
+		}
+		merged, err := r.IsAncestor(tip, head)
+		if err != nil {
+			return fmt.Errorf("failed to check branch history: %w", err)
+		}
+		if !merged {
+			return fmt.Errorf("branch %s is not fully merged (use -D to delete it anyway)", name)
+		}
+	}
+	
+	if err := r.DeleteBranch(name); err != nil {
+		return fmt.Errorf("failed to delete branch: %w", err)
+	}
+	
+	fmt.Printf("Deleted branch %s (was %s)\n", name, tip[:8])
+	return nil
+}
@@ -4,7 +4,9 @@ import (
 	"archive/zip"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"time"
@@ -17,18 +19,36 @@ var (
 	buildOutput      string
 	buildIncludeDrafts bool
 	buildCompression string
+	buildRev         string
 )
 
 // buildCmd represents the build command
 var buildCmd = &cobra.Command{
 	Use:   "build",
 	Short: "Create .rdbdata package",
-	Long: `Create a .rdbdata ZIP package from the current commit.
+	Long: `Create a .rdbdata ZIP package from the current commit, or from the
+revision given with --rev: a branch or tag, a commit hash or prefix, HEAD~<n>,
+<branch>@{<date>} and the like. --rev asset:<id>@<rev> packages only that asset
+as of <rev>. A package built from a tag records the tag in its manifest.
+
+The package contains rdb-manifest.json, which lists every asset with its
+metadata, payload paths and ETag, and one entry per referenced blob stored at
+objects/<first two hash characters>/<remaining hash characters>.
+
+Files matched by the .rdbignore rules committed in the packaged revision are
+not packaged.
+
+Assets whose meta.json has "status": "draft" are left out unless
+--include-drafts is given; included drafts are listed in the manifest.
 
 Examples:
   rdb build
   rdb build --out my-package.rdbdata
-  rdb build --include-drafts --compression deflate`,
+  rdb build --include-drafts --compression deflate
+  rdb build --rev v1.2
+  rdb build --rev HEAD~1
+  rdb build --rev "main@{yesterday}"
+  rdb build --rev asset:1030002@3f2a9c`,
 	RunE: runBuild,
 }
 
@@ -39,6 +59,7 @@ func init() {
 	buildCmd.Flags().StringVar(&buildOutput, "out", "", "output file (default: ./dist/<repo-name>-<branch>-<short-commit>.rdbdata)")
 	buildCmd.Flags().BoolVar(&buildIncludeDrafts, "include-drafts", false, "include draft assets")
 	buildCmd.Flags().StringVar(&buildCompression, "compression", "store", "compression method (store or deflate)")
+	buildCmd.Flags().StringVar(&buildRev, "rev", "", "revision to package (default: HEAD)")
 }
 
 func runBuild(cmd *cobra.Command, args []string) error {
@@ -73,17 +94,41 @@ func runBuild(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to get current branch: %w", err)
 	}
 	
-	commit, err := r.GetCurrentCommit()
-	if err != nil {
-		return fmt.Errorf("failed to get current commit: %w", err)
+	// Resolve the revision to package; the file name carries its label
+	rev := &repo.Revision{}
+	label := branch
+	tag := ""
+	if buildRev == "" {
+		rev.Commit, err = r.GetCurrentCommit()
+		if err != nil {
+			return fmt.Errorf("failed to get current commit: %w", err)
+		}
+	} else {
+		rev, err = r.ParseRevision(buildRev)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", buildRev, err)
+		}
+		label = revisionLabel(buildRev)
+		if r.BranchExists(buildRev) {
+			branch = buildRev
+		} else {
+			if r.TagExists(buildRev) {
+				tag = buildRev
+			}
+			// Record the branch the commit was made on rather than the current one
+			if commit, err := r.ReadCommit(rev.Commit); err == nil && commit.Branch != "" {
+				branch = commit.Branch
+			}
+		}
 	}
+	commit := rev.Commit
 	
 	// Determine output file
 	outputFile := buildOutput
 	if outputFile == "" {
 		repoName := filepath.Base(r.Path)
 		shortCommit := commit[:8]
-		outputFile = fmt.Sprintf("./dist/%s-%s-%s.rdbdata", repoName, branch, shortCommit)
+		outputFile = fmt.Sprintf("./dist/%s-%s-%s.rdbdata", repoName, label, shortCommit)
 	}
 	
 	// Create output directory if needed
@@ -93,7 +138,7 @@ func runBuild(cmd *cobra.Command, args []string) error {
 	}
 	
 	// Create package
-	if err := createPackage(r, outputFile, commit, branch, buildIncludeDrafts, buildCompression); err != nil {
+	if err := createPackage(r, outputFile, commit, branch, tag, rev.Asset, buildIncludeDrafts, buildCompression); err != nil {
 		return fmt.Errorf("failed to create package: %w", err)
 	}
 	
@@ -108,12 +153,15 @@ type Manifest struct {
 	CreatedAt     time.Time `json:"createdAt"`
 	Commit        struct {
 		ID        string    `json:"id"`
+		Hash      string    `json:"hash"`
 		Author    string    `json:"author"`
 		Timestamp time.Time `json:"timestamp"`
 		Message   string    `json:"message"`
 		Branch    string    `json:"branch"`
 	} `json:"commit"`
+	Tag    string       `json:"tag,omitempty"` // tag the package was built from with --rev
 	Assets []AssetEntry `json:"assets"`
+	Drafts []int        `json:"drafts,omitempty"` // IDs of draft assets included via --include-drafts
 }
 
 // AssetEntry represents an asset in the manifest
@@ -121,12 +169,34 @@ type AssetEntry struct {
 	Type  string              `json:"type"`
 	ID    int                 `json:"id"`
 	Name  string              `json:"name,omitempty"`
+	Path  string              `json:"path,omitempty"` // asset directory in the repository
 	Paths []repo.AssetPath    `json:"paths,omitempty"`
 	Meta  interface{}         `json:"meta,omitempty"`
-	ETag  string              `json:"etag,omitempty"`
+	ETag  string              `json:"etag,omitempty"` // hash of the asset's tree; changes whenever any of its files change
+	Draft bool                `json:"draft,omitempty"`
+}
+
+// packageObjectPath returns where a blob is stored inside a package
+func packageObjectPath(hash string) string {
+	return "objects/" + hash[:2] + "/" + hash[2:]
 }
 
-func createPackage(r *repo.Repository, outputFile, commitHash, branch string, includeDrafts bool, compression string) error {
+// revisionLabel turns a revision expression into a string usable in a file name
+func revisionLabel(expr string) string {
+	label := strings.Map(func(c rune) rune {
+		if strings.ContainsRune(`/\:~^@{}*?"<> `, c) {
+			return '-'
+		}
+		return c
+	}, expr)
+	for strings.Contains(label, "--") {
+		label = strings.ReplaceAll(label, "--", "-")
+	}
+	return strings.Trim(label, "-")
+}
+
+// createPackage writes the package for a commit; a non-zero assetID limits it to that asset
+func createPackage(r *repo.Repository, outputFile, commitHash, branch, tag string, assetID int, includeDrafts bool, compression string) error {
 	// Create ZIP file
 	zipFile, err := os.Create(outputFile)
 	if err != nil {
@@ -170,13 +240,73 @@ func createPackage(r *repo.Repository, outputFile, commitHash, branch string, in
 	}
 	
 	manifest.Commit.ID = commit.ID
+	manifest.Commit.Hash = commitHash
 	manifest.Commit.Author = commit.Author
 	manifest.Commit.Timestamp = commit.Timestamp
 	manifest.Commit.Message = commit.Message
 	manifest.Commit.Branch = branch
+	manifest.Tag = tag
 	
-	// TODO: Add assets to manifest
-	// For now, create empty manifest
+	// Add assets to manifest
+	assets, err := r.ListAssets(commit.Tree)
+	if err != nil {
+		return fmt.Errorf("failed to list assets: %w", err)
+	}
+	
+	manifest.Assets = []AssetEntry{}
+	// Apply the ignore rules committed with the tree, not those in the working tree
+	ignore, err := r.NewTreeIgnoreMatcher(commit.Tree)
+	if err != nil {
+		return fmt.Errorf("failed to load ignore rules: %w", err)
+	}
+	objects := make(map[string]bool)
+	var objectOrder []string
+	skippedDrafts := 0
+	for _, asset := range assets {
+		if assetID != 0 && asset.Path != fmt.Sprintf("assets/%d", assetID) {
+			continue
+		}
+		if asset.Meta.IsDraft() {
+			if !includeDrafts {
+				skippedDrafts++
+				continue
+			}
+			manifest.Drafts = append(manifest.Drafts, asset.Meta.ID)
+		}
+		
+		var meta map[string]interface{}
+		if err := json.Unmarshal(asset.Raw, &meta); err != nil {
+			return fmt.Errorf("failed to parse metadata for %s: %w", asset.Path, err)
+		}
+		
+		// Leave out ignore files and anything matched by .rdbignore
+		var files []repo.AssetPath
+		for _, file := range asset.Files {
+			if path.Base(file.Logical) == repo.IgnoreFileName || ignore.Match(asset.Path+"/"+file.Logical, false) {
+				continue
+			}
+			files = append(files, file)
+		}
+		asset.Files = files
+		
+		manifest.Assets = append(manifest.Assets, AssetEntry{
+			Type:  asset.Meta.Type,
+			ID:    asset.Meta.ID,
+			Name:  asset.Meta.Name,
+			Path:  asset.Path,
+			Paths: asset.Files,
+			Meta:  meta,
+			ETag:  asset.Entry.Object,
+			Draft: asset.Meta.IsDraft(),
+		})
+		
+		for _, file := range asset.Files {
+			if !objects[file.Object] {
+				objects[file.Object] = true
+				objectOrder = append(objectOrder, file.Object)
+			}
+		}
+	}
 	
 	// Write manifest
 	manifestData, err := json.MarshalIndent(manifest, "", "  ")
@@ -199,8 +329,38 @@ func createPackage(r *repo.Repository, outputFile, commitHash, branch string, in
 		return fmt.Errorf("failed to write manifest: %w", err)
 	}
 	
-	// TODO: Copy objects to package
-	// For now, just create the basic structure
+	// Copy objects to package
+	for _, hash := range objectOrder {
+		blob, err := r.OpenBlob(hash)
+		if err != nil {
+			return fmt.Errorf("failed to read object %s: %w", hash, err)
+		}
+		
+		header := &zip.FileHeader{
+			Name:   packageObjectPath(hash),
+			Method: method,
+		}
+		header.SetModTime(commit.Timestamp)
+		
+		objectFile, err := zipWriter.CreateHeader(header)
+		if err != nil {
+			blob.Close()
+			return fmt.Errorf("failed to create object entry: %w", err)
+		}
+		_, err = io.Copy(objectFile, blob)
+		blob.Close()
+		if err != nil {
+			return fmt.Errorf("failed to write object %s: %w", hash, err)
+		}
+	}
+	
+	fmt.Printf("Packaged %d asset(s), %d object(s)\n", len(manifest.Assets), len(objectOrder))
+	if skippedDrafts > 0 {
+		fmt.Printf("Skipped %d draft asset(s) (use --include-drafts to package them)\n", skippedDrafts)
+	}
+	if len(manifest.Drafts) > 0 {
+		fmt.Printf("Included %d draft asset(s)\n", len(manifest.Drafts))
+	}
 	
 	return nil
 } 
\ No newline at end of file
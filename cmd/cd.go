@@ -16,7 +16,7 @@ var cdCmd = &cobra.Command{
 	Short: "Change directory to asset folder",
 	Long: `Change directory to asset folder by searching for asset type names.
 
-Searches through asset descriptions to find matching folders.
+Searches through the display names and slugs of registered asset types to find matching folders.
 
 Examples:
   rdb cd text        # Go to text-related folders (Strings, Misc Text Files, etc.)
@@ -57,46 +57,22 @@ func runCd(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to open repository: %w", err)
 	}
 	
-	// Asset type mapping with searchable descriptions
-	assetTypes := map[int]string{
-		1000624: "Flash Images",
-		1030002: "Strings",
-		1010042: "Loading Screens",
-		1000083: "XML Treasure Data",
-		1000087: "XML Zone Transition Points",
-		1000090: "XML Resurrection Points",
-		1000635: "USM Video Files",
-		1000636: "Images",
-		1070003: "Playfields",
-		1010013: "Maps",
-		1010210: "Image (no name)",
-		1010211: "Image (no name)",
-		1000623: "Misc Text Files",
-		1066603: "Unknown Textures",
-		1020001: "Unknown",
-		1020002: "Sound Effects",
-		1020005: "Music",
-		1020006: "Sounds - Tones",
-		1010207: "Particle Effects",
-		1000010: "File Names Index / FME Files",
-		1000007: "PhysX XML",
-		1020003: "Dialog Audio",
-		1010008: "Miscellaneous Images",
+	// Find matching asset types by display name or slug
+	registry, err := r.LoadTypes()
+	if err != nil {
+		return err
 	}
 	
-	// Find matching assets
 	var matches []struct {
 		id   int
 		name string
 	}
 	
-	for id, name := range assetTypes {
-		if strings.Contains(strings.ToLower(name), searchTerm) {
-			matches = append(matches, struct {
-				id   int
-				name string
-			}{id, name})
-		}
+	for _, assetType := range registry.Search(searchTerm) {
+		matches = append(matches, struct {
+			id   int
+			name string
+		}{assetType.ID, assetType.Name})
 	}
 	
 	if len(matches) == 0 {
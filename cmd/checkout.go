@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/rdb/cli/internal/repo"
+	"github.com/spf13/cobra"
+)
+
+var (
+	checkoutNewBranch bool
+	checkoutForce     bool
+	checkoutOurs      bool
+	checkoutTheirs    bool
+)
+
+// checkoutCmd represents the checkout command
+var checkoutCmd = &cobra.Command{
+	Use:   "checkout <branch> | <revision> -- <path>... | asset:<id>@<revision> | --ours|--theirs <path>...",
+	Short: "Switch branches, restore files or resolve merge conflicts",
+	Long: `Switch to another branch and rewrite the files under assets/ to match it.
+
+Behaves like 'rdb switch'; -b creates the branch first.
+
+With paths after --, restores those files (or directories) as they are in the
+given revision, or in HEAD if none is given, and stages them. Local changes to
+them are overwritten. asset:<id>@<revision> restores a whole asset the same way.
+See 'rdb log --help' for the revision syntax.
+
+With --ours or --theirs, resolves merge conflicts for the given paths (files or
+directories) by taking the current branch's or the merged branch's version.
+
+Examples:
+  rdb checkout winter-event
+  rdb checkout -b summer-event
+  rdb checkout HEAD~2 -- assets/1030002/en.txt
+  rdb checkout asset:1030002@main@{yesterday}
+  rdb checkout --theirs assets/1030002/en.txt
+  rdb checkout --ours assets/1000624`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runCheckout,
+}
+
+func init() {
+	rootCmd.AddCommand(checkoutCmd)
+	
+	// Local flags
+	checkoutCmd.Flags().BoolVarP(&checkoutNewBranch, "branch", "b", false, "create the branch at the current commit before switching")
+	checkoutCmd.Flags().BoolVarP(&checkoutForce, "force", "f", false, "discard local changes that would be overwritten")
+	checkoutCmd.Flags().BoolVar(&checkoutOurs, "ours", false, "resolve conflicts with the current branch's version")
+	checkoutCmd.Flags().BoolVar(&checkoutTheirs, "theirs", false, "resolve conflicts with the merged branch's version")
+}
+
+func runCheckout(cmd *cobra.Command, args []string) error {
+	// Always use current working directory
+	repoPath := "."
+	
+	// Convert to absolute path
+	absPath, err := filepath.Abs(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve path: %w", err)
+	}
+	
+	// Safety check: prevent operations in system directories
+	if strings.Contains(strings.ToLower(absPath), "c:\\windows\\system32") {
+		return fmt.Errorf("cannot operate on RDB repository in system directory: %s", absPath)
+	}
+	
+	// Check if repository exists
+	if !repo.IsRepository(absPath) {
+		return fmt.Errorf("not an RDB repository: %s", absPath)
+	}
+	
+	// Open repository
+	r, err := repo.OpenRepository(absPath)
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+	
+	if checkoutOurs || checkoutTheirs {
+		if checkoutOurs && checkoutTheirs {
+			return fmt.Errorf("--ours and --theirs are mutually exclusive")
+		}
+		return resolveConflicts(r, args, checkoutTheirs)
+	}
+	
+	if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+		rev := "HEAD"
+		switch dash {
+		case 0:
+		case 1:
+			rev = args[0]
+		default:
+			return fmt.Errorf("usage: rdb checkout [<revision>] -- <path>...")
+		}
+		if dash == len(args) {
+			return fmt.Errorf("no paths given after --")
+		}
+		return checkoutRevisionPaths(r, rev, args[dash:])
+	}
+	
+	if len(args) != 1 {
+		return fmt.Errorf("usage: rdb checkout <branch>")
+	}
+	
+	if strings.HasPrefix(args[0], "asset:") {
+		return checkoutRevisionPaths(r, args[0], nil)
+	}
+	if !checkoutNewBranch && !r.BranchExists(args[0]) {
+		if _, err := r.ResolveRevision(args[0]); err == nil {
+			return fmt.Errorf("%s is not a branch; restore files with 'rdb checkout %s -- <path>' or start a branch there with 'rdb branch <name> %s'", args[0], args[0], args[0])
+		}
+	}
+	
+	return switchBranch(r, "checkout", args[0], checkoutNewBranch, checkoutForce)
+}
+
+// checkoutRevisionPaths restores the given paths, or the asset an asset:<id>@<rev>
+// revision names, from a revision into the working tree and index
+func checkoutRevisionPaths(r *repo.Repository, revText string, paths []string) error {
+	rev, err := r.ParseRevision(revText)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", revText, err)
+	}
+	commit, err := r.ReadCommit(rev.Commit)
+	if err != nil {
+		return fmt.Errorf("failed to read commit: %w", err)
+	}
+	
+	var specs []string
+	if rev.Asset != 0 {
+		specs = append(specs, rev.AssetPath())
+	}
+	for _, p := range paths {
+		absPath, err := filepath.Abs(p)
+		if err != nil {
+			return fmt.Errorf("failed to resolve path: %w", err)
+		}
+		relPath, err := r.RelPath(absPath)
+		if err != nil {
+			return err
+		}
+		specs = append(specs, r.Layout().TreePath(relPath))
+	}
+	
+	lock, err := r.LockIndex()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	
+	written, err := r.CheckoutPaths(commit.Tree, specs)
+	if err != nil {
+		return err
+	}
+	for _, p := range written {
+		fmt.Printf("Updated %s\n", p)
+	}
+	fmt.Printf("Restored %d file(s) from %s\n", len(written), rev.Commit[:8])
+	
+	return nil
+}
+
+// resolveConflicts takes one side of every conflicted path at or below the given paths
+func resolveConflicts(r *repo.Repository, paths []string, theirs bool) error {
+	lock, err := r.LockIndex()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	
+	idx, err := r.LoadIndex()
+	if err != nil {
+		return fmt.Errorf("failed to load index: %w", err)
+	}
+	
+	side := "ours"
+	if theirs {
+		side = "theirs"
+	}
+	
+	for _, p := range paths {
+		absPath, err := filepath.Abs(p)
+		if err != nil {
+			return fmt.Errorf("failed to resolve path: %w", err)
+		}
+		relPath, err := r.RelPath(absPath)
+		if err != nil {
+			return err
+		}
+		
+		resolved := 0
+		for _, entry := range idx.EntriesUnder(relPath) {
+			if entry.Conflict == nil {
+				continue
+			}
+			if err := r.ResolveConflict(idx, entry.Path, theirs); err != nil {
+				return err
+			}
+			fmt.Printf("Resolved %s using %s\n", entry.Path, side)
+			resolved++
+		}
+		if resolved == 0 {
+			return fmt.Errorf("no unresolved conflicts in %s", p)
+		}
+	}
+	
+	if err := r.SaveIndex(idx); err != nil {
+		return fmt.Errorf("failed to save index: %w", err)
+	}
+	
+	return nil
+}
@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
 	"strings"
 	"time"
@@ -15,6 +14,7 @@ var (
 	commitMessage string
 	commitAuthor  string
 	amend         bool
+	allowEmpty    bool
 )
 
 // commitCmd represents the commit command
@@ -26,7 +26,9 @@ var commitCmd = &cobra.Command{
 Examples:
   rdb commit -m "Add intro dialog line"
   rdb commit -m "Update textures" --author "John Doe <john@example.com>"
-  rdb commit --amend`,
+  rdb commit --amend
+  rdb commit --amend -m "Reword the previous commit"
+  rdb commit -m "Checkpoint" --allow-empty`,
 	RunE: runCommit,
 }
 
@@ -37,9 +39,7 @@ func init() {
 	commitCmd.Flags().StringVarP(&commitMessage, "message", "m", "", "commit message")
 	commitCmd.Flags().StringVar(&commitAuthor, "author", "", "author (format: 'Name <email>')")
 	commitCmd.Flags().BoolVar(&amend, "amend", false, "amend the previous commit")
-	
-	// Mark required flags
-	commitCmd.MarkFlagRequired("message")
+	commitCmd.Flags().BoolVar(&allowEmpty, "allow-empty", false, "allow a commit that records no changes")
 }
 
 func runCommit(cmd *cobra.Command, args []string) error {
@@ -68,26 +68,64 @@ func runCommit(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to open repository: %w", err)
 	}
 	
+	// Hold the index for the whole update so concurrent rdb runs do not lose changes
+	lock, err := r.LockIndex()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	
 	// Get current branch
 	branch, err := r.GetCurrentBranch()
 	if err != nil {
 		return fmt.Errorf("failed to get current branch: %w", err)
 	}
 	
-	// Get current commit (for amend)
-	var parentCommit string
+	// Get current tip; a normal commit builds on it, --amend replaces it
+	tipHash, err := r.GetCurrentCommit()
+	if err != nil {
+		return fmt.Errorf("failed to get current commit: %w", err)
+	}
+	tip, err := r.ReadCommit(tipHash)
+	if err != nil {
+		return fmt.Errorf("failed to read current commit: %w", err)
+	}
+	
+	// Pick up an in-progress merge
+	mergeHead, mergeMsg, err := r.ReadMergeState()
+	if err != nil {
+		return err
+	}
+	if mergeHead != "" && amend {
+		return fmt.Errorf("cannot amend while a merge is in progress")
+	}
+	
+	message := commitMessage
+	parents := []string{tipHash}
+	if mergeHead != "" {
+		parents = append(parents, mergeHead)
+		if message == "" {
+			message = mergeMsg
+		}
+	}
 	if amend {
-		parentCommit, err = r.GetCurrentCommit()
-		if err != nil {
-			return fmt.Errorf("failed to get current commit: %w", err)
+		// Reuse the amended commit's parents and, unless overridden, its message
+		parents = tip.ParentHashes()
+		if message == "" {
+			message = tip.Message
 		}
 	}
+	if message == "" {
+		return fmt.Errorf("commit message required (use -m)")
+	}
 	
 	// Determine author
 	author := commitAuthor
+	if author == "" && amend {
+		author = tip.Author
+	}
 	if author == "" {
-		// TODO: Get from config or environment
-		author = "RDB <rdb@localhost>"
+		author = repo.DefaultAuthor
 	}
 	
 	// Create commit
@@ -95,35 +133,66 @@ func runCommit(cmd *cobra.Command, args []string) error {
 		ID:        repo.GenerateID(),
 		Author:    author,
 		Timestamp: time.Now(),
-		Message:   commitMessage,
+		Message:   message,
 		Branch:    branch,
+		Parents:   parents,
+	}
+	
+	// Create tree from staged changes
+	idx, err := r.LoadIndex()
+	if err != nil {
+		return fmt.Errorf("failed to load index: %w", err)
 	}
 	
-	if amend && parentCommit != "" {
-		commit.Parent = parentCommit
+	if conflicts := idx.Conflicts(); len(conflicts) > 0 {
+		return fmt.Errorf("cannot commit with %d unresolved conflict(s), e.g. %s; resolve them with 'rdb checkout --ours/--theirs' or 'rdb add'", len(conflicts), conflicts[0].Path)
+	}
+	
+	// Reject metadata that does not satisfy its type's schema
+	validationErrors, err := r.ValidateIndex(idx)
+	if err != nil {
+		return fmt.Errorf("failed to validate metadata: %w", err)
+	}
+	if len(validationErrors) > 0 {
+		return repo.ValidationErrors(validationErrors)
 	}
 	
-	// TODO: Create tree from staged changes
-	// For now, create an empty tree
-	tree := &repo.Tree{Entries: []repo.TreeEntry{}}
-	treeHash, err := r.WriteObject("tree", tree)
+	treeHash, err := r.BuildTree(idx)
 	if err != nil {
-		return fmt.Errorf("failed to write tree object: %w", err)
+		return fmt.Errorf("failed to build tree: %w", err)
 	}
 	commit.Tree = treeHash
 	
+	// Refuse to record a commit identical to the current tip
+	if !amend && !allowEmpty && mergeHead == "" {
+		if tip.Tree == treeHash {
+			return fmt.Errorf("nothing to commit (use --allow-empty to commit anyway)")
+		}
+	}
+	
 	// Write commit object
 	commitHash, err := r.WriteObject("commit", commit)
 	if err != nil {
 		return fmt.Errorf("failed to write commit object: %w", err)
 	}
 	
-	// Update branch reference
-	refPath := filepath.Join(r.Path, ".rdb", "refs", "heads", branch)
-	if err := os.WriteFile(refPath, []byte(commitHash), 0644); err != nil {
+	// Update branch reference, unless another commit moved it in the meantime
+	change := repo.RefChange{Command: "commit", Message: firstLine(message), Author: author}
+	if amend {
+		change.Command = "commit --amend"
+	} else if mergeHead != "" {
+		change.Command = "commit (merge)"
+	}
+	if err := r.CompareAndSwapBranch(branch, tipHash, commitHash, change); err != nil {
 		return fmt.Errorf("failed to update branch reference: %w", err)
 	}
 	
+	if mergeHead != "" {
+		if err := r.ClearMergeState(); err != nil {
+			return err
+		}
+	}
+	
 	if amend {
 		fmt.Printf("Amended commit %s\n", commitHash[:8])
 	} else {
@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rdb/cli/internal/repo"
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffCached     bool
+	diffStat       bool
+	diffNameStatus bool
+)
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff [<revision> [<revision>]] [-- <path|asset-id>...]",
+	Short: "Show changes between commits, the index and the working tree",
+	Long: `Show changes asset by asset.
+
+With no revision, compares HEAD with the working tree. With one revision,
+compares that revision with the working tree, or with the index when
+--cached is given. With two revisions, compares the first with the second.
+An asset:<id>@<rev> revision also limits the output to that asset. Paths
+after -- limit the output; a bare number is taken as an asset ID.
+
+Changes are grouped by asset. meta.json is compared value by value, Strings,
+text and XML payloads are shown as unified diffs, and other files are
+summarized by size, hash and, for images, dimensions.
+
+Examples:
+  rdb diff
+  rdb diff --cached
+  rdb diff HEAD~1
+  rdb diff v1.0 main -- 1030002
+  rdb diff asset:1030002@HEAD~2 HEAD
+  rdb diff --stat main@{1} main`,
+	RunE: runDiff,
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	
+	// Local flags
+	diffCmd.Flags().BoolVar(&diffCached, "cached", false, "compare with the index instead of the working tree")
+	diffCmd.Flags().BoolVar(&diffCached, "staged", false, "synonym for --cached")
+	diffCmd.Flags().BoolVar(&diffStat, "stat", false, "show only a summary of changed assets and files")
+	diffCmd.Flags().BoolVar(&diffNameStatus, "name-status", false, "show only the status and path of changed files")
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	// Always use current working directory
+	repoPath := "."
+	
+	// Convert to absolute path
+	absPath, err := filepath.Abs(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve path: %w", err)
+	}
+	
+	// Safety check: prevent operations in system directories
+	if strings.Contains(strings.ToLower(absPath), "c:\\windows\\system32") {
+		return fmt.Errorf("cannot operate on RDB repository in system directory: %s", absPath)
+	}
+	
+	// Check if repository exists
+	if !repo.IsRepository(absPath) {
+		return fmt.Errorf("not an RDB repository: %s", absPath)
+	}
+	
+	// Open repository
+	r, err := repo.OpenRepository(absPath)
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+	
+	revArgs, pathArgs, err := diffArguments(r, cmd, args)
+	if err != nil {
+		return err
+	}
+	if len(revArgs) == 2 && diffCached {
+		return fmt.Errorf("--cached takes at most one revision")
+	}
+	if len(revArgs) == 0 {
+		revArgs = []string{"HEAD"}
+	}
+	
+	var paths []string
+	for _, arg := range pathArgs {
+		paths = append(paths, r.Layout().TreePath(logPathspec(arg)))
+	}
+	
+	// Resolve both sides
+	var snapshots []repo.DiffSnapshot
+	for _, revText := range revArgs {
+		rev, err := r.ParseRevision(revText)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", revText, err)
+		}
+		if rev.Asset != 0 {
+			paths = append(paths, rev.AssetPath())
+		}
+		commit, err := r.ReadCommit(rev.Commit)
+		if err != nil {
+			return fmt.Errorf("failed to read commit: %w", err)
+		}
+		snapshot, err := r.TreeSnapshot(commit.Tree)
+		if err != nil {
+			return fmt.Errorf("failed to read tree: %w", err)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	if len(snapshots) == 1 {
+		idx, err := r.LoadIndex()
+		if err != nil {
+			return fmt.Errorf("failed to load index: %w", err)
+		}
+		var snapshot repo.DiffSnapshot
+		if diffCached {
+			snapshot = r.IndexSnapshot(idx)
+		} else {
+			snapshot, err = r.WorkTreeSnapshot(idx)
+			if err != nil {
+				return fmt.Errorf("failed to scan working tree: %w", err)
+			}
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	
+	diffs, err := r.DiffSnapshots(snapshots[0], snapshots[1], paths)
+	if err != nil {
+		return fmt.Errorf("failed to compare: %w", err)
+	}
+	
+	if jsonOutput {
+		data, err := json.MarshalIndent(diffs, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal diff: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	
+	switch {
+	case diffNameStatus:
+		for _, asset := range diffs {
+			for _, file := range asset.Files {
+				fmt.Printf("%c\t%s\n", strings.ToUpper(file.Status)[0], file.Path)
+			}
+		}
+		return nil
+	case diffStat:
+		printDiffStat(diffs)
+		return nil
+	}
+	
+	for i, asset := range diffs {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Println(diffAssetHeader(asset))
+		for _, file := range asset.Files {
+			render := repo.DiffRendererFor(&asset, file)
+			if err := render(os.Stdout, r, &asset, file); err != nil {
+				return fmt.Errorf("failed to render %s: %w", file.Path, err)
+			}
+		}
+	}
+	
+	return nil
+}
+
+// diffArguments splits the arguments into up to two revisions and the path filters.
+// Without --, leading arguments that resolve as revisions are taken as such.
+func diffArguments(r *repo.Repository, cmd *cobra.Command, args []string) ([]string, []string, error) {
+	if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+		if dash > 2 {
+			return nil, nil, fmt.Errorf("too many revisions: %s", strings.Join(args[:dash], " "))
+		}
+		return args[:dash], args[dash:], nil
+	}
+	
+	n := 0
+	for n < len(args) && n < 2 {
+		if _, err := r.ParseRevision(args[n]); err != nil {
+			break
+		}
+		n++
+	}
+	return args[:n], args[n:], nil
+}
+
+// diffAssetHeader describes an asset in the diff output, such as "asset 1030002 (string) modified"
+func diffAssetHeader(asset repo.AssetDiff) string {
+	name := asset.Path
+	if asset.ID != 0 {
+		name = fmt.Sprintf("asset %d", asset.ID)
+	}
+	if asset.Type != "" {
+		name += " (" + asset.Type + ")"
+	}
+	return name + " " + asset.Status
+}
+
+// printDiffStat lists the changed files of each asset with their size change
+func printDiffStat(diffs []repo.AssetDiff) {
+	files := 0
+	for _, asset := range diffs {
+		fmt.Println(diffAssetHeader(asset))
+		for _, file := range asset.Files {
+			delta := file.New.Size - file.Old.Size
+			sign := "+"
+			if delta < 0 {
+				sign, delta = "-", -delta
+			}
+			fmt.Printf("  %c %s (%s%s)\n", strings.ToUpper(file.Status)[0], file.Path, sign, repo.FormatBytes(delta))
+			files++
+		}
+	}
+	fmt.Printf("%d asset(s) changed, %d file(s)\n", len(diffs), files)
+}
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rdb/cli/internal/repo"
+	"github.com/spf13/cobra"
+)
+
+var (
+	fsckRepair bool
+	fsckFrom   []string
+)
+
+// fsckCmd represents the fsck command
+var fsckCmd = &cobra.Command{
+	Use:   "fsck",
+	Short: "Verify the integrity of the object store",
+	Long: `Verify every object in .rdb/objects and every link reachable from the refs
+and the index.
+
+Reports objects whose content does not hash to their name (corrupt), objects that
+are referenced but absent (missing), intact objects no ref can reach (dangling)
+and refs that do not point at a commit (bad-ref).
+
+With --repair, corrupt and missing objects are restored from the repositories
+registered in .rdb/remotes and from any --from repository. A remote is a file
+.rdb/remotes/<name> containing the path of the other repository.
+
+Examples:
+  rdb fsck
+  rdb fsck --json
+  rdb fsck --repair --from ../backup`,
+	Args: cobra.NoArgs,
+	RunE: runFsck,
+}
+
+func init() {
+	rootCmd.AddCommand(fsckCmd)
+
+	// Local flags
+	fsckCmd.Flags().BoolVar(&fsckRepair, "repair", false, "restore corrupt and missing objects from remotes")
+	fsckCmd.Flags().StringSliceVar(&fsckFrom, "from", nil, "additional repository to recover objects from (implies --repair)")
+}
+
+func runFsck(cmd *cobra.Command, args []string) error {
+	// Always use current working directory
+	repoPath := "."
+
+	// Convert to absolute path
+	absPath, err := filepath.Abs(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve path: %w", err)
+	}
+
+	// Safety check: prevent operations in system directories
+	if strings.Contains(strings.ToLower(absPath), "c:\\windows\\system32") {
+		return fmt.Errorf("cannot operate on RDB repository in system directory: %s", absPath)
+	}
+
+	// Check if repository exists
+	if !repo.IsRepository(absPath) {
+		return fmt.Errorf("not an RDB repository: %s", absPath)
+	}
+
+	// Open repository
+	r, err := repo.OpenRepository(absPath)
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	var report *repo.FsckReport
+	if fsckRepair || len(fsckFrom) > 0 {
+		sources, err := repairSources(r)
+		if err != nil {
+			return err
+		}
+		report, err = r.FsckRepair(sources)
+		if err != nil {
+			return err
+		}
+	} else {
+		report, err = r.Fsck()
+		if err != nil {
+			return err
+		}
+	}
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal report: %w", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		printFsckReport(report)
+	}
+
+	if !report.Healthy() {
+		problems := 0
+		for _, issue := range report.Issues {
+			if issue.Kind != repo.FsckDangling && !issue.Repaired {
+				problems++
+			}
+		}
+		return fmt.Errorf("%d problem(s) found", problems)
+	}
+	return nil
+}
+
+// repairSources opens the registered remotes followed by the --from repositories
+func repairSources(r *repo.Repository) ([]*repo.Repository, error) {
+	remotes, err := r.Remotes()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(remotes))
+	for name := range remotes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sources []*repo.Repository
+	for _, name := range names {
+		sources = append(sources, remotes[name])
+	}
+	for _, p := range fsckFrom {
+		absPath, err := filepath.Abs(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve path: %w", err)
+		}
+		source, err := repo.OpenRepository(absPath)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, source)
+	}
+
+	if len(sources) == 0 {
+		fmt.Println("Warning: no remotes registered and no --from repository given; nothing to repair from")
+	}
+	return sources, nil
+}
+
+// printFsckReport prints one line per issue followed by a summary
+func printFsckReport(report *repo.FsckReport) {
+	repaired := 0
+	for _, issue := range report.Issues {
+		subject := issue.Object
+		if issue.Ref != "" {
+			subject = issue.Ref
+		}
+		kind := issue.Kind
+		if issue.Type != "" && issue.Kind != repo.FsckBadRef {
+			kind += " " + issue.Type
+		}
+		line := fmt.Sprintf("%s %s: %s", kind, subject, issue.Message)
+		if issue.Repaired {
+			line = "repaired " + line
+			repaired++
+		}
+		fmt.Println(line)
+	}
+
+	fmt.Printf("Checked %d object(s), %d reachable\n", report.Objects, report.Reachable)
+	if repaired > 0 {
+		fmt.Printf("Repaired %d object(s)\n", repaired)
+	}
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rdb/cli/internal/repo"
+	"github.com/spf13/cobra"
+)
+
+var (
+	gcDryRun       bool
+	gcGrace        string
+	gcExpireReflog string
+)
+
+// gcCmd represents the gc command
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Remove unreachable objects",
+	Long: `Remove loose objects that no branch, tag, in-progress merge or staged file
+references any more, such as the trees and commits left behind by amended commits.
+
+Only objects last modified longer ago than the grace period (default 2 weeks) are
+removed, so objects written by a concurrent add or commit are never collected.
+Use --grace now to remove every unreachable object.
+
+Commits named by the reflog (see 'rdb reflog') are kept until their entries
+expire after --expire-reflog (default 90 days); expired entries are dropped.
+
+Examples:
+  rdb gc --dry-run
+  rdb gc
+  rdb gc --grace 72h
+  rdb gc --grace now
+  rdb gc --grace now --expire-reflog now`,
+	Args: cobra.NoArgs,
+	RunE: runGC,
+}
+
+func init() {
+	rootCmd.AddCommand(gcCmd)
+
+	// Local flags
+	gcCmd.Flags().BoolVarP(&gcDryRun, "dry-run", "n", false, "report what would be removed without deleting anything")
+	gcCmd.Flags().StringVar(&gcGrace, "grace", "", "minimum age of unreachable objects to remove (e.g. 72h, now; default 2 weeks)")
+	gcCmd.Flags().StringVar(&gcExpireReflog, "expire-reflog", "", "age after which reflog entries are dropped (e.g. 720h, now, never; default 90 days)")
+}
+
+func runGC(cmd *cobra.Command, args []string) error {
+	// Always use current working directory
+	repoPath := "."
+
+	// Convert to absolute path
+	absPath, err := filepath.Abs(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve path: %w", err)
+	}
+
+	// Safety check: prevent operations in system directories
+	if strings.Contains(strings.ToLower(absPath), "c:\\windows\\system32") {
+		return fmt.Errorf("cannot operate on RDB repository in system directory: %s", absPath)
+	}
+
+	// Check if repository exists
+	if !repo.IsRepository(absPath) {
+		return fmt.Errorf("not an RDB repository: %s", absPath)
+	}
+
+	// Open repository
+	r, err := repo.OpenRepository(absPath)
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	opts := repo.GCOptions{GracePeriod: repo.DefaultGCGracePeriod, ReflogExpiry: repo.DefaultReflogExpiry, DryRun: gcDryRun}
+	switch gcGrace {
+	case "":
+	case "now":
+		opts.GracePeriod = 0
+	default:
+		opts.GracePeriod, err = time.ParseDuration(gcGrace)
+		if err != nil || opts.GracePeriod < 0 {
+			return fmt.Errorf("invalid grace period: %s", gcGrace)
+		}
+	}
+	switch gcExpireReflog {
+	case "":
+	case "now":
+		opts.ReflogExpiry = 0
+	case "never":
+		opts.ReflogExpiry = time.Duration(math.MaxInt64)
+	default:
+		opts.ReflogExpiry, err = time.ParseDuration(gcExpireReflog)
+		if err != nil || opts.ReflogExpiry < 0 {
+			return fmt.Errorf("invalid reflog expiry: %s", gcExpireReflog)
+		}
+	}
+
+	report, err := r.GC(opts)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal report: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	verb := "Removed"
+	if report.DryRun {
+		verb = "Would remove"
+		for _, obj := range report.Pruned {
+			fmt.Printf("would remove %s (%s)\n", obj.Object, repo.FormatBytes(obj.Size))
+		}
+	}
+	fmt.Printf("Checked %d object(s): %d reachable, %d unreachable within the grace period\n", report.Objects, report.Reachable, report.Recent)
+	fmt.Printf("%s %d unreachable object(s), reclaiming %s\n", verb, len(report.Pruned), repo.FormatBytes(report.Reclaimed))
+	if report.Expired > 0 {
+		fmt.Printf("%s %d expired reflog entry(ies)\n", verb, report.Expired)
+	}
+	return nil
+}
@@ -11,8 +11,9 @@ import (
 )
 
 var (
-	layout string
-	types  string
+	layout       string
+	types        string
+	initTemplate string
 )
 
 // initCmd represents the init command
@@ -23,9 +24,18 @@ var initCmd = &cobra.Command{
 
 Creates the directory tree and .rdb structure with the specified layout and asset types.
 
+--types selects which asset type folders are created, by ID, slug or part of the
+display name; by default every registered type gets a folder. The flat layout
+stores files as assets/<id>.<file> without per-ID folders.
+
+--template seeds the type registry from a bundled template (default, empty) or
+from a local directory, copying its .rdbignore and assets/ as well.
+
 Examples:
-  rdb init --layout tree --types "text,audio,texture,shader,mesh"
-  rdb init --layout flat --types "text,audio"`,
+  rdb init --layout tree --types "string,xml,1000635"
+  rdb init --layout flat
+  rdb init --template empty
+  rdb init --template ..\project-template`,
 	RunE: runInit,
 }
 
@@ -34,7 +44,8 @@ func init() {
 
 	// Local flags
 	initCmd.Flags().StringVar(&layout, "layout", "tree", "repository layout (tree or flat)")
-	initCmd.Flags().StringVar(&types, "types", "text,audio,texture,shader,mesh", "comma-separated list of asset types (optional)")
+	initCmd.Flags().StringVar(&types, "types", "", "comma-separated asset type IDs, slugs or names to create folders for (default all)")
+	initCmd.Flags().StringVar(&initTemplate, "template", "", "bundled template name or local template directory")
 }
 
 func runInit(cmd *cobra.Command, args []string) error {
@@ -66,27 +77,50 @@ func runInit(cmd *cobra.Command, args []string) error {
 	}
 	
 	// Parse asset types
-	assetTypes := strings.Split(types, ",")
-	for i, t := range assetTypes {
-		assetTypes[i] = strings.TrimSpace(t)
+	var assetTypes []string
+	for _, t := range strings.Split(types, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			assetTypes = append(assetTypes, t)
+		}
 	}
 	
 	// Validate layout
-	if layout != "tree" && layout != "flat" {
-		return fmt.Errorf("invalid layout: %s (must be 'tree' or 'flat')", layout)
+	if _, err := repo.LayoutByName(layout); err != nil {
+		return err
+	}
+	
+	// Load template
+	var tmpl *repo.Template
+	if initTemplate != "" {
+		tmpl, err = repo.LoadTemplate(initTemplate)
+		if err != nil {
+			return err
+		}
 	}
 	
 	// Create new repository
 	r := repo.NewRepository(absPath)
 	
 	// Initialize repository
-	if err := r.Init(layout, assetTypes); err != nil {
+	if err := r.InitFromTemplate(layout, assetTypes, tmpl); err != nil {
 		return fmt.Errorf("failed to initialize repository: %w", err)
 	}
 	
+	registry, err := r.LoadTypes()
+	if err != nil {
+		return err
+	}
+	selected, unmatched := registry.Select(assetTypes)
+	for _, t := range unmatched {
+		fmt.Printf("Warning: no registered asset type matches %s\n", t)
+	}
+	
 	fmt.Printf("Initialized RDB repository at %s\n", absPath)
 	fmt.Printf("Layout: %s\n", layout)
-	fmt.Printf("Asset types: %s\n", strings.Join(assetTypes, ", "))
+	if tmpl != nil {
+		fmt.Printf("Template: %s\n", tmpl.Name)
+	}
+	fmt.Printf("Asset types: %d of %d registered\n", len(selected), len(registry.Types))
 	
 	return nil
 } 
\ No newline at end of file
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/rdb/cli/internal/repo"
 	"github.com/spf13/cobra"
@@ -19,7 +21,8 @@ var listCmd = &cobra.Command{
 	Short: "List asset types and folders",
 	Long: `List all asset types with their corresponding folders.
 
-Shows the mapping between asset IDs and their types, and optionally changes directory to a specific asset folder.
+Shows the mapping between asset IDs and their types from the type registry
+(see 'rdb type'), and optionally changes directory to a specific asset folder.
 
 Examples:
   rdb list                    # List all asset types and folders
@@ -56,37 +59,17 @@ func runList(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to open repository: %w", err)
 	}
 	
-	// Asset type mapping
-	assetTypes := map[int]string{
-		1000624: "Flash Images",
-		1030002: "Strings",
-		1010042: "Loading Screens",
-		1000083: "XML Treasure Data",
-		1000087: "XML Zone Transition Points",
-		1000090: "XML Resurrection Points",
-		1000635: "USM Video Files",
-		1000636: "Images",
-		1070003: "Playfields",
-		1010013: "Maps",
-		1010210: "Image (no name)",
-		1010211: "Image (no name)",
-		1000623: "Misc Text Files",
-		1066603: "Unknown Textures",
-		1020001: "Unknown",
-		1020002: "Sound Effects",
-		1020005: "Music",
-		1020006: "Sounds - Tones",
-		1010207: "Particle Effects",
-		1000010: "File Names Index / FME Files",
-		1000007: "PhysX XML",
-		1020003: "Dialog Audio",
-		1010008: "Miscellaneous Images",
+	// Asset type registry
+	registry, err := r.LoadTypes()
+	if err != nil {
+		return err
 	}
 	
 	// Check if user wants to change directory
 	if listCd && len(args) > 0 {
 		targetID := args[0]
-		if assetName, exists := assetTypes[parseAssetID(targetID)]; exists {
+		if assetType, exists := registry.Get(parseAssetID(targetID)); exists {
+			assetName := assetType.Name
 			assetPath := filepath.Join(r.Path, "assets", targetID)
 			if _, err := os.Stat(assetPath); err == nil {
 				fmt.Printf("Changing directory to: %s (%s)\n", assetPath, assetName)
@@ -107,19 +90,28 @@ func runList(cmd *cobra.Command, args []string) error {
 	fmt.Printf("========================\n\n")
 	
 	assetsPath := filepath.Join(r.Path, "assets")
+	draftCounts := countDraftAssets(r)
 	
-	for id, name := range assetTypes {
+	for _, assetType := range registry.Types {
+		id, name := assetType.ID, assetType.Name
 		folderPath := filepath.Join(assetsPath, fmt.Sprintf("%d", id))
 		
-		// Check if folder exists
+		// Check if folder exists; the flat layout has files named <id>.* instead
 		exists := ""
 		if _, err := os.Stat(folderPath); err == nil {
 			exists = "✓"
+		} else if matches, _ := filepath.Glob(folderPath + ".*"); len(matches) > 0 {
+			exists = "✓"
 		} else {
 			exists = "✗"
 		}
 		
-		fmt.Printf("%s %07d - %s\n", exists, id, name)
+		drafts := ""
+		if n := draftCounts[id]; n > 0 {
+			drafts = fmt.Sprintf(" [%d draft]", n)
+		}
+		
+		fmt.Printf("%s %07d - %s%s\n", exists, id, name, drafts)
 	}
 	
 	fmt.Printf("\nUsage:\n")
@@ -136,4 +128,28 @@ func parseAssetID(idStr string) int {
 		return id
 	}
 	return 0
-} 
\ No newline at end of file
+}
+
+// countDraftAssets walks assets/ once and counts, per ID folder, the meta.json files
+// that mark their asset as a draft
+func countDraftAssets(r *repo.Repository) map[int]int {
+	counts := make(map[int]int)
+	filepath.WalkDir(filepath.Join(r.Path, "assets"), func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		relPath, err := r.RelPath(path)
+		if err != nil || !r.IsMetaPath(relPath) {
+			return nil
+		}
+		id, err := strconv.Atoi(strings.TrimPrefix(repo.AssetDir(r.Layout().TreePath(relPath)), "assets/"))
+		if err != nil {
+			return nil
+		}
+		if asset, err := repo.ReadAssetMetaFile(path); err == nil && asset.IsDraft() {
+			counts[id]++
+		}
+		return nil
+	})
+	return counts
+}
@@ -3,8 +3,12 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/rdb/cli/internal/repo"
@@ -16,19 +20,37 @@ var (
 	logMaxCount  int
 	logSince     string
 	logUntil     string
+	logAuthor    string
+	logGrep      string
+	logFormat    string
 )
 
 // logCmd represents the log command
 var logCmd = &cobra.Command{
-	Use:   "log",
+	Use:   "log [<revision>] [-- <path|asset-id>...]",
 	Short: "Show commit history",
 	Long: `Show the commit history.
 
+Walks all parents of the current commit, or of the given revision, newest
+first. A revision is a branch or tag, a full or abbreviated commit hash,
+HEAD~<n>, HEAD^<n>, <branch>@{<n>} or <branch>@{<date>}; asset:<id>@<rev>
+also limits the output to that asset. Paths after -- limit the output to
+commits that touched them; a bare number is taken as an asset ID.
+
+--format accepts a Go template with the fields .Hash, .ShortHash, .Author,
+.Date, .Message, .Branch and .Parents.
+
 Examples:
   rdb log
   rdb log --oneline
+  rdb log main@{1}
+  rdb log HEAD~3 --oneline
+  rdb log asset:1030002@main
   rdb log --max-count 10
-  rdb log --since "2024-01-01"`,
+  rdb log --since "2024-01-01"
+  rdb log --since "1 week ago" -- 1030002
+  rdb log --author "Jane" --grep "intro" -- assets/1030002
+  rdb log --format "{{.ShortHash}} {{.Date.Format \"2006-01-02\"}} {{.Message}}"`,
 	RunE: runLog,
 }
 
@@ -40,6 +62,30 @@ func init() {
 	logCmd.Flags().IntVar(&logMaxCount, "max-count", 0, "limit number of commits")
 	logCmd.Flags().StringVar(&logSince, "since", "", "show commits more recent than date")
 	logCmd.Flags().StringVar(&logUntil, "until", "", "show commits older than date")
+	logCmd.Flags().StringVar(&logAuthor, "author", "", "show commits whose author contains the given text")
+	logCmd.Flags().StringVar(&logGrep, "grep", "", "show commits whose message matches the regular expression")
+	logCmd.Flags().StringVar(&logFormat, "format", "", "format each commit with a Go template")
+}
+
+// logFilter holds the criteria a commit must match to be shown
+type logFilter struct {
+	since  time.Time
+	until  time.Time
+	author string
+	grep   *regexp.Regexp
+	paths  []string
+}
+
+// logEntry is the view of a commit exposed to --format templates and --json
+type logEntry struct {
+	Hash      string    `json:"hash"`
+	ShortHash string    `json:"-"`
+	Author    string    `json:"author"`
+	Date      time.Time `json:"date"`
+	Message   string    `json:"message"`
+	Branch    string    `json:"branch"`
+	Parents   []string  `json:"parents,omitempty"`
+	Paths     []string  `json:"paths,omitempty"`
 }
 
 func runLog(cmd *cobra.Command, args []string) error {
@@ -68,70 +114,199 @@ func runLog(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to open repository: %w", err)
 	}
 	
-	// Get current commit
-	currentCommit, err := r.GetCurrentCommit()
+	// Start from the given revision, or the current commit
+	revText, paths, err := logRevision(r, cmd, args)
+	if err != nil {
+		return err
+	}
+	rev, err := r.ParseRevision(revText)
 	if err != nil {
-		return fmt.Errorf("failed to get current commit: %w", err)
+		return fmt.Errorf("failed to resolve %s: %w", revText, err)
 	}
 	
-	// Parse date filters
-	var sinceTime, untilTime time.Time
+	// Parse filters
+	var filter logFilter
 	if logSince != "" {
-		sinceTime, err = time.Parse("2006-01-02", logSince)
+		filter.since, err = repo.ParseDate(logSince, false)
 		if err != nil {
 			return fmt.Errorf("invalid since date format: %w", err)
 		}
 	}
 	if logUntil != "" {
-		untilTime, err = time.Parse("2006-01-02", logUntil)
+		filter.until, err = repo.ParseDate(logUntil, true)
 		if err != nil {
 			return fmt.Errorf("invalid until date format: %w", err)
 		}
 	}
+	filter.author = strings.ToLower(logAuthor)
+	if logGrep != "" {
+		filter.grep, err = regexp.Compile(logGrep)
+		if err != nil {
+			return fmt.Errorf("invalid grep pattern: %w", err)
+		}
+	}
+	for _, arg := range paths {
+		filter.paths = append(filter.paths, r.Layout().TreePath(logPathspec(arg)))
+	}
+	if rev.Asset != 0 {
+		filter.paths = append(filter.paths, rev.AssetPath())
+	}
+	
+	// Parse output template
+	var tmpl *template.Template
+	if logFormat != "" {
+		tmpl, err = template.New("format").Parse(logFormat)
+		if err != nil {
+			return fmt.Errorf("invalid format template: %w", err)
+		}
+	}
 	
 	// Show commit history
-	if err := showCommitHistory(r, currentCommit, logOneline, logMaxCount, sinceTime, untilTime); err != nil {
+	entries, err := collectCommitHistory(r, rev.Commit, filter, logMaxCount)
+	if err != nil {
 		return fmt.Errorf("failed to show commit history: %w", err)
 	}
 	
-	return nil
+	return printCommitHistory(entries, tmpl)
 }
 
-func showCommitHistory(r *repo.Repository, startCommit string, oneline bool, maxCount int, since, until time.Time) error {
-	// TODO: Implement proper commit history traversal
-	// For now, just show the current commit
+// collectCommitHistory walks the commit graph from startCommit and returns up to maxCount matching commits
+func collectCommitHistory(r *repo.Repository, startCommit string, filter logFilter, maxCount int) ([]logEntry, error) {
+	var entries []logEntry
 	
-	objType, data, err := r.ReadObject(startCommit)
-	if err != nil {
-		return fmt.Errorf("failed to read commit object: %w", err)
-	}
+	err := r.WalkCommits([]string{startCommit}, func(hash string, commit *repo.Commit) error {
+		// Apply filters
+		if !filter.since.IsZero() && commit.Timestamp.Before(filter.since) {
+			return nil
+		}
+		if !filter.until.IsZero() && commit.Timestamp.After(filter.until) {
+			return nil
+		}
+		if filter.author != "" && !strings.Contains(strings.ToLower(commit.Author), filter.author) {
+			return nil
+		}
+		if filter.grep != nil && !filter.grep.MatchString(commit.Message) {
+			return nil
+		}
+		
+		entry := logEntry{
+			Hash:      hash,
+			ShortHash: hash[:8],
+			Author:    commit.Author,
+			Date:      commit.Timestamp,
+			Message:   commit.Message,
+			Branch:    commit.Branch,
+			Parents:   commit.ParentHashes(),
+		}
+		
+		if len(filter.paths) > 0 {
+			changed, err := r.ChangedPaths(commit)
+			if err != nil {
+				return err
+			}
+			entry.Paths = matchPathspecs(changed, filter.paths)
+			if len(entry.Paths) == 0 {
+				return nil
+			}
+		}
+		
+		entries = append(entries, entry)
+		if maxCount > 0 && len(entries) >= maxCount {
+			return repo.ErrStopWalk
+		}
+		return nil
+	})
 	
-	if objType != "commit" {
-		return fmt.Errorf("object is not a commit")
+	return entries, err
+}
+
+func printCommitHistory(entries []logEntry, tmpl *template.Template) error {
+	if jsonOutput {
+		if entries == nil {
+			entries = []logEntry{}
+		}
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal log: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
 	}
 	
-	var commit repo.Commit
-	if err := json.Unmarshal(data, &commit); err != nil {
-		return fmt.Errorf("failed to unmarshal commit: %w", err)
+	for _, entry := range entries {
+		// Format output
+		switch {
+		case tmpl != nil:
+			if err := tmpl.Execute(os.Stdout, entry); err != nil {
+				return fmt.Errorf("failed to format commit: %w", err)
+			}
+			fmt.Println()
+		case logOneline:
+			fmt.Printf("%s %s\n", entry.ShortHash, entry.Message)
+		default:
+			fmt.Printf("commit %s\n", entry.Hash)
+			if len(entry.Parents) > 1 {
+				fmt.Printf("Merge:  %s\n", strings.Join(shortHashes(entry.Parents), " "))
+			}
+			fmt.Printf("Author: %s\n", entry.Author)
+			fmt.Printf("Date:   %s\n", entry.Date.Format(time.RFC3339))
+			fmt.Printf("\n    %s\n\n", entry.Message)
+		}
 	}
 	
-	// Apply filters
-	if !since.IsZero() && commit.Timestamp.Before(since) {
-		return nil
+	return nil
+}
+
+// logRevision splits the arguments into the revision to start from (HEAD if none)
+// and path filters. At most one argument may come before --; without --, a first
+// argument is taken as a revision if it names one.
+func logRevision(r *repo.Repository, cmd *cobra.Command, args []string) (string, []string, error) {
+	if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+		switch dash {
+		case 0:
+			return "HEAD", args[dash:], nil
+		case 1:
+			return args[0], args[dash:], nil
+		}
+		return "", nil, fmt.Errorf("log takes at most one revision, got %s", strings.Join(args[:dash], " "))
 	}
-	if !until.IsZero() && commit.Timestamp.After(until) {
-		return nil
+	if len(args) > 0 {
+		if _, err := r.ParseRevision(args[0]); err == nil {
+			return args[0], args[1:], nil
+		}
 	}
-	
-	// Format output
-	if oneline {
-		fmt.Printf("%s %s\n", commit.ID[:8], commit.Message)
-	} else {
-		fmt.Printf("commit %s\n", commit.ID)
-		fmt.Printf("Author: %s\n", commit.Author)
-		fmt.Printf("Date:   %s\n", commit.Timestamp.Format(time.RFC3339))
-		fmt.Printf("\n    %s\n\n", commit.Message)
+	return "HEAD", args, nil
+}
+
+// logPathspec normalizes a path filter; a bare number selects the asset folder with that ID
+func logPathspec(arg string) string {
+	if _, err := strconv.Atoi(arg); err == nil {
+		return "assets/" + arg
 	}
-	
-	return nil
-} 
\ No newline at end of file
+	return strings.TrimSuffix(filepath.ToSlash(filepath.Clean(arg)), "/")
+}
+
+// matchPathspecs returns the paths that equal or lie below any of the pathspecs
+func matchPathspecs(paths, specs []string) []string {
+	var matched []string
+	for _, p := range paths {
+		for _, spec := range specs {
+			if spec == "." || p == spec || strings.HasPrefix(p, spec+"/") {
+				matched = append(matched, p)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+func shortHashes(hashes []string) []string {
+	short := make([]string, len(hashes))
+	for i, h := range hashes {
+		if len(h) > 8 {
+			h = h[:8]
+		}
+		short[i] = h
+	}
+	return short
+}
@@ -0,0 +1,274 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rdb/cli/internal/repo"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mergeMessage string
+	mergeAuthor  string
+	mergeAbort   bool
+	mergeNoFF    bool
+)
+
+// mergeCmd represents the merge command
+var mergeCmd = &cobra.Command{
+	Use:   "merge <branch>",
+	Short: "Merge another branch into the current branch",
+	Long: `Merge another branch into the current branch.
+
+Finds the merge base of both branches and merges their trees file by file.
+meta.json files changed on both sides are merged field by field: tags and
+attributes are combined and the highest version wins. XML payloads of types
+with a key path, such as the built-in treasure, zone transition, resurrection
+point and PhysX types, are merged element by element, matching repeated
+elements by that key path (see 'rdb type'). Other files changed on both sides, and XML edits that collide, are
+marked as unmerged (U in 'rdb status'); the working tree keeps the current
+branch's version, or the XML merge with the current branch's values where they
+collide.
+
+Resolve conflicts with 'rdb checkout --ours/--theirs <path>' or by editing the
+file and running 'rdb add', then finish with 'rdb commit'. A merge whose
+metadata fails schema validation is also left in progress for fixing.
+
+Examples:
+  rdb merge winter-event
+  rdb merge winter-event --no-ff -m "Bring in winter content"
+  rdb merge --abort`,
+	RunE: runMerge,
+}
+
+func init() {
+	rootCmd.AddCommand(mergeCmd)
+	
+	// Local flags
+	mergeCmd.Flags().StringVarP(&mergeMessage, "message", "m", "", "merge commit message")
+	mergeCmd.Flags().StringVar(&mergeAuthor, "author", "", "author (format: 'Name <email>')")
+	mergeCmd.Flags().BoolVar(&mergeAbort, "abort", false, "abandon an in-progress merge and restore the pre-merge state")
+	mergeCmd.Flags().BoolVar(&mergeNoFF, "no-ff", false, "create a merge commit even when a fast-forward is possible")
+}
+
+func runMerge(cmd *cobra.Command, args []string) error {
+	// Always use current working directory
+	repoPath := "."
+	
+	// Convert to absolute path
+	absPath, err := filepath.Abs(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve path: %w", err)
+	}
+	
+	// Safety check: prevent operations in system directories
+	if strings.Contains(strings.ToLower(absPath), "c:\\windows\\system32") {
+		return fmt.Errorf("cannot operate on RDB repository in system directory: %s", absPath)
+	}
+	
+	// Check if repository exists
+	if !repo.IsRepository(absPath) {
+		return fmt.Errorf("not an RDB repository: %s", absPath)
+	}
+	
+	// Open repository
+	r, err := repo.OpenRepository(absPath)
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+	
+	// Hold the index for the whole update so concurrent rdb runs do not lose changes
+	lock, err := r.LockIndex()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	
+	if mergeAbort {
+		return abortMerge(r)
+	}
+	
+	if len(args) != 1 {
+		return fmt.Errorf("usage: rdb merge <branch>")
+	}
+	
+	if pending, _, err := r.ReadMergeState(); err != nil {
+		return err
+	} else if pending != "" {
+		return fmt.Errorf("a merge is already in progress (commit it or run 'rdb merge --abort')")
+	}
+	
+	branch, err := r.GetCurrentBranch()
+	if err != nil {
+		return fmt.Errorf("failed to get current branch: %w", err)
+	}
+	
+	oursHash, err := r.GetCurrentCommit()
+	if err != nil {
+		return fmt.Errorf("failed to get current commit: %w", err)
+	}
+	theirsHash, err := r.ResolveRevision(args[0])
+	if err != nil {
+		return err
+	}
+	
+	baseHash, err := r.MergeBase(oursHash, theirsHash)
+	if err != nil {
+		return fmt.Errorf("failed to find merge base: %w", err)
+	}
+	
+	if baseHash == theirsHash {
+		fmt.Println("Already up to date.")
+		return nil
+	}
+	
+	ours, err := r.ReadCommit(oursHash)
+	if err != nil {
+		return err
+	}
+	theirs, err := r.ReadCommit(theirsHash)
+	if err != nil {
+		return err
+	}
+	
+	// Fast-forward when the current branch has no commits of its own
+	if baseHash == oursHash && !mergeNoFF {
+		if err := r.CheckoutTree(theirs.Tree, false); err != nil {
+			return fmt.Errorf("cannot fast-forward: %w", err)
+		}
+		change := repo.RefChange{Command: "merge " + args[0], Message: "fast-forward"}
+		if err := r.CompareAndSwapBranch(branch, oursHash, theirsHash, change); err != nil {
+			return err
+		}
+		fmt.Printf("Fast-forward %s..%s\n", oursHash[:8], theirsHash[:8])
+		return nil
+	}
+	
+	base, err := r.ReadCommit(baseHash)
+	if err != nil {
+		return err
+	}
+	
+	result, err := r.MergeTrees(base.Tree, ours.Tree, theirs.Tree)
+	if err != nil {
+		return fmt.Errorf("failed to merge trees: %w", err)
+	}
+	
+	if err := r.ApplyMerge(ours.Tree, result); err != nil {
+		var conflict *repo.CheckoutConflictError
+		if errors.As(err, &conflict) {
+			return fmt.Errorf("cannot merge with local changes; commit or discard them first: %w", err)
+		}
+		return fmt.Errorf("failed to apply merge: %w", err)
+	}
+	
+	for _, p := range result.Merged {
+		fmt.Printf("Auto-merged %s\n", p)
+	}
+	
+	message := mergeMessage
+	if message == "" {
+		message = fmt.Sprintf("Merge branch '%s' into %s", args[0], branch)
+	}
+	
+	if len(result.Conflicts) > 0 {
+		if err := r.WriteMergeState(theirsHash, message); err != nil {
+			return err
+		}
+		
+		paths := make([]string, 0, len(result.Conflicts))
+		for p := range result.Conflicts {
+			paths = append(paths, p)
+		}
+		sort.Strings(paths)
+		for _, p := range paths {
+			fmt.Printf("CONFLICT: %s\n", p)
+		}
+		return fmt.Errorf("automatic merge failed; resolve with 'rdb checkout --ours/--theirs <path>' or 'rdb add <path>', then run 'rdb commit'")
+	}
+	
+	idx, err := r.LoadIndex()
+	if err != nil {
+		return fmt.Errorf("failed to load index: %w", err)
+	}
+	
+	// Reject merged metadata that does not satisfy its type's schema, as rdb commit does
+	validationErrors, err := r.ValidateIndex(idx)
+	if err != nil {
+		return fmt.Errorf("failed to validate metadata: %w", err)
+	}
+	if len(validationErrors) > 0 {
+		if err := r.WriteMergeState(theirsHash, message); err != nil {
+			return err
+		}
+		fmt.Println("Merged metadata failed validation; fix it, 'rdb add' the files and run 'rdb commit'.")
+		return repo.ValidationErrors(validationErrors)
+	}
+	
+	author := mergeAuthor
+	if author == "" {
+		author = repo.DefaultAuthor
+	}
+	
+	treeHash, err := r.BuildTree(idx)
+	if err != nil {
+		return fmt.Errorf("failed to build tree: %w", err)
+	}
+	
+	commit := &repo.Commit{
+		ID:        repo.GenerateID(),
+		Author:    author,
+		Timestamp: time.Now(),
+		Message:   message,
+		Branch:    branch,
+		Parents:   []string{oursHash, theirsHash},
+		Tree:      treeHash,
+	}
+	
+	commitHash, err := r.WriteObject("commit", commit)
+	if err != nil {
+		return fmt.Errorf("failed to write commit object: %w", err)
+	}
+	change := repo.RefChange{Command: "merge " + args[0], Message: firstLine(message)}
+	if err := r.CompareAndSwapBranch(branch, oursHash, commitHash, change); err != nil {
+		return err
+	}
+	
+	fmt.Printf("Merge made commit %s\n", commitHash[:8])
+	return nil
+}
+
+// abortMerge restores the working tree and index to the current commit and clears the merge state
+func abortMerge(r *repo.Repository) error {
+	pending, _, err := r.ReadMergeState()
+	if err != nil {
+		return err
+	}
+	if pending == "" {
+		return fmt.Errorf("no merge in progress")
+	}
+	
+	head, err := r.GetCurrentCommit()
+	if err != nil {
+		return fmt.Errorf("failed to get current commit: %w", err)
+	}
+	commit, err := r.ReadCommit(head)
+	if err != nil {
+		return err
+	}
+	
+	if err := r.CheckoutTree(commit.Tree, true); err != nil {
+		return fmt.Errorf("failed to restore working tree: %w", err)
+	}
+	if err := r.ClearMergeState(); err != nil {
+		return err
+	}
+	
+	fmt.Println("Merge aborted")
+	return nil
+}
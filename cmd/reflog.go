@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rdb/cli/internal/repo"
+	"github.com/spf13/cobra"
+)
+
+var (
+	reflogMaxCount int
+)
+
+// reflogCmd represents the reflog command
+var reflogCmd = &cobra.Command{
+	Use:   "reflog [<branch>|HEAD]",
+	Short: "Show where a branch or HEAD has pointed",
+	Long: `Show every movement of a branch or of HEAD, newest first.
+
+Each commit, amend, merge, branch creation and switch is recorded in .rdb/logs
+with the old and new commit, the author, the time and the command. Entry n
+can be named as <branch>@{n} (or HEAD@{n}, or @{n} for the current branch)
+in any command that takes a revision, so a commit lost to a bad --amend can
+be recovered with 'rdb branch rescue main@{1}'.
+
+Commits named by a reflog are kept by 'rdb gc'.
+
+Examples:
+  rdb reflog
+  rdb reflog main
+  rdb log main@{1}
+  rdb branch rescue HEAD@{2}`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runReflog,
+}
+
+func init() {
+	rootCmd.AddCommand(reflogCmd)
+	
+	// Local flags
+	reflogCmd.Flags().IntVarP(&reflogMaxCount, "max-count", "n", 0, "limit number of entries")
+}
+
+// reflogLine is one reflog entry as printed with --json
+type reflogLine struct {
+	Selector string `json:"selector"`
+	repo.ReflogEntry
+}
+
+func runReflog(cmd *cobra.Command, args []string) error {
+	// Always use current working directory
+	repoPath := "."
+	
+	// Convert to absolute path
+	absPath, err := filepath.Abs(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve path: %w", err)
+	}
+	
+	// Safety check: prevent operations in system directories
+	if strings.Contains(strings.ToLower(absPath), "c:\\windows\\system32") {
+		return fmt.Errorf("cannot operate on RDB repository in system directory: %s", absPath)
+	}
+	
+	// Check if repository exists
+	if !repo.IsRepository(absPath) {
+		return fmt.Errorf("not an RDB repository: %s", absPath)
+	}
+	
+	// Open repository
+	r, err := repo.OpenRepository(absPath)
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+	
+	name := "HEAD"
+	if len(args) == 1 {
+		name = args[0]
+		if name != "HEAD" && !r.BranchExists(name) {
+			return fmt.Errorf("branch not found: %s", name)
+		}
+	}
+	
+	entries, err := r.ReadReflog(repo.ReflogRef(name))
+	if err != nil {
+		return err
+	}
+	if reflogMaxCount > 0 && len(entries) > reflogMaxCount {
+		entries = entries[:reflogMaxCount]
+	}
+	
+	if jsonOutput {
+		lines := make([]reflogLine, 0, len(entries))
+		for n, entry := range entries {
+			lines = append(lines, reflogLine{Selector: fmt.Sprintf("%s@{%d}", name, n), ReflogEntry: entry})
+		}
+		data, err := json.MarshalIndent(lines, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal reflog: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	
+	for n, entry := range entries {
+		summary := entry.Command
+		if entry.Message != "" {
+			summary += ": " + entry.Message
+		}
+		fmt.Printf("%s %s@{%d}: %s (%s, %s)\n", shortHashes([]string{entry.New})[0], name, n, summary, entry.Author, entry.Time.Format(time.RFC3339))
+	}
+	
+	return nil
+}
+
+// firstLine returns the first line of a commit message, for one-line summaries
+func firstLine(message string) string {
+	if i := strings.IndexByte(message, '\n'); i >= 0 {
+		return message[:i]
+	}
+	return message
+}
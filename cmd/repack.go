@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/rdb/cli/internal/repo"
+	"github.com/spf13/cobra"
+)
+
+var (
+	repackWindow int
+	repackDepth  int
+)
+
+// repackCmd represents the repack command
+var repackCmd = &cobra.Command{
+	Use:   "repack",
+	Short: "Pack reachable objects into a single pack file",
+	Long: `Pack every object reachable from a branch, tag, in-progress merge or the
+index into a new pack file in .rdb/packs, read every object back from it, then
+remove older packs and the loose copies of packed objects.
+
+Revisions of the same file (texture mips, XML data) are stored as binary deltas
+against each other. Each pack has a sorted index, so objects are found with a
+binary search whatever the repository size. Objects are read from packs and
+loose files transparently.
+
+Unreachable objects are left as loose objects for 'rdb gc' to prune.
+
+Examples:
+  rdb repack
+  rdb repack --window 20 --depth 10`,
+	Args: cobra.NoArgs,
+	RunE: runRepack,
+}
+
+func init() {
+	rootCmd.AddCommand(repackCmd)
+
+	// Local flags
+	repackCmd.Flags().IntVar(&repackWindow, "window", repo.DefaultPackWindow, "number of preceding blobs tried as delta bases (0 disables deltas)")
+	repackCmd.Flags().IntVar(&repackDepth, "depth", repo.DefaultPackDepth, fmt.Sprintf("maximum delta chain length (at most %d)", repo.MaxPackDepth))
+}
+
+func runRepack(cmd *cobra.Command, args []string) error {
+	// Always use current working directory
+	repoPath := "."
+
+	// Convert to absolute path
+	absPath, err := filepath.Abs(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve path: %w", err)
+	}
+
+	// Safety check: prevent operations in system directories
+	if strings.Contains(strings.ToLower(absPath), "c:\\windows\\system32") {
+		return fmt.Errorf("cannot operate on RDB repository in system directory: %s", absPath)
+	}
+
+	// Check if repository exists
+	if !repo.IsRepository(absPath) {
+		return fmt.Errorf("not an RDB repository: %s", absPath)
+	}
+
+	// Open repository
+	r, err := repo.OpenRepository(absPath)
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	if repackWindow < 0 || repackDepth < 0 {
+		return fmt.Errorf("--window and --depth must not be negative")
+	}
+	if repackDepth > repo.MaxPackDepth {
+		return fmt.Errorf("--depth must be at most %d", repo.MaxPackDepth)
+	}
+
+	report, err := r.Repack(repo.RepackOptions{Window: repackWindow, Depth: repackDepth})
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal report: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Printf("Packed %d object(s) (%d as deltas) into %s (%s)\n", report.Objects, report.Deltas, report.Pack, repo.FormatBytes(report.Size))
+	fmt.Printf("Removed %d loose object(s) and %d old pack(s)\n", report.LooseRemoved, report.PacksRemoved)
+	if report.Unpacked > 0 {
+		fmt.Printf("Unpacked %d unreachable object(s); run 'rdb gc' to prune them\n", report.Unpacked)
+	}
+	return nil
+}
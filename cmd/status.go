@@ -9,7 +9,10 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var porcelain bool
+var porcelain string
+
+// porcelainVersion is the newest --porcelain format understood by this build
+const porcelainVersion = "v1"
 
 // statusCmd represents the status command
 var statusCmd = &cobra.Command{
@@ -18,8 +21,13 @@ var statusCmd = &cobra.Command{
 	Long: `Show the status of the working tree.
 
 Shows changes: A (added), M (modified), D (deleted), R (renamed), U (unmerged).
+Files under assets/ that are not staged are listed as untracked (??).
 
-Use --porcelain for stable machine output.`,
+Use --porcelain for stable machine output. The format is versioned; --porcelain
+is the same as --porcelain=v1, which prints a "# porcelain v1" header, "# branch"
+and "# commit" lines, a "# draft <asset-dir>" line per staged draft asset,
+then one "XY <path>" line per change where X is the staged
+and Y the unstaged status. Renames are printed as "XY <orig> -> <path>".`,
 	RunE: runStatus,
 }
 
@@ -27,7 +35,8 @@ func init() {
 	rootCmd.AddCommand(statusCmd)
 	
 	// Local flags
-	statusCmd.Flags().BoolVar(&porcelain, "porcelain", false, "give stable machine output")
+	statusCmd.Flags().StringVar(&porcelain, "porcelain", "", "give stable machine output in the given format version")
+	statusCmd.Flags().Lookup("porcelain").NoOptDefVal = porcelainVersion
 }
 
 func runStatus(cmd *cobra.Command, args []string) error {
@@ -68,19 +77,134 @@ func runStatus(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to get current commit: %w", err)
 	}
 	
-	if porcelain {
+	// Refreshing the index is only an optimization, so skip it if another command holds it
+	lock, err := r.TryLockIndex()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	
+	// Compare HEAD, index and working tree
+	idx, err := r.LoadIndex()
+	if err != nil {
+		return fmt.Errorf("failed to load index: %w", err)
+	}
+	
+	status, err := r.Status(idx)
+	if err != nil {
+		return fmt.Errorf("failed to compute status: %w", err)
+	}
+	
+	// Cache refreshed stat data so unchanged files are not rehashed next time
+	if status.Refreshed && lock != nil {
+		if err := r.SaveIndex(idx); err != nil {
+			return fmt.Errorf("failed to save index: %w", err)
+		}
+	}
+	
+	// Staged assets that will be left out of release builds
+	drafts, err := r.DraftAssets(idx)
+	if err != nil {
+		return fmt.Errorf("failed to read draft status: %w", err)
+	}
+	
+	if porcelain != "" {
+		if porcelain != porcelainVersion {
+			return fmt.Errorf("unsupported porcelain version: %s (supported: %s)", porcelain, porcelainVersion)
+		}
+		
 		// Machine-readable output
-		fmt.Printf("branch %s\n", branch)
-		fmt.Printf("commit %s\n", commit)
-		// TODO: Add staged/unstaged changes
+		fmt.Printf("# porcelain %s\n", porcelainVersion)
+		fmt.Printf("# branch %s\n", branch)
+		fmt.Printf("# commit %s\n", commit)
+		for _, draft := range drafts {
+			fmt.Printf("# draft %s\n", draft)
+		}
+		for _, entry := range status.Entries {
+			if entry.OrigPath != "" {
+				fmt.Printf("%c%c %s -> %s\n", entry.Staged, entry.Unstaged, entry.OrigPath, entry.Path)
+			} else {
+				fmt.Printf("%c%c %s\n", entry.Staged, entry.Unstaged, entry.Path)
+			}
+		}
 	} else {
 		// Human-readable output
 		fmt.Printf("On branch %s\n", branch)
 		fmt.Printf("commit %s\n\n", commit)
 		
-		// TODO: Show working tree status
-		fmt.Println("No changes to commit, working tree clean")
+		if len(drafts) > 0 {
+			fmt.Println("Draft assets (excluded from builds without --include-drafts):")
+			for _, draft := range drafts {
+				fmt.Printf("  %s\n", draft)
+			}
+			fmt.Println()
+		}
+		
+		if status.Clean() {
+			fmt.Println("No changes to commit, working tree clean")
+			return nil
+		}
+		
+		printStatusSection("Changes to be committed:", status.Entries, func(e repo.StatusEntry) byte {
+			if e.Staged == repo.StatusUntracked {
+				return repo.StatusUnmodified
+			}
+			return e.Staged
+		})
+		printStatusSection("Changes not staged for commit:", status.Entries, func(e repo.StatusEntry) byte {
+			if e.Unstaged == repo.StatusUntracked {
+				return repo.StatusUnmodified
+			}
+			return e.Unstaged
+		})
+		printStatusSection("Untracked files:", status.Entries, func(e repo.StatusEntry) byte {
+			if e.Staged == repo.StatusUntracked {
+				return e.Staged
+			}
+			return repo.StatusUnmodified
+		})
 	}
 	
 	return nil
-} 
\ No newline at end of file
+}
+
+// statusLabels maps status codes to the words used in human-readable output
+var statusLabels = map[byte]string{
+	repo.StatusAdded:    "added:",
+	repo.StatusModified: "modified:",
+	repo.StatusDeleted:  "deleted:",
+	repo.StatusRenamed:  "renamed:",
+	repo.StatusUnmerged: "unmerged:",
+}
+
+// printStatusSection prints the entries for which code returns something other than unmodified
+func printStatusSection(title string, entries []repo.StatusEntry, code func(repo.StatusEntry) byte) {
+	var lines []string
+	for _, e := range entries {
+		c := code(e)
+		if c == repo.StatusUnmodified {
+			continue
+		}
+		
+		path := e.Path
+		if e.OrigPath != "" && c == repo.StatusRenamed {
+			path = e.OrigPath + " -> " + e.Path
+		}
+		
+		if label, ok := statusLabels[c]; ok {
+			lines = append(lines, fmt.Sprintf("  %-10s %s", label, path))
+		} else {
+			lines = append(lines, "  "+path)
+		}
+	}
+	
+	if len(lines) == 0 {
+		return
+	}
+	
+	fmt.Println(title)
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+	fmt.Println()
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/rdb/cli/internal/repo"
+	"github.com/spf13/cobra"
+)
+
+var (
+	switchCreate bool
+	switchForce  bool
+)
+
+// switchCmd represents the switch command
+var switchCmd = &cobra.Command{
+	Use:   "switch <branch>",
+	Short: "Switch branches",
+	Long: `Switch to another branch and rewrite the files under assets/ to match it.
+
+Local changes to files that are the same on both branches are kept. If a local
+change would be overwritten, the switch is refused unless --force is given, in
+which case the change is discarded.
+
+Examples:
+  rdb switch winter-event
+  rdb switch -c summer-event
+  rdb switch --force main`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSwitch,
+}
+
+func init() {
+	rootCmd.AddCommand(switchCmd)
+	
+	// Local flags
+	switchCmd.Flags().BoolVarP(&switchCreate, "create", "c", false, "create the branch at the current commit before switching")
+	switchCmd.Flags().BoolVarP(&switchForce, "force", "f", false, "discard local changes that would be overwritten")
+}
+
+func runSwitch(cmd *cobra.Command, args []string) error {
+	// Always use current working directory
+	repoPath := "."
+	
+	// Convert to absolute path
+	absPath, err := filepath.Abs(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve path: %w", err)
+	}
+	
+	// Safety check: prevent operations in system directories
+	if strings.Contains(strings.ToLower(absPath), "c:\\windows\\system32") {
+		return fmt.Errorf("cannot operate on RDB repository in system directory: %s", absPath)
+	}
+	
+	// Check if repository exists
+	if !repo.IsRepository(absPath) {
+		return fmt.Errorf("not an RDB repository: %s", absPath)
+	}
+	
+	// Open repository
+	r, err := repo.OpenRepository(absPath)
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+	
+	return switchBranch(r, "switch", args[0], switchCreate, switchForce)
+}
+
+// switchBranch checks out the tree of the named branch and points HEAD at it;
+// command names the rdb command in the reflog
+func switchBranch(r *repo.Repository, command, name string, create, force bool) error {
+	lock, err := r.LockIndex()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	
+	// The merge state belongs to the current branch; a commit elsewhere would pick it up
+	if pending, _, err := r.ReadMergeState(); err != nil {
+		return err
+	} else if pending != "" {
+		return fmt.Errorf("a merge is in progress (commit it or run 'rdb merge --abort')")
+	}
+	
+	current, err := r.GetCurrentBranch()
+	if err != nil {
+		return fmt.Errorf("failed to get current branch: %w", err)
+	}
+	
+	if create {
+		head, err := r.GetCurrentCommit()
+		if err != nil {
+			return fmt.Errorf("failed to get current commit: %w", err)
+		}
+		if err := r.CreateBranch(name, head); err != nil {
+			return fmt.Errorf("failed to create branch: %w", err)
+		}
+	} else if name == current {
+		fmt.Printf("Already on '%s'\n", name)
+		return nil
+	}
+	
+	tip, err := r.ReadBranch(name)
+	if err != nil {
+		return err
+	}
+	commit, err := r.ReadCommit(tip)
+	if err != nil {
+		return fmt.Errorf("failed to read target commit: %w", err)
+	}
+	
+	if err := r.CheckoutTree(commit.Tree, force); err != nil {
+		return fmt.Errorf("cannot switch to %s: %w", name, err)
+	}
+	
+	change := repo.RefChange{Command: command, Message: fmt.Sprintf("moving from %s to %s", current, name)}
+	if err := r.SetHead(name, change); err != nil {
+		return err
+	}
+	
+	if create {
+		fmt.Printf("Switched to a new branch '%s'\n", name)
+	} else {
+		fmt.Printf("Switched to branch '%s'\n", name)
+	}
+	
+	return nil
+}
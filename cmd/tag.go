@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rdb/cli/internal/repo"
+	"github.com/spf13/cobra"
+)
+
+var (
+	tagAnnotate bool
+	tagMessage  string
+	tagTagger   string
+	tagDelete   bool
+	tagForce    bool
+	tagList     bool
+)
+
+// tagCmd represents the tag command
+var tagCmd = &cobra.Command{
+	Use:   "tag [<name> [<revision>]]",
+	Short: "Create, list or delete tags",
+	Long: `Create, list or delete tags.
+
+Without arguments, lists all tags (-l takes glob patterns to filter them).
+With a name, tags the current commit, or the given revision.
+
+A plain tag is a lightweight ref to a commit. With -a or -m an annotated tag
+object is written as well, recording the tagger, the date and a message.
+Tags are meant to name releases for good: an existing tag is only moved
+with --force.
+
+A tag can be used wherever a revision is expected, e.g. 'rdb build --rev v1.2'.
+
+Examples:
+  rdb tag
+  rdb tag -l "v1.*"
+  rdb tag v1.2
+  rdb tag -a v1.2 -m "Winter event release"
+  rdb tag -m "Hotfix" v1.2.1 main~1
+  rdb tag -d v1.2`,
+	RunE: runTag,
+}
+
+func init() {
+	rootCmd.AddCommand(tagCmd)
+	
+	// Local flags
+	tagCmd.Flags().BoolVarP(&tagAnnotate, "annotate", "a", false, "create an annotated tag object")
+	tagCmd.Flags().StringVarP(&tagMessage, "message", "m", "", "tag message (implies --annotate)")
+	tagCmd.Flags().StringVar(&tagTagger, "tagger", "", "tagger of an annotated tag (format: 'Name <email>')")
+	tagCmd.Flags().BoolVarP(&tagDelete, "delete", "d", false, "delete tags")
+	tagCmd.Flags().BoolVarP(&tagForce, "force", "f", false, "replace an existing tag")
+	tagCmd.Flags().BoolVarP(&tagList, "list", "l", false, "list tags matching the given patterns")
+}
+
+// tagInfo is the view of a tag printed by 'rdb tag' and --json
+type tagInfo struct {
+	Name      string    `json:"name"`
+	Object    string    `json:"object"` // what the tag ref points to
+	Commit    string    `json:"commit"`
+	Annotated bool      `json:"annotated"`
+	Tagger    string    `json:"tagger,omitempty"`
+	Date      time.Time `json:"date,omitempty"`
+	Message   string    `json:"message,omitempty"`
+}
+
+func runTag(cmd *cobra.Command, args []string) error {
+	// Always use current working directory
+	repoPath := "."
+	
+	// Convert to absolute path
+	absPath, err := filepath.Abs(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve path: %w", err)
+	}
+	
+	// Safety check: prevent operations in system directories
+	if strings.Contains(strings.ToLower(absPath), "c:\\windows\\system32") {
+		return fmt.Errorf("cannot operate on RDB repository in system directory: %s", absPath)
+	}
+	
+	// Check if repository exists
+	if !repo.IsRepository(absPath) {
+		return fmt.Errorf("not an RDB repository: %s", absPath)
+	}
+	
+	// Open repository
+	r, err := repo.OpenRepository(absPath)
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+	
+	switch {
+	case tagDelete:
+		if len(args) == 0 {
+			return fmt.Errorf("tag name required")
+		}
+		for _, name := range args {
+			// A damaged tag ref can still be deleted
+			object, readErr := r.ReadTag(name)
+			if readErr != nil && !r.TagExists(name) {
+				return readErr
+			}
+			if err := r.DeleteTag(name); err != nil {
+				return fmt.Errorf("failed to delete tag: %w", err)
+			}
+			if readErr != nil {
+				fmt.Printf("Deleted tag %s\n", name)
+				continue
+			}
+			fmt.Printf("Deleted tag %s (was %s)\n", name, object[:8])
+		}
+		return nil
+		
+	case tagList || len(args) == 0:
+		return listTags(r, args)
+	}
+	
+	if len(args) > 2 {
+		return fmt.Errorf("too many arguments")
+	}
+	if err := repo.ValidateRefName(args[0]); err != nil {
+		return err
+	}
+	if r.TagExists(args[0]) && !tagForce {
+		return fmt.Errorf("tag already exists: %s (use --force to move it)", args[0])
+	}
+	
+	target, err := r.GetCurrentCommit()
+	if err != nil {
+		return fmt.Errorf("failed to get current commit: %w", err)
+	}
+	if len(args) == 2 {
+		target, err = r.ResolveRevision(args[1])
+		if err != nil {
+			return err
+		}
+	}
+	commitHash := target
+	
+	if tagAnnotate || tagMessage != "" {
+		if tagMessage == "" {
+			return fmt.Errorf("tag message required (use -m)")
+		}
+		tagger := tagTagger
+		if tagger == "" {
+			tagger = repo.DefaultAuthor
+		}
+		target, err = r.WriteObject("tag", &repo.Tag{
+			Object:    commitHash,
+			Type:      "commit",
+			Name:      args[0],
+			Tagger:    tagger,
+			Timestamp: time.Now(),
+			Message:   tagMessage,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write tag object: %w", err)
+		}
+	}
+	
+	if err := r.CreateTag(args[0], target, tagForce); err != nil {
+		return fmt.Errorf("failed to create tag: %w", err)
+	}
+	
+	fmt.Printf("Created tag %s at %s\n", args[0], commitHash[:8])
+	return nil
+}
+
+// listTags prints the tags matching any of the glob patterns, or all tags
+func listTags(r *repo.Repository, patterns []string) error {
+	names, err := r.ListTags()
+	if err != nil {
+		return err
+	}
+	
+	tags := []tagInfo{}
+	for _, name := range names {
+		if len(patterns) > 0 && !matchTagPattern(name, patterns) {
+			continue
+		}
+		info, err := readTagInfo(r, name)
+		if err != nil {
+			return err
+		}
+		tags = append(tags, *info)
+	}
+	
+	if jsonOutput {
+		data, err := json.MarshalIndent(tags, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal tags: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	
+	for _, tag := range tags {
+		// Annotated tags show their own message, lightweight tags the commit's
+		summary := firstLine(tag.Message)
+		if !tag.Annotated {
+			if commit, err := r.ReadCommit(tag.Commit); err == nil {
+				summary = firstLine(commit.Message)
+			}
+		}
+		fmt.Printf("%-20s %s %s\n", tag.Name, tag.Commit[:8], summary)
+	}
+	
+	return nil
+}
+
+// readTagInfo reads a tag ref and, for an annotated tag, its tag object
+func readTagInfo(r *repo.Repository, name string) (*tagInfo, error) {
+	object, err := r.ReadTag(name)
+	if err != nil {
+		return nil, err
+	}
+	commitHash, err := r.PeelTag(object)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tag %s: %w", name, err)
+	}
+	
+	info := &tagInfo{Name: name, Object: object, Commit: commitHash}
+	if object != commitHash {
+		tag, err := r.ReadTagObject(object)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tag %s: %w", name, err)
+		}
+		info.Annotated = true
+		info.Tagger = tag.Tagger
+		info.Date = tag.Timestamp
+		info.Message = tag.Message
+	}
+	return info, nil
+}
+
+// matchTagPattern reports whether a tag name matches any of the glob patterns
+func matchTagPattern(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
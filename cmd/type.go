@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/rdb/cli/internal/repo"
+	"github.com/spf13/cobra"
+)
+
+var (
+	typeName       string
+	typeExtensions []string
+	typeSchema     string
+	typeKeyPath    string
+)
+
+// typeCmd represents the type command
+var typeCmd = &cobra.Command{
+	Use:   "type",
+	Short: "Manage the asset type registry",
+	Long: `Manage the asset type registry stored in .rdb/types.json.
+
+Each type maps a numeric ID to a slug (written to the "type" field of meta.json),
+a display name, the payload file extensions it allows and an optional JSON Schema
+for meta.json. Repositories without a registry use the built-in types.
+
+--key-path makes diff and merge treat the type's payloads as XML and tells them
+how to match repeated elements: a comma-separated list of paths such as @id,
+@name or Key/@value, tried in order. The built-in XML types use @id; change it
+with 'rdb type set', or clear it to stop treating a type as XML.
+
+Examples:
+  rdb type list
+  rdb type add 1040001 quest_xml --name "Quest Data" --ext .xml
+  rdb type add 1040002 voice --name "Voice Lines" --ext .wav,.ogg --schema voice.schema.json
+  rdb type add 1040003 loot_xml --ext .xml --key-path @name,Key/@value
+  rdb type set 1000087 --key-path @id,Key/@name
+  rdb type remove 1040002`,
+}
+
+// typeListCmd represents the type list command
+var typeListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List registered asset types",
+	Args:  cobra.NoArgs,
+	RunE:  runTypeList,
+}
+
+// typeAddCmd represents the type add command
+var typeAddCmd = &cobra.Command{
+	Use:   "add <id> <slug>",
+	Short: "Register an asset type",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runTypeAdd,
+}
+
+// typeSetCmd represents the type set command
+var typeSetCmd = &cobra.Command{
+	Use:   "set <id> --key-path <paths>",
+	Short: "Change settings of a registered asset type",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runTypeSet,
+}
+
+// typeRemoveCmd represents the type remove command
+var typeRemoveCmd = &cobra.Command{
+	Use:   "remove <id>",
+	Short: "Unregister an asset type",
+	Long: `Unregister an asset type.
+
+Existing asset folders and meta.json files are left untouched.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTypeRemove,
+}
+
+func init() {
+	rootCmd.AddCommand(typeCmd)
+	typeCmd.AddCommand(typeListCmd, typeAddCmd, typeSetCmd, typeRemoveCmd)
+
+	// Local flags
+	typeAddCmd.Flags().StringVar(&typeName, "name", "", "display name (defaults to the slug)")
+	typeAddCmd.Flags().StringSliceVar(&typeExtensions, "ext", nil, "allowed payload file extensions (comma-separated; default allows any)")
+	typeAddCmd.Flags().StringVar(&typeSchema, "schema", "", "JSON Schema file for the type's meta.json")
+	typeAddCmd.Flags().StringVar(&typeKeyPath, "key-path", "", "paths identifying repeated XML elements in diff and merge")
+	typeSetCmd.Flags().StringVar(&typeKeyPath, "key-path", "", "paths identifying repeated XML elements in diff and merge (empty disables XML handling)")
+}
+
+// openTypeRegistry opens the repository in the current directory and loads its type registry
+func openTypeRegistry() (*repo.Repository, *repo.TypeRegistry, error) {
+	// Convert to absolute path
+	absPath, err := filepath.Abs(".")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve path: %w", err)
+	}
+
+	// Safety check: prevent operations in system directories
+	if strings.Contains(strings.ToLower(absPath), "c:\\windows\\system32") {
+		return nil, nil, fmt.Errorf("cannot operate on RDB repository in system directory: %s", absPath)
+	}
+
+	// Check if repository exists
+	if !repo.IsRepository(absPath) {
+		return nil, nil, fmt.Errorf("not an RDB repository: %s", absPath)
+	}
+
+	// Open repository
+	r, err := repo.OpenRepository(absPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	registry, err := r.LoadTypes()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return r, registry, nil
+}
+
+func runTypeList(cmd *cobra.Command, args []string) error {
+	_, registry, err := openTypeRegistry()
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(registry.Types, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal types: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	for _, t := range registry.Types {
+		extensions := "any"
+		if len(t.Extensions) > 0 {
+			extensions = strings.Join(t.Extensions, ",")
+		}
+		schema := ""
+		if len(t.Schema) > 0 {
+			schema = " [schema]"
+		}
+		if t.KeyPath != "" {
+			schema += " [key " + t.KeyPath + "]"
+		}
+		fmt.Printf("%07d  %-20s %-30s %s%s\n", t.ID, t.Slug, t.Name, extensions, schema)
+	}
+
+	return nil
+}
+
+func runTypeAdd(cmd *cobra.Command, args []string) error {
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid type ID: %s", args[0])
+	}
+
+	r, registry, err := openTypeRegistry()
+	if err != nil {
+		return err
+	}
+
+	assetType := repo.AssetType{
+		ID:         id,
+		Slug:       args[1],
+		Name:       typeName,
+		Extensions: typeExtensions,
+		KeyPath:    typeKeyPath,
+	}
+
+	if typeSchema != "" {
+		data, err := os.ReadFile(typeSchema)
+		if err != nil {
+			return fmt.Errorf("failed to read schema: %w", err)
+		}
+		assetType.Schema = json.RawMessage(data)
+	}
+
+	if err := registry.Add(assetType); err != nil {
+		return err
+	}
+	if err := r.SaveTypes(registry); err != nil {
+		return err
+	}
+
+	// Every registered type gets its folder, except in the flat layout
+	if r.Layout().Name() == repo.LayoutTree {
+		if err := os.MkdirAll(filepath.Join(r.Path, "assets", strconv.Itoa(id)), 0755); err != nil {
+			return fmt.Errorf("failed to create asset directory: %w", err)
+		}
+	}
+
+	fmt.Printf("Registered type %07d (%s)\n", id, args[1])
+	return nil
+}
+
+func runTypeSet(cmd *cobra.Command, args []string) error {
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid type ID: %s", args[0])
+	}
+	if !cmd.Flags().Changed("key-path") {
+		return fmt.Errorf("nothing to change (use --key-path)")
+	}
+
+	r, registry, err := openTypeRegistry()
+	if err != nil {
+		return err
+	}
+
+	if err := registry.SetKeyPath(id, typeKeyPath); err != nil {
+		return err
+	}
+	if err := r.SaveTypes(registry); err != nil {
+		return err
+	}
+
+	fmt.Printf("Updated type %07d\n", id)
+	return nil
+}
+
+func runTypeRemove(cmd *cobra.Command, args []string) error {
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid type ID: %s", args[0])
+	}
+
+	r, registry, err := openTypeRegistry()
+	if err != nil {
+		return err
+	}
+
+	if !registry.Remove(id) {
+		return fmt.Errorf("type %d is not registered", id)
+	}
+	if err := r.SaveTypes(registry); err != nil {
+		return err
+	}
+
+	fmt.Printf("Unregistered type %07d\n", id)
+	return nil
+}
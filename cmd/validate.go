@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/rdb/cli/internal/repo"
+	"github.com/spf13/cobra"
+)
+
+var (
+	validateCached bool
+)
+
+// validateCmd represents the validate command
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate asset metadata against type schemas",
+	Long: `Validate every meta.json against the base metadata rules and the JSON Schema
+registered for its asset type (see 'rdb type add --schema').
+
+Errors are reported as <file>:<line>: <json pointer>: <message>. The same checks
+run on 'rdb add' and 'rdb commit'.
+
+Examples:
+  rdb validate
+  rdb validate --cached
+  rdb validate --json`,
+	Args: cobra.NoArgs,
+	RunE: runValidate,
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+
+	// Local flags
+	validateCmd.Flags().BoolVar(&validateCached, "cached", false, "validate the staged metadata instead of the working tree")
+}
+
+func runValidate(cmd *cobra.Command, args []string) error {
+	// Always use current working directory
+	repoPath := "."
+
+	// Convert to absolute path
+	absPath, err := filepath.Abs(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve path: %w", err)
+	}
+
+	// Safety check: prevent operations in system directories
+	if strings.Contains(strings.ToLower(absPath), "c:\\windows\\system32") {
+		return fmt.Errorf("cannot operate on RDB repository in system directory: %s", absPath)
+	}
+
+	// Check if repository exists
+	if !repo.IsRepository(absPath) {
+		return fmt.Errorf("not an RDB repository: %s", absPath)
+	}
+
+	// Open repository
+	r, err := repo.OpenRepository(absPath)
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	var errs []repo.ValidationError
+	if validateCached {
+		idx, err := r.LoadIndex()
+		if err != nil {
+			return fmt.Errorf("failed to load index: %w", err)
+		}
+		errs, err = r.ValidateIndex(idx)
+		if err != nil {
+			return err
+		}
+	} else {
+		errs, err = r.ValidateWorkingTree()
+		if err != nil {
+			return err
+		}
+	}
+
+	if jsonOutput {
+		if errs == nil {
+			errs = []repo.ValidationError{}
+		}
+		data, err := json.MarshalIndent(errs, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal errors: %w", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		for _, e := range errs {
+			fmt.Println(e.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%d metadata error(s)", len(errs))
+	}
+
+	if !jsonOutput {
+		fmt.Println("All metadata is valid")
+	}
+	return nil
+}
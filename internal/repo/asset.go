@@ -0,0 +1,244 @@
+package repo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+// MetaFileName is the name of the metadata file inside every asset directory
+const MetaFileName = "meta.json"
+
+// utf8BOM is written by PowerShell's Out-File and must be skipped when decoding
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// ParseAssetMeta decodes the content of a meta.json file
+func ParseAssetMeta(data []byte) (*Asset, error) {
+	var asset Asset
+	if err := json.Unmarshal(bytes.TrimPrefix(data, utf8BOM), &asset); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", MetaFileName, err)
+	}
+	return &asset, nil
+}
+
+// ReadAssetMeta reads the meta.json file in the given asset directory
+func ReadAssetMeta(assetDir string) (*Asset, error) {
+	return ReadAssetMetaFile(filepath.Join(assetDir, MetaFileName))
+}
+
+// ReadAssetMetaFile reads an asset's metadata from metaPath
+func ReadAssetMetaFile(metaPath string) (*Asset, error) {
+	data, err := os.ReadFile(metaPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", MetaFileName, err)
+	}
+	return ParseAssetMeta(data)
+}
+
+// metaParseError describes a meta.json decoding failure, with the line it occurred on when known
+func metaParseError(metaPath string, data []byte, err error) error {
+	if line := jsonErrorLine(bytes.TrimPrefix(data, utf8BOM), err); line > 0 {
+		return fmt.Errorf("failed to parse %s:%d: %w", metaPath, line, err)
+	}
+	return fmt.Errorf("failed to parse %s: %w", metaPath, err)
+}
+
+// UpdateAssetMeta creates or merges the meta.json file in assetDir.
+// Existing fields are preserved; type and id are filled in when missing and
+// name is overwritten when given. It reports whether the file was written.
+func UpdateAssetMeta(assetDir, assetType string, assetID int, assetName string) (bool, error) {
+	return UpdateAssetMetaFile(filepath.Join(assetDir, MetaFileName), assetType, assetID, assetName)
+}
+
+// UpdateAssetMetaFile is UpdateAssetMeta for a metadata file at an explicit path,
+// as used by layouts that do not keep meta.json inside an asset folder
+func UpdateAssetMetaFile(metaPath, assetType string, assetID int, assetName string) (bool, error) {
+	meta := map[string]interface{}{}
+	data, err := os.ReadFile(metaPath)
+	if err == nil {
+		if err := json.Unmarshal(bytes.TrimPrefix(data, utf8BOM), &meta); err != nil {
+			return false, metaParseError(metaPath, data, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return false, fmt.Errorf("failed to read %s: %w", metaPath, err)
+	}
+
+	changed := data == nil
+	if _, ok := meta["type"]; !ok && assetType != "" {
+		meta["type"] = assetType
+		changed = true
+	}
+	if _, ok := meta["id"]; !ok && assetID != 0 {
+		meta["id"] = assetID
+		changed = true
+	}
+	if assetName != "" && meta["name"] != assetName {
+		meta["name"] = assetName
+		changed = true
+	}
+
+	if !changed {
+		return false, nil
+	}
+
+	out, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(metaPath), 0755); err != nil {
+		return false, fmt.Errorf("failed to create asset directory: %w", err)
+	}
+	if err := os.WriteFile(metaPath, append(out, '\n'), 0644); err != nil {
+		return false, fmt.Errorf("failed to write %s: %w", metaPath, err)
+	}
+
+	return true, nil
+}
+
+// SetAssetDraft marks or unmarks the asset in assetDir as a draft and reports whether meta.json changed
+func SetAssetDraft(assetDir string, draft bool) (bool, error) {
+	return SetAssetDraftFile(filepath.Join(assetDir, MetaFileName), draft)
+}
+
+// SetAssetDraftFile is SetAssetDraft for a metadata file at an explicit path
+func SetAssetDraftFile(metaPath string, draft bool) (bool, error) {
+	data, err := os.ReadFile(metaPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", metaPath, err)
+	}
+
+	meta := map[string]interface{}{}
+	if err := json.Unmarshal(bytes.TrimPrefix(data, utf8BOM), &meta); err != nil {
+		return false, metaParseError(metaPath, data, err)
+	}
+
+	isDraft := meta["status"] == AssetStatusDraft
+	if isDraft == draft {
+		return false, nil
+	}
+	if draft {
+		meta["status"] = AssetStatusDraft
+	} else {
+		delete(meta, "status")
+	}
+
+	out, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+	if err := os.WriteFile(metaPath, append(out, '\n'), 0644); err != nil {
+		return false, fmt.Errorf("failed to write %s: %w", metaPath, err)
+	}
+
+	return true, nil
+}
+
+// DraftAssets returns the canonical directories of staged assets whose meta.json marks them as drafts
+func (r *Repository) DraftAssets(idx *Index) ([]string, error) {
+	var drafts []string
+	for _, entry := range idx.Entries {
+		if !r.IsMetaPath(entry.Path) || entry.Conflict != nil {
+			continue
+		}
+		asset, err := r.readAssetMetaObject(entry.Object)
+		if err != nil {
+			return nil, fmt.Errorf("invalid metadata %s: %w", entry.Path, err)
+		}
+		if asset.IsDraft() {
+			drafts = append(drafts, path.Dir(r.Layout().TreePath(entry.Path)))
+		}
+	}
+	return drafts, nil
+}
+
+// TreeAsset is an asset found while walking a commit tree
+type TreeAsset struct {
+	Path  string      // slash-separated asset directory, relative to the repository root
+	Entry TreeEntry   // the "asset" tree entry; Object is the asset's tree hash
+	Meta  *Asset      // decoded meta.json
+	Raw   []byte      // meta.json exactly as committed
+	Files []AssetPath // payload files relative to the asset directory, excluding meta.json
+}
+
+// ListAssets returns every asset in the given tree, ordered by path
+func (r *Repository) ListAssets(treeHash string) ([]TreeAsset, error) {
+	var assets []TreeAsset
+	if err := r.collectAssets(treeHash, "", &assets); err != nil {
+		return nil, err
+	}
+	return assets, nil
+}
+
+func (r *Repository) collectAssets(treeHash, prefix string, assets *[]TreeAsset) error {
+	tree, err := r.ReadTree(treeHash)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range tree.Entries {
+		entryPath := path.Join(prefix, entry.Name)
+		switch entry.Type {
+		case "tree":
+			if err := r.collectAssets(entry.Object, entryPath, assets); err != nil {
+				return err
+			}
+		case "asset":
+			asset, err := r.readTreeAsset(entryPath, entry)
+			if err != nil {
+				return fmt.Errorf("failed to read asset %s: %w", entryPath, err)
+			}
+			*assets = append(*assets, *asset)
+		}
+	}
+
+	return nil
+}
+
+func (r *Repository) readTreeAsset(assetPath string, entry TreeEntry) (*TreeAsset, error) {
+	files, err := r.FlattenTree(entry.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	asset := &TreeAsset{Path: assetPath, Entry: entry}
+
+	logical := make([]string, 0, len(files))
+	for name := range files {
+		logical = append(logical, name)
+	}
+	sort.Strings(logical)
+
+	for _, name := range logical {
+		file := files[name]
+		if name == MetaFileName {
+			objType, data, err := r.readObject(file.Object)
+			if err != nil {
+				return nil, err
+			}
+			if objType != "blob" {
+				return nil, fmt.Errorf("object %s is not a blob", file.Object)
+			}
+			asset.Raw = bytes.TrimPrefix(data, utf8BOM)
+			if asset.Meta, err = ParseAssetMeta(data); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		asset.Files = append(asset.Files, AssetPath{
+			Logical: name,
+			Object:  file.Object,
+			Size:    file.Size,
+		})
+	}
+
+	if asset.Meta == nil {
+		return nil, fmt.Errorf("%s is missing", MetaFileName)
+	}
+
+	return asset, nil
+}
@@ -0,0 +1,139 @@
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUpdateAssetMetaPreservesFields(t *testing.T) {
+	assetDir := t.TempDir()
+	metaPath := filepath.Join(assetDir, MetaFileName)
+
+	// Existing file written by PowerShell with a byte order mark
+	content := append([]byte{0xEF, 0xBB, 0xBF}, []byte(`{"type":"text","tags":["ui"],"custom":true}`)...)
+	if err := os.WriteFile(metaPath, content, 0644); err != nil {
+		t.Fatalf("Failed to write meta.json: %v", err)
+	}
+
+	written, err := UpdateAssetMeta(assetDir, "string", 1030002, "Intro")
+	if err != nil {
+		t.Fatalf("Failed to update metadata: %v", err)
+	}
+	if !written {
+		t.Error("Expected metadata to be written")
+	}
+
+	asset, err := ReadAssetMeta(assetDir)
+	if err != nil {
+		t.Fatalf("Failed to read metadata: %v", err)
+	}
+
+	if asset.Type != "text" {
+		t.Errorf("Expected existing type 'text' to be kept, got '%s'", asset.Type)
+	}
+	if asset.ID != 1030002 {
+		t.Errorf("Expected id 1030002, got %d", asset.ID)
+	}
+	if asset.Name != "Intro" {
+		t.Errorf("Expected name 'Intro', got '%s'", asset.Name)
+	}
+	if len(asset.Tags) != 1 {
+		t.Errorf("Expected tags to be preserved, got %v", asset.Tags)
+	}
+
+	// A second identical update is a no-op
+	written, err = UpdateAssetMeta(assetDir, "string", 1030002, "Intro")
+	if err != nil {
+		t.Fatalf("Failed to update metadata: %v", err)
+	}
+	if written {
+		t.Error("Expected unchanged metadata not to be rewritten")
+	}
+}
+
+func TestListAssets(t *testing.T) {
+	tempDir := t.TempDir()
+	repo := NewRepository(tempDir)
+	if err := repo.Init("tree", []string{"text"}); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+
+	hash := commitFiles(t, repo, map[string]string{
+		"assets/1030002/meta.json":   `{"type":"string","id":1030002,"name":"Intro"}`,
+		"assets/1030002/en.txt":      "hello",
+		"assets/1030002/data/fr.txt": "bonjour",
+		"assets/1000624/meta.json":   `{"type":"flash_image","id":1000624}`,
+		"assets/1000624/logo.swf":    "swf",
+	}, "assets")
+
+	commit, err := repo.ReadCommit(hash)
+	if err != nil {
+		t.Fatalf("Failed to read commit: %v", err)
+	}
+
+	assets, err := repo.ListAssets(commit.Tree)
+	if err != nil {
+		t.Fatalf("Failed to list assets: %v", err)
+	}
+	if len(assets) != 2 {
+		t.Fatalf("Expected 2 assets, got %d", len(assets))
+	}
+
+	intro := assets[1]
+	if intro.Path != "assets/1030002" || intro.Meta.Name != "Intro" {
+		t.Errorf("Unexpected asset: %s %+v", intro.Path, intro.Meta)
+	}
+	if len(intro.Files) != 2 || intro.Files[0].Logical != "data/fr.txt" || intro.Files[1].Logical != "en.txt" {
+		t.Errorf("Unexpected payload files: %+v", intro.Files)
+	}
+	if intro.Entry.Object == "" || intro.Entry.AssetID != 1030002 {
+		t.Errorf("Unexpected asset entry: %+v", intro.Entry)
+	}
+}
+
+func TestDraftAssets(t *testing.T) {
+	tempDir := t.TempDir()
+	repo := NewRepository(tempDir)
+	if err := repo.Init("tree", []string{"text"}); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+
+	commitFiles(t, repo, map[string]string{
+		"assets/1030002/meta.json": `{"type":"string","id":1030002}`,
+		"assets/1000624/meta.json": `{"type":"flash_image","id":1000624}`,
+	}, "assets")
+
+	assetDir := filepath.Join(tempDir, "assets", "1030002")
+	changed, err := SetAssetDraft(assetDir, true)
+	if err != nil {
+		t.Fatalf("Failed to mark draft: %v", err)
+	}
+	if !changed {
+		t.Error("Expected meta.json to change")
+	}
+
+	idx, _ := repo.LoadIndex()
+	entry, err := repo.StageFile("assets/1030002/meta.json")
+	if err != nil {
+		t.Fatalf("Failed to stage metadata: %v", err)
+	}
+	idx.Add(entry)
+
+	drafts, err := repo.DraftAssets(idx)
+	if err != nil {
+		t.Fatalf("Failed to list drafts: %v", err)
+	}
+	if len(drafts) != 1 || drafts[0] != "assets/1030002" {
+		t.Errorf("Expected assets/1030002 to be a draft, got %v", drafts)
+	}
+
+	changed, err = SetAssetDraft(assetDir, false)
+	if err != nil {
+		t.Fatalf("Failed to clear draft: %v", err)
+	}
+	asset, _ := ReadAssetMeta(assetDir)
+	if !changed || asset.IsDraft() {
+		t.Error("Expected draft status to be cleared")
+	}
+}
@@ -0,0 +1,312 @@
+package repo
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WriteBlob stores everything read from src as a blob and returns its hash and size.
+// Content is hashed while it is copied, so memory use does not depend on the blob size.
+// The object is written to a temporary file and renamed into place, so a reader never
+// sees a partial object. Sources that report their size (such as *os.File) are copied
+// once; others are spooled to disk first because the object header carries the length.
+// The blob is compressed unless compression is disabled for the repository, and blobs
+// of at least the chunk threshold are split into content-defined chunks.
+func (r *Repository) WriteBlob(src io.Reader) (string, int64, error) {
+	return r.writeBlob(src, r.compressionEnabled())
+}
+
+// writeBlob implements WriteBlob, compressing the stored content if asked to
+func (r *Repository) writeBlob(src io.Reader, compress bool) (string, int64, error) {
+	objectsDir := filepath.Join(r.Path, ".rdb", "objects")
+	if err := os.MkdirAll(objectsDir, 0755); err != nil {
+		return "", 0, fmt.Errorf("failed to create object directory: %w", err)
+	}
+
+	threshold := r.chunkThreshold()
+	if size, ok := readerSize(src); ok {
+		if threshold >= 0 && size >= threshold {
+			return r.writeChunkedBlob(src, size, compress)
+		}
+		return r.writeSizedBlob(src, size, compress)
+	}
+
+	spool, err := os.CreateTemp(objectsDir, "tmp-spool-")
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	size, err := io.Copy(spool, src)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read blob content: %w", err)
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return "", 0, fmt.Errorf("failed to rewind temporary file: %w", err)
+	}
+	if threshold >= 0 && size >= threshold {
+		return r.writeChunkedBlob(spool, size, compress)
+	}
+	return r.writeSizedBlob(spool, size, compress)
+}
+
+// readerSize returns the number of bytes left in src when it can tell without reading
+func readerSize(src io.Reader) (int64, bool) {
+	switch s := src.(type) {
+	case *os.File:
+		info, err := s.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			return 0, false
+		}
+		offset, err := s.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, false
+		}
+		return info.Size() - offset, true
+	case *bytes.Reader:
+		return int64(s.Len()), true
+	case *strings.Reader:
+		return int64(s.Len()), true
+	}
+	return 0, false
+}
+
+// writeSizedBlob copies exactly size bytes from src into a new blob object
+func (r *Repository) writeSizedBlob(src io.Reader, size int64, compress bool) (string, int64, error) {
+	tmp, err := os.CreateTemp(filepath.Join(r.Path, ".rdb", "objects"), "tmp-obj-")
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create temporary object: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	w := bufio.NewWriter(tmp)
+	w.WriteString(objectHeader("blob", size, compress))
+
+	var payload io.Writer = w
+	var zw *zlib.Writer
+	if compress {
+		zw = zlib.NewWriter(w)
+		payload = zw
+	}
+
+	sum := sha256.New()
+	copied, err := io.Copy(io.MultiWriter(payload, sum), src)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to write blob: %w", err)
+	}
+	if copied != size {
+		return "", 0, fmt.Errorf("content changed while writing blob: expected %d bytes, got %d", size, copied)
+	}
+	hashStr := hex.EncodeToString(sum.Sum(nil))
+
+	if r.hasObject(hashStr) {
+		return hashStr, size, nil
+	}
+
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return "", 0, fmt.Errorf("failed to compress blob: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return "", 0, fmt.Errorf("failed to write blob: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return "", 0, fmt.Errorf("failed to write blob: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", 0, fmt.Errorf("failed to write blob: %w", err)
+	}
+
+	objPath := r.objectPath(hashStr)
+	if err := os.MkdirAll(filepath.Dir(objPath), 0755); err != nil {
+		return "", 0, fmt.Errorf("failed to create object directory: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), objPath); err != nil {
+		return "", 0, fmt.Errorf("failed to install blob: %w", err)
+	}
+
+	return hashStr, size, nil
+}
+
+// storeObject atomically writes data as the loose object named hash
+func (r *Repository) storeObject(hash, objType string, data []byte, compress bool) error {
+	objPath := r.objectPath(hash)
+	if err := os.MkdirAll(filepath.Dir(objPath), 0755); err != nil {
+		return fmt.Errorf("failed to create object directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(objPath), "tmp-obj-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary object: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(encodeObject(objType, data, compress)); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write object: %w", err)
+	}
+	// Repack and gc delete other copies based on what is on disk, so the object must be durable first
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write object: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write object: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), objPath); err != nil {
+		return fmt.Errorf("failed to write object: %w", err)
+	}
+	return nil
+}
+
+// hasObject reports whether an intact-looking copy of the object is stored loose or packed
+func (r *Repository) hasObject(hash string) bool {
+	if _, err := os.Stat(r.objectPath(hash)); err == nil {
+		return true
+	}
+	packs, err := r.loadPacks()
+	if err != nil {
+		return false
+	}
+	for _, idx := range packs {
+		if _, ok := idx.lookup(hash); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// OpenBlob returns a reader for the content of a blob without loading it into memory.
+// The content is hashed as it is read, and reaching the end of a blob whose content
+// does not match its name returns an error instead of io.EOF.
+func (r *Repository) OpenBlob(hash string) (io.ReadCloser, error) {
+	file, err := os.Open(r.objectPath(hash))
+	if err != nil {
+		if os.IsNotExist(err) {
+			if rc, ok, perr := r.openPackedBlob(hash); ok || perr != nil {
+				return rc, perr
+			}
+		}
+		return nil, fmt.Errorf("failed to open object: %w", err)
+	}
+
+	br := bufio.NewReader(file)
+	header, err := br.ReadString(0)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("invalid object format: no null separator found")
+	}
+	objType, size, encoding, err := parseObjectHeader(strings.TrimSuffix(header, "\000"))
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if objType != "blob" && objType != ChunkedType {
+		file.Close()
+		return nil, fmt.Errorf("object %s is not a blob", hash)
+	}
+
+	var content io.Reader = br
+	var closer io.Closer = file
+	if encoding == EncodingZlib {
+		zr, err := zlib.NewReader(br)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to decompress object: %w", err)
+		}
+		content, closer = zr, &zlibReadCloser{ReadCloser: zr, src: file}
+	}
+
+	if objType == ChunkedType {
+		defer closer.Close()
+		data, err := io.ReadAll(io.LimitReader(content, size))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read chunk manifest: %w", err)
+		}
+		return r.openChunkedBlob(hash, data)
+	}
+	return newBlobReader(hash, size, content, closer), nil
+}
+
+// openPackedBlob opens a blob stored in a pack, reporting whether any pack has it
+func (r *Repository) openPackedBlob(hash string) (io.ReadCloser, bool, error) {
+	packs, err := r.loadPacks()
+	if err != nil {
+		return nil, false, err
+	}
+	for _, idx := range packs {
+		offset, ok := idx.lookup(hash)
+		if !ok {
+			continue
+		}
+		objType, size, rc, err := idx.open(offset)
+		if err != nil {
+			return nil, true, fmt.Errorf("failed to read packed object %s: %w", hash, err)
+		}
+		switch objType {
+		case "blob":
+			return newBlobReader(hash, size, rc, rc), true, nil
+		case ChunkedType:
+			data, err := io.ReadAll(io.LimitReader(rc, size))
+			rc.Close()
+			if err != nil {
+				return nil, true, fmt.Errorf("failed to read chunk manifest: %w", err)
+			}
+			blob, err := r.openChunkedBlob(hash, data)
+			return blob, true, err
+		}
+		rc.Close()
+		return nil, true, fmt.Errorf("object %s is not a blob", hash)
+	}
+	return nil, false, nil
+}
+
+// blobReader reads exactly size bytes of blob content and checks the hash at the end
+type blobReader struct {
+	src       io.Reader
+	closer    io.Closer
+	hash      string
+	sum       hash.Hash
+	remaining int64
+}
+
+func newBlobReader(hash string, size int64, src io.Reader, closer io.Closer) *blobReader {
+	return &blobReader{
+		src:       io.LimitReader(src, size),
+		closer:    closer,
+		hash:      hash,
+		sum:       sha256.New(),
+		remaining: size,
+	}
+}
+
+func (b *blobReader) Read(p []byte) (int, error) {
+	n, err := b.src.Read(p)
+	b.sum.Write(p[:n])
+	b.remaining -= int64(n)
+	if err == io.EOF {
+		if b.remaining > 0 {
+			return n, fmt.Errorf("object %s is truncated: %w", b.hash, io.ErrUnexpectedEOF)
+		}
+		if actual := hex.EncodeToString(b.sum.Sum(nil)); actual != b.hash {
+			return n, fmt.Errorf("object %s is corrupt: content hashes to %s", b.hash, actual)
+		}
+	}
+	return n, err
+}
+
+func (b *blobReader) Close() error {
+	return b.closer.Close()
+}
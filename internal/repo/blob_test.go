@@ -0,0 +1,103 @@
+package repo
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// onlyReader hides any Len or Stat method so WriteBlob cannot learn the size up front
+type onlyReader struct {
+	io.Reader
+}
+
+func TestWriteBlobMatchesRawObjects(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+
+	content := make([]byte, 3<<20)
+	rand.New(rand.NewSource(1)).Read(content)
+	want, err := repo.writeRawObject("blob", content)
+	if err != nil {
+		t.Fatalf("Failed to write raw object: %v", err)
+	}
+	if err := os.Remove(repo.objectPath(want)); err != nil {
+		t.Fatalf("Failed to remove object: %v", err)
+	}
+
+	for _, src := range []io.Reader{bytes.NewReader(content), onlyReader{bytes.NewReader(content)}} {
+		hash, size, err := repo.WriteBlob(src)
+		if err != nil {
+			t.Fatalf("WriteBlob failed: %v", err)
+		}
+		if hash != want || size != int64(len(content)) {
+			t.Errorf("Expected %s (%d bytes), got %s (%d bytes)", want, len(content), hash, size)
+		}
+		if _, err := repo.verifyObject(hash); err != nil {
+			t.Errorf("Expected a valid loose object: %v", err)
+		}
+	}
+
+	leftovers, err := filepath.Glob(filepath.Join(repo.Path, ".rdb", "objects", "tmp-*"))
+	if err != nil || len(leftovers) != 0 {
+		t.Errorf("Expected no temporary files, got %v", leftovers)
+	}
+}
+
+func TestOpenBlobStreamsLooseAndPackedContent(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+	commitFiles(t, repo, map[string]string{"assets/1000635/intro.usm": "movie data"}, "first")
+	hash, _, err := repo.WriteBlob(strings.NewReader("movie data"))
+	if err != nil {
+		t.Fatalf("WriteBlob failed: %v", err)
+	}
+
+	readBlob := func() string {
+		t.Helper()
+		blob, err := repo.OpenBlob(hash)
+		if err != nil {
+			t.Fatalf("OpenBlob failed: %v", err)
+		}
+		defer blob.Close()
+		data, err := io.ReadAll(blob)
+		if err != nil {
+			t.Fatalf("Failed to read blob: %v", err)
+		}
+		return string(data)
+	}
+
+	if got := readBlob(); got != "movie data" {
+		t.Errorf("Expected loose content, got %q", got)
+	}
+	if _, err := repo.Repack(RepackOptions{Window: DefaultPackWindow, Depth: DefaultPackDepth}); err != nil {
+		t.Fatalf("Repack failed: %v", err)
+	}
+	if got := readBlob(); got != "movie data" {
+		t.Errorf("Expected packed content, got %q", got)
+	}
+
+	// Damaged content is detected when the reader reaches the end
+	if err := os.MkdirAll(filepath.Dir(repo.objectPath(hash)), 0755); err != nil {
+		t.Fatalf("Failed to create object directory: %v", err)
+	}
+	if err := os.WriteFile(repo.objectPath(hash), []byte("blob 10\000movie dat?"), 0644); err != nil {
+		t.Fatalf("Failed to corrupt object: %v", err)
+	}
+	blob, err := repo.OpenBlob(hash)
+	if err != nil {
+		t.Fatalf("OpenBlob failed: %v", err)
+	}
+	defer blob.Close()
+	if _, err := io.ReadAll(blob); err == nil {
+		t.Error("Expected corrupt content to fail the read")
+	}
+}
@@ -0,0 +1,235 @@
+package repo
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// CheckoutConflictError lists the paths that prevent a checkout from running safely
+type CheckoutConflictError struct {
+	Paths []string
+}
+
+func (e *CheckoutConflictError) Error() string {
+	return fmt.Sprintf("your local changes to the following files would be overwritten:\n  %s",
+		strings.Join(e.Paths, "\n  "))
+}
+
+// CheckoutTree rewrites the working tree under assets/ and the index from the current
+// HEAD tree to targetTree. Local changes to files that are identical in both trees are
+// carried over; local changes to any other file abort the checkout with a
+// *CheckoutConflictError. With force set, all local changes to tracked files are discarded.
+func (r *Repository) CheckoutTree(targetTree string, force bool) error {
+	idx, err := r.LoadIndex()
+	if err != nil {
+		return err
+	}
+
+	current := map[string]TreeEntry{}
+	if commitHash, err := r.GetCurrentCommit(); err == nil && commitHash != "" {
+		commit, err := r.ReadCommit(commitHash)
+		if err != nil {
+			return err
+		}
+		current, err = r.flattenWorkTree(commit.Tree)
+		if err != nil {
+			return err
+		}
+	}
+
+	target, err := r.flattenWorkTree(targetTree)
+	if err != nil {
+		return err
+	}
+
+	status, err := r.Status(idx)
+	if err != nil {
+		return err
+	}
+
+	// Paths whose local state must be preserved as-is
+	carried := make(map[string]bool)
+	var conflicts []string
+	for _, e := range status.Entries {
+		if e.Staged == StatusUntracked {
+			// Untracked files only matter if the target would overwrite them
+			if t, ok := target[e.Path]; ok && !force {
+				hash, err := HashFile(filepath.Join(r.Path, filepath.FromSlash(e.Path)))
+				if err != nil || hash != t.Object {
+					conflicts = append(conflicts, e.Path)
+				}
+			}
+			continue
+		}
+
+		for _, path := range []string{e.Path, e.OrigPath} {
+			if path == "" {
+				continue
+			}
+			switch {
+			case force:
+				// Discarded below
+			case current[path].Object == target[path].Object:
+				carried[path] = true
+			default:
+				conflicts = append(conflicts, path)
+			}
+		}
+	}
+
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return &CheckoutConflictError{Paths: conflicts}
+	}
+
+	// Remove files that do not exist in the target
+	for path := range current {
+		if _, ok := target[path]; ok || carried[path] {
+			continue
+		}
+		absPath := filepath.Join(r.Path, filepath.FromSlash(path))
+		if err := os.Remove(absPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+		r.removeEmptyAssetDirs(filepath.Dir(absPath))
+		idx.Remove(path)
+	}
+
+	// When forcing, discard staged files that are not in the target
+	if force {
+		for _, entry := range append([]IndexEntry(nil), idx.Entries...) {
+			if _, ok := target[entry.Path]; ok {
+				continue
+			}
+			absPath := filepath.Join(r.Path, filepath.FromSlash(entry.Path))
+			if err := os.Remove(absPath); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove %s: %w", entry.Path, err)
+			}
+			r.removeEmptyAssetDirs(filepath.Dir(absPath))
+			idx.Remove(entry.Path)
+		}
+	}
+
+	// Write every target file that differs from what is on disk
+	for path, entry := range target {
+		if carried[path] {
+			continue
+		}
+
+		absPath := filepath.Join(r.Path, filepath.FromSlash(path))
+		if staged, ok := idx.Get(path); ok && staged.Object == entry.Object && !force {
+			if info, err := os.Stat(absPath); err == nil && info.Size() == staged.Size && info.ModTime().Equal(staged.ModTime) {
+				continue
+			}
+		}
+
+		if err := r.checkoutBlob(entry.Object, absPath); err != nil {
+			return fmt.Errorf("failed to check out %s: %w", path, err)
+		}
+
+		info, err := os.Stat(absPath)
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+		idx.Add(IndexEntry{
+			Path:    path,
+			Object:  entry.Object,
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		})
+	}
+
+	return r.SaveIndex(idx)
+}
+
+// CheckoutPaths writes the files of a root tree at or below the given canonical tree
+// paths to the working tree and stages them, overwriting any local changes, like
+// restoring a file from an older commit. Files that do not exist in the tree are left
+// alone. It returns the working-tree paths written; a path matching nothing is an error.
+func (r *Repository) CheckoutPaths(treeHash string, paths []string) ([]string, error) {
+	idx, err := r.LoadIndex()
+	if err != nil {
+		return nil, err
+	}
+	files, err := r.FlattenTree(treeHash)
+	if err != nil {
+		return nil, err
+	}
+
+	layout := r.Layout()
+	var written []string
+	for _, spec := range paths {
+		matched := 0
+		for treePath, entry := range files {
+			if treePath != spec && !strings.HasPrefix(treePath, spec+"/") {
+				continue
+			}
+			matched++
+
+			workPath := layout.WorkPath(treePath)
+			absPath := filepath.Join(r.Path, filepath.FromSlash(workPath))
+			if err := r.checkoutBlob(entry.Object, absPath); err != nil {
+				return nil, fmt.Errorf("failed to check out %s: %w", workPath, err)
+			}
+			info, err := os.Stat(absPath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to stat %s: %w", workPath, err)
+			}
+			idx.Add(IndexEntry{
+				Path:    workPath,
+				Object:  entry.Object,
+				Size:    info.Size(),
+				ModTime: info.ModTime(),
+			})
+			written = append(written, workPath)
+		}
+		if matched == 0 {
+			return nil, fmt.Errorf("path %s does not exist in the given revision", spec)
+		}
+	}
+
+	sort.Strings(written)
+	return written, r.SaveIndex(idx)
+}
+
+// checkoutBlob streams the content of a blob object to absPath
+func (r *Repository) checkoutBlob(hash, absPath string) error {
+	blob, err := r.OpenBlob(hash)
+	if err != nil {
+		return err
+	}
+	defer blob.Close()
+
+	if err := os.MkdirAll(filepath.Dir(absPath), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(absPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, blob); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// removeEmptyAssetDirs prunes empty directories below the asset ID folders,
+// which are part of the repository layout and always kept
+func (r *Repository) removeEmptyAssetDirs(dir string) {
+	assetsPath := filepath.Join(r.Path, "assets")
+	for {
+		rel, err := filepath.Rel(assetsPath, dir)
+		if err != nil || rel == "." || !strings.Contains(rel, string(filepath.Separator)) {
+			return
+		}
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
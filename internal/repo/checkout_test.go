@@ -0,0 +1,129 @@
+package repo
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckoutTreeRewritesWorkingTree(t *testing.T) {
+	tempDir := t.TempDir()
+	repo := NewRepository(tempDir)
+	if err := repo.Init("tree", []string{"text"}); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+
+	base := commitFiles(t, repo, map[string]string{"assets/1030002/en.txt": "base"}, "base")
+	baseCommit, _ := repo.ReadCommit(base)
+
+	commitFiles(t, repo, map[string]string{
+		"assets/1030002/en.txt":       "winter",
+		"assets/1030002/event/fx.txt": "snow",
+	}, "winter")
+
+	if err := repo.CheckoutTree(baseCommit.Tree, false); err != nil {
+		t.Fatalf("Failed to check out tree: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tempDir, "assets", "1030002", "en.txt"))
+	if err != nil || string(data) != "base" {
+		t.Errorf("Expected en.txt to contain 'base', got %q (%v)", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "assets", "1030002", "event")); !os.IsNotExist(err) {
+		t.Error("Expected event directory to be removed")
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "assets", "1030002")); err != nil {
+		t.Error("Expected asset folder to be kept")
+	}
+
+	idx, _ := repo.LoadIndex()
+	if _, ok := idx.Get("assets/1030002/event/fx.txt"); ok {
+		t.Error("Expected removed file to be dropped from the index")
+	}
+}
+
+func TestCheckoutTreeProtectsLocalChanges(t *testing.T) {
+	tempDir := t.TempDir()
+	repo := NewRepository(tempDir)
+	if err := repo.Init("tree", []string{"text"}); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+
+	base := commitFiles(t, repo, map[string]string{"assets/1030002/en.txt": "base"}, "base")
+	baseCommit, _ := repo.ReadCommit(base)
+	commitFiles(t, repo, map[string]string{"assets/1030002/en.txt": "winter"}, "winter")
+
+	enPath := filepath.Join(tempDir, "assets", "1030002", "en.txt")
+	if err := os.WriteFile(enPath, []byte("unsaved work"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	err := repo.CheckoutTree(baseCommit.Tree, false)
+	var conflict *CheckoutConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("Expected a checkout conflict, got %v", err)
+	}
+	if len(conflict.Paths) != 1 || conflict.Paths[0] != "assets/1030002/en.txt" {
+		t.Errorf("Unexpected conflict paths: %v", conflict.Paths)
+	}
+
+	data, _ := os.ReadFile(enPath)
+	if string(data) != "unsaved work" {
+		t.Error("Expected local change to be left untouched")
+	}
+
+	if err := repo.CheckoutTree(baseCommit.Tree, true); err != nil {
+		t.Fatalf("Failed to force checkout: %v", err)
+	}
+	data, _ = os.ReadFile(enPath)
+	if string(data) != "base" {
+		t.Errorf("Expected forced checkout to restore 'base', got %q", data)
+	}
+}
+
+func TestCheckoutPathsRestoresFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	repo := NewRepository(tempDir)
+	if err := repo.Init(LayoutFlat, []string{"text"}); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+
+	base := commitFiles(t, repo, map[string]string{
+		"assets/1030002.en.txt": "base",
+		"assets/1030002.de.txt": "basis",
+		"assets/1000624.fx.txt": "fx",
+	}, "base")
+	baseCommit, _ := repo.ReadCommit(base)
+	commitFiles(t, repo, map[string]string{"assets/1030002.en.txt": "winter", "assets/1000624.fx.txt": "snow"}, "winter")
+
+	// A local change is overwritten too
+	if err := os.WriteFile(filepath.Join(tempDir, "assets", "1030002.de.txt"), []byte("local"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	written, err := repo.CheckoutPaths(baseCommit.Tree, []string{"assets/1030002"})
+	if err != nil {
+		t.Fatalf("Failed to check out paths: %v", err)
+	}
+	if len(written) != 2 || written[0] != "assets/1030002.de.txt" || written[1] != "assets/1030002.en.txt" {
+		t.Errorf("Unexpected paths written: %v", written)
+	}
+
+	for name, want := range map[string]string{"1030002.en.txt": "base", "1030002.de.txt": "basis", "1000624.fx.txt": "snow"} {
+		data, err := os.ReadFile(filepath.Join(tempDir, "assets", name))
+		if err != nil || string(data) != want {
+			t.Errorf("Expected %s to contain %q, got %q (%v)", name, want, data, err)
+		}
+	}
+
+	idx, _ := repo.LoadIndex()
+	entry, ok := idx.Get("assets/1030002.en.txt")
+	if hash, _ := HashFile(filepath.Join(tempDir, "assets", "1030002.en.txt")); !ok || entry.Object != hash {
+		t.Errorf("Expected restored file to be staged, got %+v", entry)
+	}
+
+	if _, err := repo.CheckoutPaths(baseCommit.Tree, []string{"assets/1999999"}); err == nil {
+		t.Error("Expected an error for a path missing from the tree")
+	}
+}
@@ -0,0 +1,290 @@
+package repo
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ChunkedType is the object type of a large blob stored as content-defined chunks.
+// The object is named by the hash of the whole content, like a blob, but holds a
+// ChunkManifest; each chunk is an ordinary blob, so unchanged regions of a file are
+// shared between its versions.
+const ChunkedType = "chunked"
+
+// DefaultChunkThreshold is the blob size from which content is stored in chunks
+const DefaultChunkThreshold = 8 << 20
+
+// Chunk boundaries fall where the top bits of a gear rolling hash over the last 64 bytes
+// are zero, giving chunks of about 64 KiB between chunkMin and chunkMax
+const (
+	chunkMin  = 16 << 10
+	chunkMax  = 256 << 10
+	chunkMask = uint64(0xFFFF) << 48
+)
+
+// gearTable maps each byte to a fixed pseudo-random value. It must never change, or
+// new versions of a file would stop sharing chunks with stored ones.
+var gearTable = func() [256]uint64 {
+	var table [256]uint64
+	state := uint64(0x5244424348554e4b) // "RDBCHUNK"
+	for i := range table {
+		// splitmix64
+		state += 0x9e3779b97f4a7c15
+		z := state
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		table[i] = z ^ (z >> 31)
+	}
+	return table
+}()
+
+// ChunkManifest lists the chunks of a chunked blob in order
+type ChunkManifest struct {
+	Size   int64   `json:"size"`
+	Chunks []Chunk `json:"chunks"`
+}
+
+// Chunk is one blob holding a slice of a chunked blob's content
+type Chunk struct {
+	Object string `json:"object"`
+	Size   int64  `json:"size"`
+}
+
+// chunker splits a stream at content-defined boundaries
+type chunker struct {
+	src *bufio.Reader
+	buf []byte
+}
+
+func newChunker(src io.Reader) *chunker {
+	return &chunker{src: bufio.NewReaderSize(src, chunkMax), buf: make([]byte, 0, chunkMax)}
+}
+
+// next returns the next chunk, valid until the following call, or io.EOF at the end
+func (c *chunker) next() ([]byte, error) {
+	c.buf = c.buf[:0]
+	var h uint64
+	for {
+		b, err := c.src.ReadByte()
+		if err == io.EOF {
+			if len(c.buf) > 0 {
+				return c.buf, nil
+			}
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		c.buf = append(c.buf, b)
+		h = (h << 1) + gearTable[b]
+		if len(c.buf) >= chunkMax || (len(c.buf) >= chunkMin && h&chunkMask == 0) {
+			return c.buf, nil
+		}
+	}
+}
+
+// chunkThreshold returns the blob size from which new blobs are chunked, or -1 if never
+func (r *Repository) chunkThreshold() int64 {
+	switch threshold := r.Config.Storage.ChunkThreshold; {
+	case threshold < 0:
+		return -1
+	case threshold == 0:
+		return DefaultChunkThreshold
+	default:
+		return threshold
+	}
+}
+
+// writeChunkedBlob stores exactly size bytes from src as chunks plus a manifest
+func (r *Repository) writeChunkedBlob(src io.Reader, size int64, compress bool) (string, int64, error) {
+	sum := sha256.New()
+	c := newChunker(io.TeeReader(src, sum))
+
+	manifest := ChunkManifest{Chunks: []Chunk{}}
+	for {
+		chunk, err := c.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", 0, fmt.Errorf("failed to read blob content: %w", err)
+		}
+
+		chunkSum := sha256.Sum256(chunk)
+		chunkHash := hex.EncodeToString(chunkSum[:])
+		if !r.hasObject(chunkHash) {
+			if err := r.storeObject(chunkHash, "blob", chunk, compress); err != nil {
+				return "", 0, err
+			}
+		}
+		manifest.Chunks = append(manifest.Chunks, Chunk{Object: chunkHash, Size: int64(len(chunk))})
+		manifest.Size += int64(len(chunk))
+	}
+	if manifest.Size != size {
+		return "", 0, fmt.Errorf("content changed while writing blob: expected %d bytes, got %d", size, manifest.Size)
+	}
+
+	hash := hex.EncodeToString(sum.Sum(nil))
+	if r.hasObject(hash) {
+		return hash, size, nil
+	}
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to marshal chunk manifest: %w", err)
+	}
+	if err := r.storeObject(hash, ChunkedType, data, r.compressionEnabled()); err != nil {
+		return "", 0, err
+	}
+	return hash, size, nil
+}
+
+// parseChunkManifest decodes and checks the content of a chunked object
+func parseChunkManifest(data []byte) (*ChunkManifest, error) {
+	var manifest ChunkManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal chunk manifest: %w", err)
+	}
+
+	var total int64
+	for _, chunk := range manifest.Chunks {
+		if !isObjectHash(chunk.Object) || chunk.Size <= 0 {
+			return nil, fmt.Errorf("invalid chunk %q in manifest", chunk.Object)
+		}
+		total += chunk.Size
+	}
+	if total != manifest.Size {
+		return nil, fmt.Errorf("chunk sizes add up to %d, manifest says %d", total, manifest.Size)
+	}
+	return &manifest, nil
+}
+
+// readChunkedBlob reassembles a chunked blob in memory
+func (r *Repository) readChunkedBlob(data []byte) (string, []byte, error) {
+	manifest, err := parseChunkManifest(data)
+	if err != nil {
+		return "", nil, err
+	}
+
+	content := make([]byte, 0, manifest.Size)
+	for _, chunk := range manifest.Chunks {
+		objType, part, err := r.readStoredObject(chunk.Object)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to read chunk %s: %w", chunk.Object, err)
+		}
+		if objType != "blob" || int64(len(part)) != chunk.Size {
+			return "", nil, fmt.Errorf("chunk %s does not match its manifest", chunk.Object)
+		}
+		content = append(content, part...)
+	}
+	return "blob", content, nil
+}
+
+// openChunkedBlob streams a chunked blob one chunk at a time
+func (r *Repository) openChunkedBlob(hash string, data []byte) (io.ReadCloser, error) {
+	manifest, err := parseChunkManifest(data)
+	if err != nil {
+		return nil, err
+	}
+	chunks := &chunkReader{repo: r, chunks: manifest.Chunks}
+	return newBlobReader(hash, manifest.Size, chunks, chunks), nil
+}
+
+// chunkReader concatenates the chunks of a chunked blob, opening each in turn
+type chunkReader struct {
+	repo    *Repository
+	chunks  []Chunk
+	current io.ReadCloser
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	for {
+		if c.current == nil {
+			if len(c.chunks) == 0 {
+				return 0, io.EOF
+			}
+			blob, err := c.repo.OpenBlob(c.chunks[0].Object)
+			if err != nil {
+				return 0, fmt.Errorf("failed to open chunk %s: %w", c.chunks[0].Object, err)
+			}
+			c.current = blob
+			c.chunks = c.chunks[1:]
+		}
+
+		n, err := c.current.Read(p)
+		if err == io.EOF {
+			c.current.Close()
+			c.current = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (c *chunkReader) Close() error {
+	if c.current != nil {
+		return c.current.Close()
+	}
+	return nil
+}
+
+// storedChunks returns the chunks of an object if it is stored as a chunked blob.
+// Only the object header is read for other objects.
+func (r *Repository) storedChunks(hash string) ([]Chunk, error) {
+	objType, err := r.storedType(hash)
+	if err != nil || objType != ChunkedType {
+		return nil, err
+	}
+	_, data, err := r.readStoredObject(hash)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := parseChunkManifest(data)
+	if err != nil {
+		return nil, err
+	}
+	return manifest.Chunks, nil
+}
+
+// storedType returns the type an object is stored as without reading its content.
+// A missing object has an empty type and no error.
+func (r *Repository) storedType(hash string) (string, error) {
+	file, err := os.Open(r.objectPath(hash))
+	if err == nil {
+		defer file.Close()
+		header, err := bufio.NewReaderSize(file, 64).ReadString(0)
+		if err != nil {
+			return "", fmt.Errorf("invalid object format: no null separator found")
+		}
+		objType, _, _, err := parseObjectHeader(strings.TrimSuffix(header, "\000"))
+		return objType, err
+	}
+	if !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to open object: %w", err)
+	}
+
+	packs, err := r.loadPacks()
+	if err != nil {
+		return "", err
+	}
+	for _, idx := range packs {
+		if offset, ok := idx.lookup(hash); ok {
+			objType, _, rc, err := idx.open(offset)
+			if err != nil {
+				return "", err
+			}
+			rc.Close()
+			return objType, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,108 @@
+package repo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+func chunkHashes(t *testing.T, content []byte) map[string]bool {
+	t.Helper()
+	hashes := make(map[string]bool)
+	c := newChunker(bytes.NewReader(content))
+	for {
+		chunk, err := c.next()
+		if err == io.EOF {
+			return hashes
+		}
+		if err != nil {
+			t.Fatalf("Chunker failed: %v", err)
+		}
+		if len(chunk) > chunkMax {
+			t.Fatalf("Chunk of %d bytes exceeds the maximum", len(chunk))
+		}
+		sum := sha256.Sum256(chunk)
+		hashes[hex.EncodeToString(sum[:])] = true
+	}
+}
+
+func TestChunkBoundariesSurviveEdits(t *testing.T) {
+	content := make([]byte, 4<<20)
+	rand.New(rand.NewSource(1)).Read(content)
+
+	edited := append([]byte{}, content[:1<<20]...)
+	edited = append(edited, []byte("inserted bytes")...)
+	edited = append(edited, content[1<<20:]...)
+
+	before := chunkHashes(t, content)
+	after := chunkHashes(t, edited)
+	shared := 0
+	for hash := range after {
+		if before[hash] {
+			shared++
+		}
+	}
+	if len(before) < 16 || shared < len(before)-3 {
+		t.Errorf("Expected all but a few of %d chunks to be shared, got %d", len(before), shared)
+	}
+}
+
+func TestLargeBlobsAreChunked(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+	repo.Config.Storage.ChunkThreshold = 1 << 20
+
+	content := make([]byte, 2<<20)
+	rand.New(rand.NewSource(2)).Read(content)
+	commitFiles(t, repo, map[string]string{"assets/1000635/intro.usm": string(content)}, "first")
+
+	hash, size, err := repo.WriteBlob(onlyReader{bytes.NewReader(content)})
+	if err != nil {
+		t.Fatalf("WriteBlob failed: %v", err)
+	}
+	sum := sha256.Sum256(content)
+	if hash != hex.EncodeToString(sum[:]) || size != int64(len(content)) {
+		t.Errorf("Expected the blob to be named by its content, got %s (%d bytes)", hash, size)
+	}
+	chunks, err := repo.storedChunks(hash)
+	if err != nil || len(chunks) < 2 {
+		t.Fatalf("Expected a chunked blob, got %d chunks (%v)", len(chunks), err)
+	}
+
+	readBack := func() {
+		t.Helper()
+		if objType, data, err := repo.readObject(hash); err != nil || objType != "blob" || !bytes.Equal(data, content) {
+			t.Errorf("readObject did not reassemble the blob (%s, %v)", objType, err)
+		}
+		blob, err := repo.OpenBlob(hash)
+		if err != nil {
+			t.Fatalf("OpenBlob failed: %v", err)
+		}
+		defer blob.Close()
+		if data, err := io.ReadAll(blob); err != nil || !bytes.Equal(data, content) {
+			t.Errorf("OpenBlob did not reassemble the blob: %v", err)
+		}
+	}
+	readBack()
+
+	if report, err := repo.GC(GCOptions{}); err != nil || len(report.Pruned) != 0 {
+		t.Fatalf("Expected gc to keep every chunk, got %+v (%v)", report, err)
+	}
+	if _, err := repo.Repack(RepackOptions{Window: DefaultPackWindow, Depth: DefaultPackDepth}); err != nil {
+		t.Fatalf("Repack failed: %v", err)
+	}
+	readBack()
+
+	fsck, err := repo.Fsck()
+	if err != nil {
+		t.Fatalf("Fsck failed: %v", err)
+	}
+	if !fsck.Healthy() {
+		t.Errorf("Expected healthy repository, got %v", fsck.Issues)
+	}
+}
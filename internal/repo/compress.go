@@ -0,0 +1,171 @@
+package repo
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Loose objects are written with a versioned header:
+//
+//	"v2 <type> <size> <encoding>\0<payload>"
+//
+// where size is the uncompressed length and encoding is EncodingZlib or EncodingRaw.
+// Objects written before compression existed use "<type> <size>\0<data>" and are
+// still read. The object hash is always the SHA-256 of the uncompressed data.
+const (
+	objectFormatVersion = "v2"
+
+	EncodingZlib = "zlib"
+	EncodingRaw  = "raw"
+
+	// CompressionNone disables compression of new objects
+	CompressionNone = "none"
+)
+
+// DefaultSkipCompression lists file extensions whose content is already compressed
+var DefaultSkipCompression = []string{
+	".usm", ".png", ".ogg", ".jpg", ".jpeg", ".mp3", ".mp4", ".webm", ".zip", ".gz", ".rdbdata",
+}
+
+// StorageConfig controls how new objects are written
+type StorageConfig struct {
+	// Compression is EncodingZlib (the default when empty) or CompressionNone
+	Compression string `json:"compression,omitempty"`
+
+	// SkipCompression lists extensions of files stored without compression;
+	// nil means DefaultSkipCompression and an empty list compresses everything
+	SkipCompression []string `json:"skip_compression"`
+
+	// ChunkThreshold is the blob size in bytes from which content is stored as
+	// content-defined chunks; 0 means DefaultChunkThreshold and a negative value disables chunking
+	ChunkThreshold int64 `json:"chunk_threshold,omitempty"`
+}
+
+// compressionEnabled reports whether new objects are compressed at all
+func (r *Repository) compressionEnabled() bool {
+	return r.Config.Storage.Compression != CompressionNone
+}
+
+// shouldCompress reports whether a blob stored under the given file name is compressed
+func (r *Repository) shouldCompress(name string) bool {
+	if !r.compressionEnabled() {
+		return false
+	}
+	skip := r.Config.Storage.SkipCompression
+	if skip == nil {
+		skip = DefaultSkipCompression
+	}
+	ext := strings.ToLower(path.Ext(name))
+	for _, s := range skip {
+		if strings.ToLower(s) == ext {
+			return false
+		}
+	}
+	return true
+}
+
+// objectHeader returns the versioned header for an object of the given type and size
+func objectHeader(objType string, size int64, compressed bool) string {
+	encoding := EncodingRaw
+	if compressed {
+		encoding = EncodingZlib
+	}
+	return fmt.Sprintf("%s %s %d %s\000", objectFormatVersion, objType, size, encoding)
+}
+
+// parseObjectHeader parses either header format, returning the type, uncompressed size and encoding
+func parseObjectHeader(header string) (string, int64, string, error) {
+	fields := strings.Fields(header)
+	switch {
+	case len(fields) == 2:
+		size, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil || size < 0 {
+			return "", 0, "", fmt.Errorf("failed to parse object header %q", header)
+		}
+		return fields[0], size, EncodingRaw, nil
+	case len(fields) == 4 && fields[0] == objectFormatVersion:
+		size, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil || size < 0 {
+			return "", 0, "", fmt.Errorf("failed to parse object header %q", header)
+		}
+		if fields[3] != EncodingZlib && fields[3] != EncodingRaw {
+			return "", 0, "", fmt.Errorf("unknown object encoding %q", fields[3])
+		}
+		return fields[1], size, fields[3], nil
+	case len(fields) > 0 && strings.HasPrefix(fields[0], "v"):
+		return "", 0, "", fmt.Errorf("unsupported object format %q", fields[0])
+	}
+	return "", 0, "", fmt.Errorf("failed to parse object header %q", header)
+}
+
+// parseObject decodes a whole loose object file, returning its type and uncompressed data
+func parseObject(raw []byte) (string, []byte, error) {
+	nullIndex := bytes.IndexByte(raw, 0)
+	if nullIndex == -1 {
+		return "", nil, fmt.Errorf("invalid object format: no null separator found")
+	}
+	objType, size, encoding, err := parseObjectHeader(string(raw[:nullIndex]))
+	if err != nil {
+		return "", nil, err
+	}
+
+	data := raw[nullIndex+1:]
+	if encoding == EncodingZlib {
+		if data, err = inflate(data, size); err != nil {
+			return objType, nil, err
+		}
+	}
+
+	if int64(len(data)) != size {
+		return objType, nil, fmt.Errorf("object size mismatch: expected %d, got %d", size, len(data))
+	}
+	return objType, data, nil
+}
+
+// encodeObject returns the loose object file content for data
+func encodeObject(objType string, data []byte, compressed bool) []byte {
+	header := objectHeader(objType, int64(len(data)), compressed)
+	if compressed {
+		data = deflate(data)
+	}
+	return append([]byte(header), data...)
+}
+
+// deflate compresses data with zlib
+func deflate(data []byte) []byte {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write(data)
+	zw.Close()
+	return buf.Bytes()
+}
+
+// inflate decompresses zlib data, reading at most one byte more than the expected size
+func inflate(data []byte, size int64) ([]byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress object: %w", err)
+	}
+	defer zr.Close()
+	out, err := io.ReadAll(io.LimitReader(zr, size+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress object: %w", err)
+	}
+	return out, nil
+}
+
+// zlibReadCloser decompresses a stream and closes both the decompressor and the source
+type zlibReadCloser struct {
+	io.ReadCloser
+	src io.Closer
+}
+
+func (z *zlibReadCloser) Close() error {
+	z.ReadCloser.Close()
+	return z.src.Close()
+}
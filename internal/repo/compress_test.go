@@ -0,0 +1,107 @@
+package repo
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestReadsLegacyUncompressedObjects(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+
+	hash, err := repo.writeRawObject("blob", []byte("legacy"))
+	if err != nil {
+		t.Fatalf("Failed to write blob: %v", err)
+	}
+	if err := os.WriteFile(repo.objectPath(hash), []byte("blob 6\000legacy"), 0644); err != nil {
+		t.Fatalf("Failed to write legacy object: %v", err)
+	}
+
+	if _, data, err := repo.readObject(hash); err != nil || string(data) != "legacy" {
+		t.Errorf("Expected legacy content, got %q (%v)", data, err)
+	}
+	blob, err := repo.OpenBlob(hash)
+	if err != nil {
+		t.Fatalf("OpenBlob failed: %v", err)
+	}
+	defer blob.Close()
+	if data, err := io.ReadAll(blob); err != nil || string(data) != "legacy" {
+		t.Errorf("Expected legacy content, got %q (%v)", data, err)
+	}
+	if _, err := repo.verifyObject(hash); err != nil {
+		t.Errorf("Expected legacy object to verify: %v", err)
+	}
+}
+
+func TestStageFileCompressesByExtension(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+
+	content := strings.Repeat("<string id=\"1\">Hello</string>\n", 1000)
+	commitFiles(t, repo, map[string]string{
+		"assets/1030002/en.xml":   content,
+		"assets/1030002/logo.png": content + "png",
+	}, "first")
+
+	idx, err := repo.LoadIndex()
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+	xmlEntry, _ := idx.Get("assets/1030002/en.xml")
+	pngEntry, _ := idx.Get("assets/1030002/logo.png")
+
+	stored := func(hash string) (string, int64) {
+		t.Helper()
+		raw, err := os.ReadFile(repo.objectPath(hash))
+		if err != nil {
+			t.Fatalf("Failed to read object: %v", err)
+		}
+		header := string(raw[:strings.IndexByte(string(raw), 0)])
+		return header, int64(len(raw))
+	}
+
+	if header, size := stored(xmlEntry.Object); !strings.HasSuffix(header, EncodingZlib) || size > int64(len(content))/10 {
+		t.Errorf("Expected compressed XML, got header %q and %d bytes", header, size)
+	}
+	if header, _ := stored(pngEntry.Object); !strings.HasSuffix(header, EncodingRaw) {
+		t.Errorf("Expected PNG to be stored raw, got header %q", header)
+	}
+
+	if _, data, err := repo.readObject(xmlEntry.Object); err != nil || string(data) != content {
+		t.Errorf("Failed to read compressed object back: %v", err)
+	}
+}
+
+func TestCompressionCanBeDisabled(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+	repo.Config.Storage.Compression = CompressionNone
+	if repo.shouldCompress("assets/1030002/en.txt") {
+		t.Error("Expected no compression when disabled")
+	}
+
+	hash, _, err := repo.WriteBlob(strings.NewReader(strings.Repeat("a", 1000)))
+	if err != nil {
+		t.Fatalf("WriteBlob failed: %v", err)
+	}
+	raw, err := os.ReadFile(repo.objectPath(hash))
+	if err != nil {
+		t.Fatalf("Failed to read object: %v", err)
+	}
+	if !strings.HasPrefix(string(raw), "v2 blob 1000 raw\000") {
+		t.Errorf("Expected raw versioned object, got %q", raw[:20])
+	}
+
+	repo.Config.Storage = StorageConfig{SkipCompression: []string{}}
+	if !repo.shouldCompress("assets/1000635/intro.usm") {
+		t.Error("Expected an empty skip list to compress everything")
+	}
+}
@@ -0,0 +1,127 @@
+package repo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Binary deltas describe a target as a sequence of copies from a base and literal inserts:
+//
+//	uvarint base size, uvarint target size, then ops until the end:
+//	deltaInsert, uvarint n, n literal bytes
+//	deltaCopy, uvarint offset, uvarint n (copy n bytes of the base from offset)
+const (
+	deltaInsert = 0
+	deltaCopy   = 1
+
+	// deltaBlock is the match granularity; shorter repeats are stored as literals
+	deltaBlock = 16
+)
+
+var errBadDelta = errors.New("invalid delta")
+
+// computeDelta encodes target as a delta against base
+func computeDelta(base, target []byte) []byte {
+	blocks := make(map[string]int, len(base)/deltaBlock+1)
+	for i := 0; i+deltaBlock <= len(base); i += deltaBlock {
+		key := string(base[i : i+deltaBlock])
+		if _, ok := blocks[key]; !ok {
+			blocks[key] = i
+		}
+	}
+
+	var out bytes.Buffer
+	writeUvarint(&out, uint64(len(base)))
+	writeUvarint(&out, uint64(len(target)))
+
+	literalStart := 0
+	flush := func(end int) {
+		if end > literalStart {
+			out.WriteByte(deltaInsert)
+			writeUvarint(&out, uint64(end-literalStart))
+			out.Write(target[literalStart:end])
+		}
+	}
+
+	for p := 0; p+deltaBlock <= len(target); {
+		offset, ok := blocks[string(target[p:p+deltaBlock])]
+		if !ok {
+			p++
+			continue
+		}
+
+		// Extend the match backwards over pending literals, then forwards
+		start := p
+		for start > literalStart && offset > 0 && base[offset-1] == target[start-1] {
+			start--
+			offset--
+		}
+		end := p + deltaBlock
+		for end < len(target) && offset+(end-start) < len(base) && base[offset+(end-start)] == target[end] {
+			end++
+		}
+
+		flush(start)
+		out.WriteByte(deltaCopy)
+		writeUvarint(&out, uint64(offset))
+		writeUvarint(&out, uint64(end-start))
+		p = end
+		literalStart = end
+	}
+	flush(len(target))
+
+	return out.Bytes()
+}
+
+// applyDelta rebuilds a target from its base and delta
+func applyDelta(base, delta []byte) ([]byte, error) {
+	r := bytes.NewReader(delta)
+	baseSize, err := binary.ReadUvarint(r)
+	if err != nil || baseSize != uint64(len(base)) {
+		return nil, fmt.Errorf("%w: base size mismatch", errBadDelta)
+	}
+	targetSize, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, errBadDelta
+	}
+
+	target := make([]byte, 0, targetSize)
+	for r.Len() > 0 {
+		op, _ := r.ReadByte()
+		switch op {
+		case deltaInsert:
+			n, err := binary.ReadUvarint(r)
+			if err != nil || n > uint64(r.Len()) {
+				return nil, errBadDelta
+			}
+			literal := make([]byte, n)
+			r.Read(literal)
+			target = append(target, literal...)
+		case deltaCopy:
+			offset, err := binary.ReadUvarint(r)
+			if err != nil {
+				return nil, errBadDelta
+			}
+			n, err := binary.ReadUvarint(r)
+			if err != nil || offset+n > uint64(len(base)) {
+				return nil, errBadDelta
+			}
+			target = append(target, base[offset:offset+n]...)
+		default:
+			return nil, fmt.Errorf("%w: unknown op %d", errBadDelta, op)
+		}
+	}
+
+	if uint64(len(target)) != targetSize {
+		return nil, fmt.Errorf("%w: target size mismatch", errBadDelta)
+	}
+	return target, nil
+}
+
+// writeUvarint appends v to buf in unsigned varint encoding
+func writeUvarint(buf *bytes.Buffer, v uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	buf.Write(tmp[:binary.PutUvarint(tmp[:], v)])
+}
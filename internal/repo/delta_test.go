@@ -0,0 +1,50 @@
+package repo
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestDeltaRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	base := make([]byte, 64*1024)
+	rng.Read(base)
+
+	// Insert, overwrite and delete a few regions
+	target := append([]byte{}, base[:1000]...)
+	target = append(target, []byte("inserted bytes")...)
+	target = append(target, base[1000:30000]...)
+	target = append(target, bytes.Repeat([]byte{0xAB}, 500)...)
+	target = append(target, base[40000:]...)
+
+	delta := computeDelta(base, target)
+	if len(delta) > len(target)/10 {
+		t.Errorf("Expected a small delta, got %d bytes for a %d byte target", len(delta), len(target))
+	}
+
+	got, err := applyDelta(base, delta)
+	if err != nil {
+		t.Fatalf("Failed to apply delta: %v", err)
+	}
+	if !bytes.Equal(got, target) {
+		t.Error("Delta did not reproduce the target")
+	}
+
+	if _, err := applyDelta(base[1:], delta); err == nil {
+		t.Error("Expected delta against the wrong base to fail")
+	}
+}
+
+func TestDeltaUnrelatedContent(t *testing.T) {
+	base := []byte("<treasure id=\"1\"/>")
+	target := []byte("entirely different content that shares nothing")
+
+	got, err := applyDelta(base, computeDelta(base, target))
+	if err != nil {
+		t.Fatalf("Failed to apply delta: %v", err)
+	}
+	if !bytes.Equal(got, target) {
+		t.Errorf("Expected %q, got %q", target, got)
+	}
+}
@@ -0,0 +1,566 @@
+package repo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/gif"  // register GIF for dimension summaries
+	_ "image/jpeg" // register JPEG for dimension summaries
+	_ "image/png"  // register PNG for dimension summaries
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// DiffFile is one side of a changed file
+type DiffFile struct {
+	Object  string `json:"object,omitempty"` // blob hash; empty if the file does not exist on this side
+	Size    int64  `json:"size"`
+	AbsPath string `json:"-"` // set when the content is read from the working tree
+}
+
+// Exists reports whether the file exists on this side
+func (f DiffFile) Exists() bool {
+	return f.Object != ""
+}
+
+// FileDiff is a file that differs between two snapshots
+type FileDiff struct {
+	Path   string   `json:"path"`   // canonical tree path
+	Status string   `json:"status"` // "added", "modified" or "deleted"
+	Old    DiffFile `json:"old"`
+	New    DiffFile `json:"new"`
+}
+
+// AssetDiff groups the changed files of one asset
+type AssetDiff struct {
+	Path    string     `json:"path"` // canonical asset directory, such as assets/1030002
+	ID      int        `json:"id,omitempty"`
+	Type    string     `json:"type,omitempty"`    // slug from meta.json
+	TypeID  int        `json:"type_id,omitempty"` // registered type, 0 if unknown
+	KeyPath string     `json:"-"`                 // XML key path of the registered type
+	Status  string     `json:"status"`            // "added", "modified" or "deleted"
+	Files   []FileDiff `json:"files"`
+}
+
+// Diff statuses of files and assets
+const (
+	DiffAdded    = "added"
+	DiffModified = "modified"
+	DiffDeleted  = "deleted"
+)
+
+// DiffSnapshot is a set of files to compare, keyed by canonical tree path
+type DiffSnapshot map[string]DiffFile
+
+// TreeSnapshot returns the files of a root tree
+func (r *Repository) TreeSnapshot(treeHash string) (DiffSnapshot, error) {
+	files, err := r.FlattenTree(treeHash)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := make(DiffSnapshot, len(files))
+	for p, entry := range files {
+		snapshot[p] = DiffFile{Object: entry.Object, Size: entry.Size}
+	}
+	return snapshot, nil
+}
+
+// IndexSnapshot returns the files staged in the index. Unresolved conflicts are left out.
+func (r *Repository) IndexSnapshot(idx *Index) DiffSnapshot {
+	layout := r.Layout()
+	snapshot := make(DiffSnapshot, len(idx.Entries))
+	for _, entry := range idx.Entries {
+		if entry.Conflict == nil {
+			snapshot[layout.TreePath(entry.Path)] = DiffFile{Object: entry.Object, Size: entry.Size}
+		}
+	}
+	return snapshot
+}
+
+// WorkTreeSnapshot returns the tracked files as they are in the working tree. Files
+// whose size and modification time match the index are not rehashed; untracked files
+// are left out, as they are not part of any diff until added.
+func (r *Repository) WorkTreeSnapshot(idx *Index) (DiffSnapshot, error) {
+	layout := r.Layout()
+	snapshot := make(DiffSnapshot, len(idx.Entries))
+	for _, entry := range idx.Entries {
+		if entry.Conflict != nil {
+			continue
+		}
+		absPath := filepath.Join(r.Path, filepath.FromSlash(entry.Path))
+		info, err := os.Stat(absPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to stat %s: %w", entry.Path, err)
+		}
+		hash := entry.Object
+		if info.Size() != entry.Size || !info.ModTime().Equal(entry.ModTime) {
+			if hash, err = HashFile(absPath); err != nil {
+				return nil, fmt.Errorf("failed to hash %s: %w", entry.Path, err)
+			}
+		}
+		snapshot[layout.TreePath(entry.Path)] = DiffFile{Object: hash, Size: info.Size(), AbsPath: absPath}
+	}
+	return snapshot, nil
+}
+
+// DiffSnapshots compares two snapshots and groups the changed files by asset. An asset
+// is the deepest directory holding a meta.json on either side; files outside any asset
+// are grouped by their directory. Only paths at or below one of the given canonical
+// paths are compared, unless paths is empty.
+func (r *Repository) DiffSnapshots(old, new DiffSnapshot, paths []string) ([]AssetDiff, error) {
+	// Directories that are assets on either side
+	assetDirs := make(map[string]bool)
+	for _, snapshot := range []DiffSnapshot{old, new} {
+		for p := range snapshot {
+			if path.Base(p) == MetaFileName {
+				assetDirs[path.Dir(p)] = true
+			}
+		}
+	}
+	assetDir := func(p string) string {
+		for dir := path.Dir(p); dir != "." && dir != "/"; dir = path.Dir(dir) {
+			if assetDirs[dir] {
+				return dir
+			}
+		}
+		return path.Dir(p)
+	}
+
+	all := make(map[string]bool)
+	for _, snapshot := range []DiffSnapshot{old, new} {
+		for p := range snapshot {
+			if len(paths) == 0 || underAnyPath(p, paths) {
+				all[p] = true
+			}
+		}
+	}
+
+	assets := make(map[string]*AssetDiff)
+	for p := range all {
+		before, after := old[p], new[p]
+		if before.Object == after.Object {
+			continue
+		}
+		file := FileDiff{Path: p, Status: DiffModified, Old: before, New: after}
+		switch {
+		case !before.Exists():
+			file.Status = DiffAdded
+		case !after.Exists():
+			file.Status = DiffDeleted
+		}
+
+		dir := assetDir(p)
+		asset, ok := assets[dir]
+		if !ok {
+			asset = &AssetDiff{Path: dir, Status: DiffModified}
+			assets[dir] = asset
+		}
+		asset.Files = append(asset.Files, file)
+	}
+
+	reg, err := r.LoadTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	diffs := make([]AssetDiff, 0, len(assets))
+	for dir, asset := range assets {
+		sort.Slice(asset.Files, func(i, j int) bool { return asset.Files[i].Path < asset.Files[j].Path })
+
+		// The asset itself was added or deleted when its meta.json was
+		metaPath := path.Join(dir, MetaFileName)
+		before, after := old[metaPath], new[metaPath]
+		switch {
+		case !before.Exists() && after.Exists():
+			asset.Status = DiffAdded
+		case before.Exists() && !after.Exists():
+			asset.Status = DiffDeleted
+		}
+
+		meta := after
+		if !meta.Exists() {
+			meta = before
+		}
+		if meta.Exists() {
+			if data, err := r.ReadDiffFile(meta); err == nil {
+				if parsed, err := ParseAssetMeta(data); err == nil {
+					asset.ID = parsed.ID
+					asset.Type = parsed.Type
+					if t, ok := reg.Resolve(parsed); ok {
+						asset.TypeID = t.ID
+						asset.KeyPath = t.KeyPath
+					}
+				}
+			}
+		}
+		if asset.ID == 0 {
+			// Fall back to the asset ID folder name
+			if id, err := strconv.Atoi(path.Base(dir)); err == nil && isAssetID(path.Base(dir)) {
+				asset.ID = id
+				if t, ok := reg.Get(id); ok {
+					asset.TypeID = t.ID
+					asset.Type = t.Slug
+					asset.KeyPath = t.KeyPath
+				}
+			}
+		}
+
+		diffs = append(diffs, *asset)
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Path < diffs[j].Path })
+	return diffs, nil
+}
+
+// underAnyPath reports whether p equals or lies below any of the given paths
+func underAnyPath(p string, paths []string) bool {
+	for _, spec := range paths {
+		if spec == "." || p == spec || strings.HasPrefix(p, spec+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// OpenDiffFile opens the content of one side of a changed file
+func (r *Repository) OpenDiffFile(f DiffFile) (io.ReadCloser, error) {
+	if !f.Exists() {
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	}
+	if f.AbsPath != "" {
+		return os.Open(f.AbsPath)
+	}
+	return r.OpenBlob(f.Object)
+}
+
+// ReadDiffFile reads the content of one side of a changed file
+func (r *Repository) ReadDiffFile(f DiffFile) ([]byte, error) {
+	reader, err := r.OpenDiffFile(f)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// DiffRenderer writes a human-readable description of the change to one file of an asset
+type DiffRenderer func(w io.Writer, r *Repository, asset *AssetDiff, file FileDiff) error
+
+// diffRenderers holds the renderers registered per asset type ID
+var diffRenderers = map[int]DiffRenderer{}
+
+// RegisterDiffRenderer makes renderer the diff renderer for payload files of the asset
+// type with the given ID, replacing any earlier registration. meta.json files are
+// always rendered with RenderJSONDiff.
+func RegisterDiffRenderer(typeID int, renderer DiffRenderer) {
+	diffRenderers[typeID] = renderer
+}
+
+func init() {
+	// Strings and misc text are line-oriented
+	for _, typeID := range []int{1030002, 1000623} {
+		RegisterDiffRenderer(typeID, RenderTextDiff)
+	}
+}
+
+// DiffRendererFor returns the renderer for a file of the given asset. Files of types
+// without a registered renderer are compared as XML if the type has a key path, shown
+// as text diffs if they look like text and summarized as binaries otherwise.
+func DiffRendererFor(asset *AssetDiff, file FileDiff) DiffRenderer {
+	if path.Base(file.Path) == MetaFileName {
+		return RenderJSONDiff
+	}
+	if renderer, ok := diffRenderers[asset.TypeID]; ok {
+		return renderer
+	}
+	if asset.KeyPath != "" {
+		return RenderXMLDiff
+	}
+	return renderDetectedDiff
+}
+
+// renderDetectedDiff renders a file as text or binary depending on its content
+func renderDetectedDiff(w io.Writer, r *Repository, asset *AssetDiff, file FileDiff) error {
+	for _, side := range []DiffFile{file.Old, file.New} {
+		text, err := r.looksLikeText(side)
+		if err != nil {
+			return err
+		}
+		if !text {
+			return RenderBinaryDiff(w, r, asset, file)
+		}
+	}
+	return RenderTextDiff(w, r, asset, file)
+}
+
+// looksLikeText reports whether the start of a file is valid UTF-8 without NUL bytes
+func (r *Repository) looksLikeText(f DiffFile) (bool, error) {
+	reader, err := r.OpenDiffFile(f)
+	if err != nil {
+		return false, err
+	}
+	defer reader.Close()
+	head := make([]byte, 8000)
+	n, err := io.ReadFull(reader, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+	return isText(head[:n], n < len(head)), nil
+}
+
+// isText reports whether data is UTF-8 text without NUL bytes. Unless complete is set,
+// data may end in the middle of a multi-byte character.
+func isText(data []byte, complete bool) bool {
+	if bytes.IndexByte(data, 0) >= 0 {
+		return false
+	}
+	if !complete {
+		// Drop a trailing partial rune
+		for i := 0; i < utf8.UTFMax && len(data) > 0 && !utf8.Valid(data); i++ {
+			data = data[:len(data)-1]
+		}
+	}
+	return utf8.Valid(data)
+}
+
+// diffNames returns the a/ and b/ labels of a file diff, /dev/null for a missing side
+func diffNames(file FileDiff) (string, string) {
+	oldName, newName := "a/"+file.Path, "b/"+file.Path
+	if !file.Old.Exists() {
+		oldName = "/dev/null"
+	}
+	if !file.New.Exists() {
+		newName = "/dev/null"
+	}
+	return oldName, newName
+}
+
+// RenderTextDiff renders a file as a unified line diff, falling back to
+// RenderBinaryDiff when either side is not text
+func RenderTextDiff(w io.Writer, r *Repository, asset *AssetDiff, file FileDiff) error {
+	oldData, err := r.ReadDiffFile(file.Old)
+	if err != nil {
+		return err
+	}
+	newData, err := r.ReadDiffFile(file.New)
+	if err != nil {
+		return err
+	}
+	if !isText(oldData, true) || !isText(newData, true) {
+		return RenderBinaryDiff(w, r, asset, file)
+	}
+
+	oldName, newName := diffNames(file)
+	return WriteUnifiedDiff(w, oldName, newName, string(bytes.TrimPrefix(oldData, utf8BOM)), string(bytes.TrimPrefix(newData, utf8BOM)))
+}
+
+// jsonChange is one difference between two JSON documents
+type jsonChange struct {
+	Op      byte   // '+' added, '-' removed, '~' changed
+	Pointer string // JSON pointer of the value
+	Old     interface{}
+	New     interface{}
+}
+
+// diffJSON collects the differences between two decoded JSON values, descending into
+// objects by key and into arrays by index
+func diffJSON(pointer string, old, new interface{}, changes *[]jsonChange) {
+	switch o := old.(type) {
+	case map[string]interface{}:
+		if n, ok := new.(map[string]interface{}); ok {
+			keys := make([]string, 0, len(o)+len(n))
+			for key := range o {
+				keys = append(keys, key)
+			}
+			for key := range n {
+				if _, ok := o[key]; !ok {
+					keys = append(keys, key)
+				}
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				childPointer := pointer + "/" + escapePointer(key)
+				ov, inOld := o[key]
+				nv, inNew := n[key]
+				switch {
+				case !inOld:
+					*changes = append(*changes, jsonChange{Op: '+', Pointer: childPointer, New: nv})
+				case !inNew:
+					*changes = append(*changes, jsonChange{Op: '-', Pointer: childPointer, Old: ov})
+				default:
+					diffJSON(childPointer, ov, nv, changes)
+				}
+			}
+			return
+		}
+	case []interface{}:
+		if n, ok := new.([]interface{}); ok {
+			for i := 0; i < len(o) || i < len(n); i++ {
+				childPointer := pointer + "/" + strconv.Itoa(i)
+				switch {
+				case i >= len(o):
+					*changes = append(*changes, jsonChange{Op: '+', Pointer: childPointer, New: n[i]})
+				case i >= len(n):
+					*changes = append(*changes, jsonChange{Op: '-', Pointer: childPointer, Old: o[i]})
+				default:
+					diffJSON(childPointer, o[i], n[i], changes)
+				}
+			}
+			return
+		}
+	}
+	if !jsonEqual(old, new) {
+		*changes = append(*changes, jsonChange{Op: '~', Pointer: pointer, Old: old, New: new})
+	}
+}
+
+// RenderJSONDiff renders a JSON file such as meta.json as a list of changed values,
+// one line per JSON pointer. Files that do not parse are shown as text diffs.
+func RenderJSONDiff(w io.Writer, r *Repository, asset *AssetDiff, file FileDiff) error {
+	decode := func(f DiffFile) (interface{}, bool) {
+		if !f.Exists() {
+			return map[string]interface{}{}, true
+		}
+		data, err := r.ReadDiffFile(f)
+		if err != nil {
+			return nil, false
+		}
+		decoder := json.NewDecoder(bytes.NewReader(bytes.TrimPrefix(data, utf8BOM)))
+		decoder.UseNumber()
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return nil, false
+		}
+		return value, true
+	}
+	oldValue, ok := decode(file.Old)
+	if !ok {
+		return RenderTextDiff(w, r, asset, file)
+	}
+	newValue, ok := decode(file.New)
+	if !ok {
+		return RenderTextDiff(w, r, asset, file)
+	}
+
+	var changes []jsonChange
+	diffJSON("", oldValue, newValue, &changes)
+
+	bw := bufio.NewWriter(w)
+	oldName, newName := diffNames(file)
+	fmt.Fprintf(bw, "--- %s\n+++ %s\n", oldName, newName)
+	if len(changes) == 0 {
+		// Only formatting differs
+		fmt.Fprintln(bw, "  (formatting only)")
+	}
+	for _, change := range changes {
+		pointer := change.Pointer
+		if pointer == "" {
+			pointer = "/"
+		}
+		switch change.Op {
+		case '+':
+			fmt.Fprintf(bw, "+ %s: %s\n", pointer, compactJSON(change.New))
+		case '-':
+			fmt.Fprintf(bw, "- %s: %s\n", pointer, compactJSON(change.Old))
+		default:
+			fmt.Fprintf(bw, "~ %s: %s -> %s\n", pointer, compactJSON(change.Old), compactJSON(change.New))
+		}
+	}
+	return bw.Flush()
+}
+
+// RenderBinaryDiff summarizes a binary file by size, hash and, for images it can
+// read, dimensions
+func RenderBinaryDiff(w io.Writer, r *Repository, asset *AssetDiff, file FileDiff) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "Binary file %s %s\n", file.Path, file.Status)
+
+	// row prints a value of the side that exists, or "old -> new" for a modified file
+	row := func(label, oldValue, newValue string) {
+		switch {
+		case !file.Old.Exists():
+			fmt.Fprintf(bw, "  %-11s %s\n", label+":", newValue)
+		case !file.New.Exists():
+			fmt.Fprintf(bw, "  %-11s %s\n", label+":", oldValue)
+		default:
+			fmt.Fprintf(bw, "  %-11s %s -> %s\n", label+":", oldValue, newValue)
+		}
+	}
+
+	size := func(f DiffFile) string { return FormatBytes(f.Size) }
+	oldSize, newSize := size(file.Old), size(file.New)
+	if delta := file.New.Size - file.Old.Size; file.Old.Exists() && file.New.Exists() && delta != 0 {
+		if delta > 0 {
+			newSize += fmt.Sprintf(" (+%s)", FormatBytes(delta))
+		} else {
+			newSize += fmt.Sprintf(" (-%s)", FormatBytes(-delta))
+		}
+	}
+	row("size", oldSize, newSize)
+
+	hash := func(f DiffFile) string { return f.Object[:min(len(f.Object), 12)] }
+	row("hash", hash(file.Old), hash(file.New))
+
+	oldDims, newDims := r.imageDimensions(file.Old), r.imageDimensions(file.New)
+	if oldDims != "" || newDims != "" {
+		if oldDims == "" {
+			oldDims = "unknown"
+		}
+		if newDims == "" {
+			newDims = "unknown"
+		}
+		row("dimensions", oldDims, newDims)
+	}
+
+	return bw.Flush()
+}
+
+// imageDimensions returns "<width>x<height>" for PNG, JPEG, GIF and DDS images, or ""
+func (r *Repository) imageDimensions(f DiffFile) string {
+	if !f.Exists() {
+		return ""
+	}
+	reader, err := r.OpenDiffFile(f)
+	if err != nil {
+		return ""
+	}
+	defer reader.Close()
+
+	br := bufio.NewReader(reader)
+	if header, err := br.Peek(20); err == nil && string(header[:4]) == "DDS " {
+		// DDS_HEADER: dwSize, dwFlags, dwHeight, dwWidth follow the magic
+		height := binary.LittleEndian.Uint32(header[12:16])
+		width := binary.LittleEndian.Uint32(header[16:20])
+		return fmt.Sprintf("%dx%d", width, height)
+	}
+	config, _, err := image.DecodeConfig(br)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%dx%d", config.Width, config.Height)
+}
+
+// FormatBytes renders a byte count with a binary unit suffix
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,217 @@
+package repo
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// pngData encodes a blank image of the given size
+func pngData(t *testing.T, width, height int) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+	return buf.String()
+}
+
+// commitSnapshot returns the tree snapshot of a commit
+func commitSnapshot(t *testing.T, repo *Repository, commitHash string) DiffSnapshot {
+	t.Helper()
+	commit, err := repo.ReadCommit(commitHash)
+	if err != nil {
+		t.Fatalf("Failed to read commit: %v", err)
+	}
+	snapshot, err := repo.TreeSnapshot(commit.Tree)
+	if err != nil {
+		t.Fatalf("Failed to read tree: %v", err)
+	}
+	return snapshot
+}
+
+// renderDiff renders every file of the given asset diff
+func renderDiff(t *testing.T, repo *Repository, asset AssetDiff) string {
+	t.Helper()
+	var out strings.Builder
+	for _, file := range asset.Files {
+		if err := DiffRendererFor(&asset, file)(&out, repo, &asset, file); err != nil {
+			t.Fatalf("Failed to render %s: %v", file.Path, err)
+		}
+	}
+	return out.String()
+}
+
+func TestDiffSnapshotsGroupsByAsset(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+	first := commitFiles(t, repo, map[string]string{
+		"assets/1030002/meta.json": `{"id": 1030002, "type": "string", "name": "Intro", "tags": ["a", "b"]}`,
+		"assets/1030002/en.txt":    "hello\nworld\n",
+		"assets/1000636/meta.json": `{"id": 1000636, "type": "image"}`,
+		"assets/1000636/logo.png":  pngData(t, 4, 4),
+	}, "first")
+	if err := os.RemoveAll(filepath.Join(repo.Path, "assets", "1000636")); err != nil {
+		t.Fatalf("Failed to remove asset: %v", err)
+	}
+	idx, _ := repo.LoadIndex()
+	idx.Remove("assets/1000636/meta.json")
+	idx.Remove("assets/1000636/logo.png")
+	if err := repo.SaveIndex(idx); err != nil {
+		t.Fatalf("Failed to save index: %v", err)
+	}
+	second := commitFiles(t, repo, map[string]string{
+		"assets/1030002/meta.json": `{"id": 1030002, "type": "string", "name": "Outro", "tags": ["a"], "version": 2}`,
+		"assets/1030002/en.txt":    "hello\nthere\n",
+		"assets/1030003/meta.json": `{"id": 1030003, "type": "string"}`,
+		"assets/1030003/en.txt":    "new\n",
+	}, "second")
+
+	diffs, err := repo.DiffSnapshots(commitSnapshot(t, repo, first), commitSnapshot(t, repo, second), nil)
+	if err != nil {
+		t.Fatalf("DiffSnapshots failed: %v", err)
+	}
+	if len(diffs) != 3 {
+		t.Fatalf("Expected 3 changed assets, got %+v", diffs)
+	}
+	wantStatus := map[string]string{"assets/1000636": DiffDeleted, "assets/1030002": DiffModified, "assets/1030003": DiffAdded}
+	for _, asset := range diffs {
+		if asset.Status != wantStatus[asset.Path] || len(asset.Files) != 2 {
+			t.Errorf("Unexpected asset diff: %+v", asset)
+		}
+	}
+	if diffs[1].ID != 1030002 || diffs[1].Type != "string" || diffs[1].TypeID != 1030002 {
+		t.Errorf("Expected the asset type from meta.json, got %+v", diffs[1])
+	}
+
+	// Path filters
+	filtered, err := repo.DiffSnapshots(commitSnapshot(t, repo, first), commitSnapshot(t, repo, second), []string{"assets/1030003"})
+	if err != nil || len(filtered) != 1 || filtered[0].Path != "assets/1030003" {
+		t.Errorf("Expected only the filtered asset, got %+v (%v)", filtered, err)
+	}
+
+	out := renderDiff(t, repo, diffs[1])
+	for _, want := range []string{
+		"-world\n+there\n",
+		`~ /name: "Intro" -> "Outro"`,
+		`- /tags/1: "b"`,
+		"+ /version: 2",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in diff:\n%s", want, out)
+		}
+	}
+
+	out = renderDiff(t, repo, diffs[0])
+	for _, want := range []string{"Binary file assets/1000636/logo.png deleted", "dimensions: 4x4"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in diff:\n%s", want, out)
+		}
+	}
+}
+
+func TestRenderBinaryDiffSummarizesImages(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+	first := commitFiles(t, repo, map[string]string{"assets/1000636/logo.png": pngData(t, 4, 4)}, "first")
+	dds := "DDS " + string([]byte{124, 0, 0, 0, 7, 0x10, 0, 0, 32, 0, 0, 0, 64, 0, 0, 0}) + strings.Repeat("\x00", 100)
+	second := commitFiles(t, repo, map[string]string{"assets/1000636/logo.png": pngData(t, 16, 8), "assets/1000636/logo.dds": dds}, "second")
+
+	diffs, err := repo.DiffSnapshots(commitSnapshot(t, repo, first), commitSnapshot(t, repo, second), nil)
+	if err != nil || len(diffs) != 1 {
+		t.Fatalf("Unexpected diff: %+v (%v)", diffs, err)
+	}
+	out := renderDiff(t, repo, diffs[0])
+	for _, want := range []string{
+		"Binary file assets/1000636/logo.dds added",
+		"dimensions: 64x32",
+		"Binary file assets/1000636/logo.png modified",
+		"dimensions: 4x4 -> 16x8",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in diff:\n%s", want, out)
+		}
+	}
+}
+
+func TestRegisterDiffRenderer(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+	first := commitFiles(t, repo, map[string]string{"assets/1020002/hit.wav": "RIFF one"}, "first")
+	second := commitFiles(t, repo, map[string]string{"assets/1020002/hit.wav": "RIFF two"}, "second")
+
+	diffs, err := repo.DiffSnapshots(commitSnapshot(t, repo, first), commitSnapshot(t, repo, second), nil)
+	if err != nil || len(diffs) != 1 || diffs[0].TypeID != 1020002 {
+		t.Fatalf("Unexpected diff: %+v (%v)", diffs, err)
+	}
+
+	// Without a renderer the text content is shown as a text diff
+	if out := renderDiff(t, repo, diffs[0]); !strings.Contains(out, "-RIFF one\n") {
+		t.Errorf("Expected a text diff, got:\n%s", out)
+	}
+
+	RegisterDiffRenderer(1020002, func(w io.Writer, r *Repository, asset *AssetDiff, file FileDiff) error {
+		_, err := io.WriteString(w, "audio changed\n")
+		return err
+	})
+	defer delete(diffRenderers, 1020002)
+	if out := renderDiff(t, repo, diffs[0]); out != "audio changed\n" {
+		t.Errorf("Expected the registered renderer to be used, got:\n%s", out)
+	}
+}
+
+func TestWorkTreeSnapshot(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+	head := commitFiles(t, repo, map[string]string{
+		"assets/1030002/en.txt": "one\n",
+		"assets/1030002/de.txt": "eins\n",
+	}, "first")
+
+	if err := os.WriteFile(filepath.Join(repo.Path, "assets", "1030002", "en.txt"), []byte("two\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.Remove(filepath.Join(repo.Path, "assets", "1030002", "de.txt")); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repo.Path, "assets", "1030002", "fr.txt"), []byte("untracked\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	idx, err := repo.LoadIndex()
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+	work, err := repo.WorkTreeSnapshot(idx)
+	if err != nil {
+		t.Fatalf("WorkTreeSnapshot failed: %v", err)
+	}
+	diffs, err := repo.DiffSnapshots(commitSnapshot(t, repo, head), work, nil)
+	if err != nil || len(diffs) != 1 || len(diffs[0].Files) != 2 {
+		t.Fatalf("Unexpected diff: %+v (%v)", diffs, err)
+	}
+	if de, en := diffs[0].Files[0], diffs[0].Files[1]; de.Status != DiffDeleted || en.Status != DiffModified {
+		t.Errorf("Unexpected file statuses: %+v", diffs[0].Files)
+	}
+	if out := renderDiff(t, repo, diffs[0]); !strings.Contains(out, "-one\n+two\n") {
+		t.Errorf("Expected the working tree content in the diff, got:\n%s", out)
+	}
+
+	// The index matches HEAD
+	if diffs, _ := repo.DiffSnapshots(commitSnapshot(t, repo, head), repo.IndexSnapshot(idx), nil); len(diffs) != 0 {
+		t.Errorf("Expected no staged changes, got %+v", diffs)
+	}
+}
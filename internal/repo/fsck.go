@@ -0,0 +1,467 @@
+package repo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Kinds of problems reported by Fsck
+const (
+	FsckCorrupt  = "corrupt"  // object content does not match its name or header
+	FsckMissing  = "missing"  // object is referenced but not in the store
+	FsckDangling = "dangling" // object is valid but unreachable from any ref
+	FsckBadRef   = "bad-ref"  // ref does not point at a commit or tag
+)
+
+// FsckIssue is a single problem found in the object store
+type FsckIssue struct {
+	Kind     string `json:"kind"`
+	Object   string `json:"object,omitempty"`
+	Ref      string `json:"ref,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Message  string `json:"message"`
+	Repaired bool   `json:"repaired,omitempty"`
+}
+
+// FsckReport is the result of an integrity check
+type FsckReport struct {
+	Objects   int         `json:"objects"`   // loose objects examined
+	Reachable int         `json:"reachable"` // objects reachable from refs and the index
+	Issues    []FsckIssue `json:"issues"`
+}
+
+// Healthy reports whether the check found nothing worse than dangling objects
+func (rep *FsckReport) Healthy() bool {
+	for _, issue := range rep.Issues {
+		if issue.Kind != FsckDangling && !issue.Repaired {
+			return false
+		}
+	}
+	return true
+}
+
+// isObjectHash reports whether s is a full lowercase SHA-256 object name
+func isObjectHash(s string) bool {
+	if len(s) != sha256.Size*2 {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// ListObjects returns the hashes of all loose objects in sorted order
+func (r *Repository) ListObjects() ([]string, error) {
+	objectsDir := filepath.Join(r.Path, ".rdb", "objects")
+	var hashes []string
+	err := filepath.WalkDir(objectsDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == objectsDir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		hash := filepath.Base(filepath.Dir(p)) + d.Name()
+		if isObjectHash(hash) {
+			hashes = append(hashes, hash)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list objects: %w", err)
+	}
+
+	sort.Strings(hashes)
+	return hashes, nil
+}
+
+// verifyObject checks that a loose object is well formed and that its content hashes
+// to its name, returning the object type
+func (r *Repository) verifyObject(hash string) (string, error) {
+	data, err := os.ReadFile(r.objectPath(hash))
+	if err != nil {
+		return "", err
+	}
+
+	objType, objData, err := parseObject(data)
+	if err != nil {
+		return objType, err
+	}
+
+	return objType, checkObject(hash, objType, objData)
+}
+
+// checkObject checks that object content hashes to its name and parses as its type.
+// A chunked object is named by its reassembled content, so only its manifest is checked.
+func checkObject(hash, objType string, data []byte) error {
+	if objType == ChunkedType {
+		_, err := parseChunkManifest(data)
+		return err
+	}
+
+	sum := sha256.Sum256(data)
+	if actual := hex.EncodeToString(sum[:]); actual != hash {
+		return fmt.Errorf("hash mismatch: content hashes to %s", actual)
+	}
+
+	switch objType {
+	case "blob":
+	case "tree", "commit", "tag":
+		if !json.Valid(data) {
+			return fmt.Errorf("%s is not valid JSON", objType)
+		}
+	default:
+		return fmt.Errorf("unknown object type %q", objType)
+	}
+
+	return nil
+}
+
+// RefRoots returns the commit or tag hash of every ref that keeps objects alive, keyed by ref name.
+// All refs below .rdb/refs count, whatever their namespace, as do MERGE_HEAD and
+// every value recorded in the reflogs, keyed as <ref>@{n}.
+func (r *Repository) RefRoots() (map[string]string, error) {
+	return r.refRoots(time.Time{})
+}
+
+// refRoots implements RefRoots, ignoring reflog entries recorded before reflogCutoff
+func (r *Repository) refRoots(reflogCutoff time.Time) (map[string]string, error) {
+	roots := make(map[string]string)
+	dir := filepath.Join(r.Path, ".rdb", "refs")
+	names, err := r.listRefs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ref %s: %w", name, err)
+		}
+		roots["refs/"+name] = strings.TrimSpace(string(data))
+	}
+
+	mergeHead, _, err := r.ReadMergeState()
+	if err != nil {
+		return nil, err
+	}
+	if mergeHead != "" {
+		roots["MERGE_HEAD"] = mergeHead
+	}
+
+	if err := r.reflogRoots(roots, reflogCutoff); err != nil {
+		return nil, err
+	}
+	return roots, nil
+}
+
+// Fsck verifies every loose and packed object and every link reachable from the refs and the index.
+// Loose objects shadow packed copies, as they do when reading.
+func (r *Repository) Fsck() (*FsckReport, error) {
+	hashes, err := r.ListObjects()
+	if err != nil {
+		return nil, err
+	}
+	packed, err := r.ListPackedObjects()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &FsckReport{Issues: []FsckIssue{}}
+	valid := make(map[string]string) // hash -> type of intact objects
+	corrupt := make(map[string]bool)
+	for _, hash := range hashes {
+		objType, err := r.verifyObject(hash)
+		if err != nil {
+			corrupt[hash] = true
+			report.Issues = append(report.Issues, FsckIssue{Kind: FsckCorrupt, Object: hash, Type: objType, Message: err.Error()})
+			continue
+		}
+		valid[hash] = objType
+	}
+	for _, hash := range packed {
+		if valid[hash] != "" || corrupt[hash] {
+			continue
+		}
+		hashes = append(hashes, hash)
+		objType, data, _, err := r.readPackedObject(hash)
+		if err == nil {
+			err = checkObject(hash, objType, data)
+		}
+		if err != nil {
+			corrupt[hash] = true
+			report.Issues = append(report.Issues, FsckIssue{Kind: FsckCorrupt, Object: hash, Type: objType, Message: "packed: " + err.Error()})
+			continue
+		}
+		valid[hash] = objType
+	}
+	report.Objects = len(hashes)
+
+	type link struct {
+		hash, want, from string
+	}
+	var pending []link
+
+	roots, err := r.RefRoots()
+	if err != nil {
+		return nil, err
+	}
+	refNames := make([]string, 0, len(roots))
+	for name := range roots {
+		refNames = append(refNames, name)
+	}
+	sort.Strings(refNames)
+	for _, name := range refNames {
+		hash := roots[name]
+		switch {
+		case !isObjectHash(hash):
+			report.Issues = append(report.Issues, FsckIssue{Kind: FsckBadRef, Ref: name, Message: fmt.Sprintf("invalid object name %q", hash)})
+		case corrupt[hash]:
+			report.Issues = append(report.Issues, FsckIssue{Kind: FsckBadRef, Ref: name, Object: hash, Message: "points at a corrupt object"})
+		case valid[hash] == "":
+			report.Issues = append(report.Issues, FsckIssue{Kind: FsckBadRef, Ref: name, Object: hash, Message: "points at a missing object"})
+			pending = append(pending, link{hash: hash, want: "commit", from: name})
+		case valid[hash] == "tag":
+			pending = append(pending, link{hash: hash, want: "tag", from: name})
+		case valid[hash] != "commit":
+			report.Issues = append(report.Issues, FsckIssue{Kind: FsckBadRef, Ref: name, Object: hash, Message: fmt.Sprintf("points at a %s, not a commit", valid[hash])})
+		default:
+			pending = append(pending, link{hash: hash, want: "commit", from: name})
+		}
+	}
+
+	if branch, err := r.GetCurrentBranch(); err == nil {
+		if _, ok := roots["refs/heads/"+branch]; !ok {
+			report.Issues = append(report.Issues, FsckIssue{Kind: FsckBadRef, Ref: "HEAD", Message: fmt.Sprintf("points at missing branch %s", branch)})
+		}
+	} else {
+		report.Issues = append(report.Issues, FsckIssue{Kind: FsckBadRef, Ref: "HEAD", Message: err.Error()})
+	}
+
+	idx, err := r.LoadIndex()
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range idx.Entries {
+		objects := []string{entry.Object}
+		if entry.Conflict != nil {
+			objects = append(objects, entry.Conflict.Base, entry.Conflict.Ours, entry.Conflict.Theirs, entry.Conflict.Merged)
+		}
+		for _, hash := range objects {
+			if hash != "" {
+				pending = append(pending, link{hash: hash, want: "blob", from: "index " + entry.Path})
+			}
+		}
+	}
+
+	reachable := make(map[string]bool)
+	var chunked []string
+	for len(pending) > 0 {
+		next := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if reachable[next.hash] {
+			continue
+		}
+		reachable[next.hash] = true
+
+		if corrupt[next.hash] {
+			continue
+		}
+		objType, ok := valid[next.hash]
+		if !ok {
+			report.Issues = append(report.Issues, FsckIssue{Kind: FsckMissing, Object: next.hash, Type: next.want, Message: "referenced by " + next.from})
+			continue
+		}
+		if objType != next.want && !(objType == ChunkedType && next.want == "blob") {
+			report.Issues = append(report.Issues, FsckIssue{Kind: FsckCorrupt, Object: next.hash, Type: objType, Message: fmt.Sprintf("%s expects a %s, found a %s", next.from, next.want, objType)})
+			continue
+		}
+
+		switch objType {
+		case "commit":
+			commit, err := r.ReadCommit(next.hash)
+			if err != nil {
+				report.Issues = append(report.Issues, FsckIssue{Kind: FsckCorrupt, Object: next.hash, Type: objType, Message: err.Error()})
+				continue
+			}
+			from := "commit " + next.hash
+			pending = append(pending, link{hash: commit.Tree, want: "tree", from: from})
+			for _, parent := range commit.ParentHashes() {
+				pending = append(pending, link{hash: parent, want: "commit", from: from})
+			}
+		case "tag":
+			tag, err := r.ReadTagObject(next.hash)
+			if err != nil {
+				report.Issues = append(report.Issues, FsckIssue{Kind: FsckCorrupt, Object: next.hash, Type: objType, Message: err.Error()})
+				continue
+			}
+			want := tag.Type
+			if want == "" {
+				want = "commit"
+			}
+			pending = append(pending, link{hash: tag.Object, want: want, from: "tag " + next.hash})
+		case "tree":
+			tree, err := r.ReadTree(next.hash)
+			if err != nil {
+				report.Issues = append(report.Issues, FsckIssue{Kind: FsckCorrupt, Object: next.hash, Type: objType, Message: err.Error()})
+				continue
+			}
+			from := "tree " + next.hash
+			for _, entry := range tree.Entries {
+				want := "blob"
+				if entry.Type == "tree" || entry.Type == "asset" {
+					want = "tree"
+				}
+				pending = append(pending, link{hash: entry.Object, want: want, from: from + " (" + entry.Name + ")"})
+			}
+		case ChunkedType:
+			_, data, err := r.readStoredObject(next.hash)
+			if err == nil {
+				var manifest *ChunkManifest
+				if manifest, err = parseChunkManifest(data); err == nil {
+					for _, chunk := range manifest.Chunks {
+						pending = append(pending, link{hash: chunk.Object, want: "blob", from: "chunked " + next.hash})
+					}
+					chunked = append(chunked, next.hash)
+				}
+			}
+			if err != nil {
+				report.Issues = append(report.Issues, FsckIssue{Kind: FsckCorrupt, Object: next.hash, Type: objType, Message: err.Error()})
+			}
+		}
+	}
+
+	// Chunked blobs are named by their content, so check it once every chunk is known to be good
+	for _, hash := range chunked {
+		if err := r.verifyChunkedBlob(hash); err != nil {
+			report.Issues = append(report.Issues, FsckIssue{Kind: FsckCorrupt, Object: hash, Type: ChunkedType, Message: err.Error()})
+		}
+	}
+
+	for _, hash := range hashes {
+		if reachable[hash] {
+			report.Reachable++
+		} else if objType, ok := valid[hash]; ok {
+			report.Issues = append(report.Issues, FsckIssue{Kind: FsckDangling, Object: hash, Type: objType, Message: "unreachable " + objType})
+		}
+	}
+
+	return report, nil
+}
+
+// verifyChunkedBlob streams a chunked blob to check that its content hashes to its name
+func (r *Repository) verifyChunkedBlob(hash string) error {
+	blob, err := r.OpenBlob(hash)
+	if err != nil {
+		return err
+	}
+	defer blob.Close()
+	_, err = io.Copy(io.Discard, blob)
+	return err
+}
+
+// FsckRepair runs Fsck and restores corrupt and missing objects from the given source
+// repositories, repeating until no further object can be recovered. Repaired issues
+// are reported first with Repaired set.
+func (r *Repository) FsckRepair(sources []*Repository) (*FsckReport, error) {
+	var repaired []FsckIssue
+	for {
+		report, err := r.Fsck()
+		if err != nil {
+			return nil, err
+		}
+
+		progress := false
+		for _, issue := range report.Issues {
+			if issue.Kind != FsckCorrupt && issue.Kind != FsckMissing {
+				continue
+			}
+			if err := r.restoreObject(issue.Object, sources); err != nil {
+				continue
+			}
+			issue.Repaired = true
+			repaired = append(repaired, issue)
+			progress = true
+		}
+
+		if !progress {
+			report.Issues = append(repaired, report.Issues...)
+			return report, nil
+		}
+	}
+}
+
+// restoreObject replaces a damaged or missing loose object with a verified copy from
+// the repository's own packs or from the first source that has one
+func (r *Repository) restoreObject(hash string, sources []*Repository) error {
+	if _, err := r.verifyObject(hash); err == nil {
+		return fmt.Errorf("object %s is intact", hash)
+	}
+
+	// An intact packed copy is used as soon as the damaged loose copy is out of the way
+	if objType, data, ok, err := r.readPackedObject(hash); ok && err == nil && checkObject(hash, objType, data) == nil {
+		if err := os.Remove(r.objectPath(hash)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove damaged object: %w", err)
+		}
+		return nil
+	}
+
+	for _, src := range sources {
+		objType, data, err := src.readObject(hash)
+		if err != nil || checkObject(hash, objType, data) != nil {
+			continue
+		}
+		if err := os.Remove(r.objectPath(hash)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove damaged object: %w", err)
+		}
+		if _, err := r.writeRawObject(objType, data); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	return fmt.Errorf("object %s not found in any source", hash)
+}
+
+// Remotes returns the repositories registered under .rdb/remotes. Each file there
+// names a remote and holds the path of its repository, relative to this one or absolute.
+func (r *Repository) Remotes() (map[string]*Repository, error) {
+	dir := filepath.Join(r.Path, ".rdb", "remotes")
+	names, err := r.listRefs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	remotes := make(map[string]*Repository)
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read remote %s: %w", name, err)
+		}
+		remotePath := strings.TrimSpace(string(data))
+		if !filepath.IsAbs(remotePath) {
+			remotePath = filepath.Join(r.Path, remotePath)
+		}
+		remote, err := OpenRepository(remotePath)
+		if err != nil {
+			return nil, fmt.Errorf("remote %s: %w", name, err)
+		}
+		remotes[name] = remote
+	}
+
+	return remotes, nil
+}
@@ -0,0 +1,137 @@
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fsckIssues indexes the report's issues by kind and object or ref
+func fsckIssues(report *FsckReport) map[string]FsckIssue {
+	issues := make(map[string]FsckIssue)
+	for _, issue := range report.Issues {
+		subject := issue.Object
+		if issue.Ref != "" {
+			subject = issue.Ref
+		}
+		issues[issue.Kind+" "+subject] = issue
+	}
+	return issues
+}
+
+func TestFsckHealthyRepository(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+	commitFiles(t, repo, map[string]string{"assets/1030002/en.txt": "hello"}, "first")
+
+	report, err := repo.Fsck()
+	if err != nil {
+		t.Fatalf("Fsck failed: %v", err)
+	}
+	if !report.Healthy() || len(report.Issues) != 0 {
+		t.Errorf("Expected no issues, got %v", report.Issues)
+	}
+	if report.Objects == 0 || report.Reachable != report.Objects {
+		t.Errorf("Expected all %d objects reachable, got %d", report.Objects, report.Reachable)
+	}
+}
+
+func TestFsckReportsProblems(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+	commitFiles(t, repo, map[string]string{
+		"assets/1030002/en.txt": "hello",
+		"assets/1030002/fr.txt": "bonjour",
+	}, "first")
+
+	hello, err := repo.writeRawObject("blob", []byte("hello"))
+	if err != nil {
+		t.Fatalf("Failed to write blob: %v", err)
+	}
+	bonjour, err := repo.writeRawObject("blob", []byte("bonjour"))
+	if err != nil {
+		t.Fatalf("Failed to write blob: %v", err)
+	}
+	dangling, err := repo.writeRawObject("blob", []byte("orphan"))
+	if err != nil {
+		t.Fatalf("Failed to write blob: %v", err)
+	}
+
+	if err := os.WriteFile(repo.objectPath(hello), []byte("blob 5\000jello"), 0644); err != nil {
+		t.Fatalf("Failed to corrupt object: %v", err)
+	}
+	if err := os.Remove(repo.objectPath(bonjour)); err != nil {
+		t.Fatalf("Failed to remove object: %v", err)
+	}
+	if err := repo.UpdateBranch("broken", hello, RefChange{Command: "test"}); err != nil {
+		t.Fatalf("Failed to write ref: %v", err)
+	}
+
+	report, err := repo.Fsck()
+	if err != nil {
+		t.Fatalf("Fsck failed: %v", err)
+	}
+	if report.Healthy() {
+		t.Error("Expected an unhealthy report")
+	}
+
+	issues := fsckIssues(report)
+	for _, key := range []string{
+		FsckCorrupt + " " + hello,
+		FsckMissing + " " + bonjour,
+		FsckDangling + " " + dangling,
+		FsckBadRef + " refs/heads/broken",
+	} {
+		if _, ok := issues[key]; !ok {
+			t.Errorf("Expected issue %q, got %v", key, report.Issues)
+		}
+	}
+}
+
+func TestFsckRepairFromRemote(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+	commitFiles(t, repo, map[string]string{"assets/1030002/en.txt": "hello"}, "first")
+
+	backup := NewRepository(t.TempDir())
+	if err := backup.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize backup: %v", err)
+	}
+	hello, err := backup.writeRawObject("blob", []byte("hello"))
+	if err != nil {
+		t.Fatalf("Failed to write blob: %v", err)
+	}
+	remotePath := filepath.Join(repo.Path, ".rdb", "remotes", "backup")
+	if err := os.WriteFile(remotePath, []byte(backup.Path), 0644); err != nil {
+		t.Fatalf("Failed to register remote: %v", err)
+	}
+
+	if err := os.Remove(repo.objectPath(hello)); err != nil {
+		t.Fatalf("Failed to remove object: %v", err)
+	}
+
+	remotes, err := repo.Remotes()
+	if err != nil {
+		t.Fatalf("Failed to load remotes: %v", err)
+	}
+	report, err := repo.FsckRepair([]*Repository{remotes["backup"]})
+	if err != nil {
+		t.Fatalf("FsckRepair failed: %v", err)
+	}
+	if !report.Healthy() {
+		t.Errorf("Expected repaired repository to be healthy, got %v", report.Issues)
+	}
+	if issue, ok := fsckIssues(report)[FsckMissing+" "+hello]; !ok || !issue.Repaired {
+		t.Errorf("Expected missing object to be reported as repaired, got %v", report.Issues)
+	}
+
+	if _, data, err := repo.readObject(hello); err != nil || string(data) != "hello" {
+		t.Errorf("Expected restored object, got %q (%v)", data, err)
+	}
+}
@@ -0,0 +1,243 @@
+package repo
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+// DefaultGCGracePeriod is how long unreachable objects are kept before gc may remove them
+const DefaultGCGracePeriod = 14 * 24 * time.Hour
+
+// DefaultReflogExpiry is how long reflog entries keep the commits they name
+const DefaultReflogExpiry = 90 * 24 * time.Hour
+
+// GCOptions controls a garbage collection run
+type GCOptions struct {
+	GracePeriod  time.Duration // only objects last modified longer ago than this are removed
+	ReflogExpiry time.Duration // reflog entries older than this are dropped and keep nothing alive
+	DryRun       bool          // report what would be removed without deleting anything
+}
+
+// PrunedObject describes an unreachable object removed (or, in a dry run, selected) by gc
+type PrunedObject struct {
+	Object string `json:"object"`
+	Size   int64  `json:"size"` // bytes on disk
+}
+
+// GCReport is the result of a garbage collection run
+type GCReport struct {
+	Objects   int            `json:"objects"`   // loose objects examined
+	Reachable int            `json:"reachable"` // objects kept because something references them
+	Recent    int            `json:"recent"`    // unreachable objects kept because they are within the grace period
+	Pruned    []PrunedObject `json:"pruned"`
+	Reclaimed int64          `json:"reclaimed"` // bytes freed (or that would be freed)
+	Expired   int            `json:"expired"`   // reflog entries dropped (or that would be dropped)
+	DryRun    bool           `json:"dry_run,omitempty"`
+}
+
+// reachableObject records how an object was reached
+type reachableObject struct {
+	Type  string // object type implied by the reference
+	Path  string // canonical tree path of trees and blobs, empty for commits and the root tree
+	Order int    // position in the walk, newest history first
+}
+
+// reachableObjects returns every object reachable from the refs, MERGE_HEAD and the index.
+// Unlike Fsck it fails on the first unreadable object, since collecting garbage from a
+// damaged store could delete data that is still needed.
+func (r *Repository) reachableObjects(reflogCutoff time.Time) (map[string]reachableObject, error) {
+	reachable := make(map[string]reachableObject)
+	mark := func(hash, objType, p string) bool {
+		if _, ok := reachable[hash]; ok {
+			return false
+		}
+		reachable[hash] = reachableObject{Type: objType, Path: p, Order: len(reachable)}
+		return true
+	}
+
+	roots, err := r.refRoots(reflogCutoff)
+	if err != nil {
+		return nil, err
+	}
+	var commits []string
+	for name, hash := range roots {
+		if !isObjectHash(hash) {
+			return nil, fmt.Errorf("ref %s has invalid object name %q", name, hash)
+		}
+		// Annotated tags keep themselves and the commit they tag alive
+		for {
+			objType, err := r.storedType(hash)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", name, err)
+			}
+			if objType != "tag" {
+				break
+			}
+			mark(hash, "tag", "")
+			tag, err := r.ReadTagObject(hash)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read tag %s: %w", hash, err)
+			}
+			hash = tag.Object
+		}
+		commits = append(commits, hash)
+	}
+
+	// Blobs stored in chunks keep their chunks alive
+	markBlob := func(hash, p string) error {
+		if !mark(hash, "blob", p) {
+			return nil
+		}
+		chunks, err := r.storedChunks(hash)
+		if err != nil {
+			return fmt.Errorf("failed to read blob %s: %w", hash, err)
+		}
+		for _, chunk := range chunks {
+			mark(chunk.Object, "blob", p)
+		}
+		return nil
+	}
+
+	var walkTree func(hash, prefix string) error
+	walkTree = func(hash, prefix string) error {
+		if !mark(hash, "tree", prefix) {
+			return nil
+		}
+		tree, err := r.ReadTree(hash)
+		if err != nil {
+			return fmt.Errorf("failed to read tree %s: %w", hash, err)
+		}
+		for _, entry := range tree.Entries {
+			entryPath := path.Join(prefix, entry.Name)
+			if entry.Type == "tree" || entry.Type == "asset" {
+				if err := walkTree(entry.Object, entryPath); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := markBlob(entry.Object, entryPath); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	err = r.WalkCommits(commits, func(hash string, commit *Commit) error {
+		mark(hash, "commit", "")
+		return walkTree(commit.Tree, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	idx, err := r.LoadIndex()
+	if err != nil {
+		return nil, err
+	}
+	layout := r.Layout()
+	for _, entry := range idx.Entries {
+		treePath := layout.TreePath(entry.Path)
+		hashes := []string{entry.Object}
+		if c := entry.Conflict; c != nil {
+			hashes = append(hashes, c.Base, c.Ours, c.Theirs, c.Merged)
+		}
+		for _, hash := range hashes {
+			if hash == "" {
+				continue
+			}
+			if err := markBlob(hash, treePath); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return reachable, nil
+}
+
+// GC expires old reflog entries and removes loose objects that nothing references and
+// that are older than the grace period. Packed objects are left alone; Repack turns
+// unreachable packed objects back into loose ones.
+func (r *Repository) GC(opts GCOptions) (*GCReport, error) {
+	if !opts.DryRun {
+		unlock, err := r.lockObjectStore()
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+
+	reflogCutoff := time.Now().Add(-opts.ReflogExpiry)
+	reachable, err := r.reachableObjects(reflogCutoff)
+	if err != nil {
+		return nil, fmt.Errorf("refusing to collect garbage: %w", err)
+	}
+
+	hashes, err := r.ListObjects()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &GCReport{Objects: len(hashes), Pruned: []PrunedObject{}, DryRun: opts.DryRun}
+	cutoff := time.Now().Add(-opts.GracePeriod)
+	for _, hash := range hashes {
+		if _, ok := reachable[hash]; ok {
+			report.Reachable++
+			continue
+		}
+
+		objPath := r.objectPath(hash)
+		info, err := os.Stat(objPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat object %s: %w", hash, err)
+		}
+		if info.ModTime().After(cutoff) {
+			report.Recent++
+			continue
+		}
+
+		if !opts.DryRun {
+			if err := os.Remove(objPath); err != nil {
+				return nil, fmt.Errorf("failed to remove object %s: %w", hash, err)
+			}
+			// Drop the fan-out directory once it is empty; failure just means it is not
+			os.Remove(filepath.Dir(objPath))
+		}
+		report.Pruned = append(report.Pruned, PrunedObject{Object: hash, Size: info.Size()})
+		report.Reclaimed += info.Size()
+	}
+
+	if opts.DryRun {
+		report.Expired, err = r.countExpiredReflog(reflogCutoff)
+	} else {
+		report.Expired, err = r.ExpireReflogs(reflogCutoff)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// countExpiredReflog returns how many reflog entries were recorded before cutoff
+func (r *Repository) countExpiredReflog(cutoff time.Time) (int, error) {
+	refs, err := r.reflogRefs()
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, ref := range refs {
+		entries, err := r.ReadReflog(ref)
+		if err != nil {
+			return 0, err
+		}
+		for _, entry := range entries {
+			if entry.Time.Before(cutoff) {
+				count++
+			}
+		}
+	}
+	return count, nil
+}
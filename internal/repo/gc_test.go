@@ -0,0 +1,88 @@
+package repo
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestGCRemovesOnlyOldUnreachableObjects(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+	commitFiles(t, repo, map[string]string{"assets/1030002/en.txt": "hello"}, "first")
+
+	old, err := repo.writeRawObject("blob", []byte("old garbage"))
+	if err != nil {
+		t.Fatalf("Failed to write blob: %v", err)
+	}
+	recent, err := repo.writeRawObject("blob", []byte("fresh garbage"))
+	if err != nil {
+		t.Fatalf("Failed to write blob: %v", err)
+	}
+	past := time.Now().Add(-30 * 24 * time.Hour)
+	if err := os.Chtimes(repo.objectPath(old), past, past); err != nil {
+		t.Fatalf("Failed to age object: %v", err)
+	}
+
+	report, err := repo.GC(GCOptions{GracePeriod: DefaultGCGracePeriod, DryRun: true})
+	if err != nil {
+		t.Fatalf("GC dry run failed: %v", err)
+	}
+	if len(report.Pruned) != 1 || report.Pruned[0].Object != old || report.Recent != 1 {
+		t.Errorf("Unexpected dry run report: %+v", report)
+	}
+	if _, err := os.Stat(repo.objectPath(old)); err != nil {
+		t.Errorf("Expected dry run to keep the object: %v", err)
+	}
+
+	report, err = repo.GC(GCOptions{GracePeriod: DefaultGCGracePeriod})
+	if err != nil {
+		t.Fatalf("GC failed: %v", err)
+	}
+	if report.Reclaimed == 0 || report.Reachable != report.Objects-2 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if _, err := os.Stat(repo.objectPath(old)); !os.IsNotExist(err) {
+		t.Error("Expected old unreachable object to be removed")
+	}
+	if _, err := os.Stat(repo.objectPath(recent)); err != nil {
+		t.Errorf("Expected recent object to be kept: %v", err)
+	}
+
+	fsck, err := repo.Fsck()
+	if err != nil {
+		t.Fatalf("Fsck failed: %v", err)
+	}
+	if !fsck.Healthy() {
+		t.Errorf("Expected healthy repository after gc, got %v", fsck.Issues)
+	}
+}
+
+func TestGCKeepsObjectsReachableFromAnyRef(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+	first := commitFiles(t, repo, map[string]string{"assets/1030002/en.txt": "hello"}, "first")
+	if err := repo.CreateBranch("keep", first); err != nil {
+		t.Fatalf("Failed to create branch: %v", err)
+	}
+	commitFiles(t, repo, map[string]string{"assets/1030002/en.txt": "goodbye"}, "second")
+	if err := repo.UpdateBranch("main", first, RefChange{Command: "reset"}); err != nil {
+		t.Fatalf("Failed to reset branch: %v", err)
+	}
+
+	report, err := repo.GC(GCOptions{})
+	if err != nil {
+		t.Fatalf("GC failed: %v", err)
+	}
+	if _, err := repo.ReadCommit(first); err != nil {
+		t.Errorf("Expected commit on keep to survive: %v", err)
+	}
+	// The second commit and its three trees are unreachable; the index still holds the new blob
+	if len(report.Pruned) != 4 {
+		t.Errorf("Expected 4 pruned objects, got %+v", report.Pruned)
+	}
+}
@@ -0,0 +1,101 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// WalkCommits visits every commit reachable from the given starting hashes exactly once,
+// newest first. Returning ErrStopWalk from fn ends the walk without error.
+func (r *Repository) WalkCommits(starts []string, fn func(hash string, commit *Commit) error) error {
+	type pending struct {
+		hash   string
+		commit *Commit
+	}
+
+	seen := make(map[string]bool)
+	var queue []pending
+
+	push := func(hash string) error {
+		if hash == "" || seen[hash] {
+			return nil
+		}
+		seen[hash] = true
+		commit, err := r.ReadCommit(hash)
+		if err != nil {
+			return fmt.Errorf("failed to read commit %s: %w", hash, err)
+		}
+		queue = append(queue, pending{hash: hash, commit: commit})
+		return nil
+	}
+
+	for _, hash := range starts {
+		if err := push(hash); err != nil {
+			return err
+		}
+	}
+
+	for len(queue) > 0 {
+		// Always continue with the most recent pending commit
+		sort.SliceStable(queue, func(i, j int) bool {
+			return queue[i].commit.Timestamp.After(queue[j].commit.Timestamp)
+		})
+		next := queue[0]
+		queue = queue[1:]
+
+		if err := fn(next.hash, next.commit); err != nil {
+			if errors.Is(err, ErrStopWalk) {
+				return nil
+			}
+			return err
+		}
+
+		for _, parent := range next.commit.ParentHashes() {
+			if err := push(parent); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// ErrStopWalk can be returned from a WalkCommits callback to end the walk early
+var ErrStopWalk = errors.New("stop walk")
+
+// ChangedPaths returns the files whose content differs between a commit and its first parent.
+// For a root commit every file in its tree is reported.
+func (r *Repository) ChangedPaths(commit *Commit) ([]string, error) {
+	current, err := r.FlattenTree(commit.Tree)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := map[string]TreeEntry{}
+	if parents := commit.ParentHashes(); len(parents) > 0 {
+		parent, err := r.ReadCommit(parents[0])
+		if err != nil {
+			return nil, err
+		}
+		previous, err = r.FlattenTree(parent.Tree)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var changed []string
+	for path, entry := range current {
+		if old, ok := previous[path]; !ok || old.Object != entry.Object {
+			changed = append(changed, path)
+		}
+	}
+	for path := range previous {
+		if _, ok := current[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+
+	sort.Strings(changed)
+	return changed, nil
+}
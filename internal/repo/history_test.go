@@ -0,0 +1,136 @@
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// commitFiles writes the given files, stages them and commits on top of the current branch tip
+func commitFiles(t *testing.T, repo *Repository, files map[string]string, message string) string {
+	t.Helper()
+
+	idx, err := repo.LoadIndex()
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+
+	for relPath, content := range files {
+		absPath := filepath.Join(repo.Path, filepath.FromSlash(relPath))
+		if err := os.MkdirAll(filepath.Dir(absPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(absPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", relPath, err)
+		}
+		entry, err := repo.StageFile(relPath)
+		if err != nil {
+			t.Fatalf("Failed to stage %s: %v", relPath, err)
+		}
+		idx.Add(entry)
+	}
+
+	if err := repo.SaveIndex(idx); err != nil {
+		t.Fatalf("Failed to save index: %v", err)
+	}
+
+	treeHash, err := repo.BuildTree(idx)
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+
+	branch, err := repo.GetCurrentBranch()
+	if err != nil {
+		t.Fatalf("Failed to get current branch: %v", err)
+	}
+	tip, err := repo.GetCurrentCommit()
+	if err != nil {
+		t.Fatalf("Failed to get current commit: %v", err)
+	}
+
+	commit := &Commit{
+		ID:        GenerateID(),
+		Author:    "Test <test@localhost>",
+		Timestamp: time.Now(),
+		Message:   message,
+		Branch:    branch,
+		Parents:   []string{tip},
+		Tree:      treeHash,
+	}
+	hash, err := repo.WriteObject("commit", commit)
+	if err != nil {
+		t.Fatalf("Failed to write commit: %v", err)
+	}
+
+	if err := repo.CompareAndSwapBranch(branch, tip, hash, RefChange{Command: "commit", Message: message}); err != nil {
+		t.Fatalf("Failed to update ref: %v", err)
+	}
+
+	return hash
+}
+
+func TestWalkCommitsFollowsParents(t *testing.T) {
+	tempDir := t.TempDir()
+	repo := NewRepository(tempDir)
+	if err := repo.Init("tree", []string{"text"}); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+
+	first := commitFiles(t, repo, map[string]string{"assets/1030002/en.txt": "one"}, "first")
+	second := commitFiles(t, repo, map[string]string{"assets/1000624/a.swf": "two"}, "second")
+
+	var visited []string
+	err := repo.WalkCommits([]string{second}, func(hash string, commit *Commit) error {
+		visited = append(visited, hash)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to walk commits: %v", err)
+	}
+
+	if len(visited) != 3 {
+		t.Fatalf("Expected 3 commits including the initial commit, got %d", len(visited))
+	}
+	if visited[0] != second || visited[1] != first {
+		t.Errorf("Expected newest-first order, got %v", visited)
+	}
+
+	// Stopping early visits only the tip
+	visited = nil
+	err = repo.WalkCommits([]string{second}, func(hash string, commit *Commit) error {
+		visited = append(visited, hash)
+		return ErrStopWalk
+	})
+	if err != nil {
+		t.Fatalf("Failed to walk commits: %v", err)
+	}
+	if len(visited) != 1 {
+		t.Errorf("Expected walk to stop after 1 commit, got %d", len(visited))
+	}
+}
+
+func TestChangedPaths(t *testing.T) {
+	tempDir := t.TempDir()
+	repo := NewRepository(tempDir)
+	if err := repo.Init("tree", []string{"text"}); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+
+	commitFiles(t, repo, map[string]string{"assets/1030002/en.txt": "one"}, "first")
+	hash := commitFiles(t, repo, map[string]string{"assets/1000624/a.swf": "two"}, "second")
+
+	commit, err := repo.ReadCommit(hash)
+	if err != nil {
+		t.Fatalf("Failed to read commit: %v", err)
+	}
+
+	changed, err := repo.ChangedPaths(commit)
+	if err != nil {
+		t.Fatalf("Failed to compute changed paths: %v", err)
+	}
+
+	if len(changed) != 1 || changed[0] != "assets/1000624/a.swf" {
+		t.Errorf("Expected only assets/1000624/a.swf to change, got %v", changed)
+	}
+}
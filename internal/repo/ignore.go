@@ -0,0 +1,260 @@
+package repo
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// IgnoreFileName is the name of the files holding ignore rules
+const IgnoreFileName = ".rdbignore"
+
+// ignoreRule is a single parsed line of an .rdbignore file
+type ignoreRule struct {
+	base     string // directory containing the .rdbignore file, slash-separated ("" for the root)
+	pattern  *regexp.Regexp
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// IgnoreMatcher evaluates gitignore-style rules from the root .rdbignore and
+// any nested .rdbignore files. Rules in deeper files take precedence, later
+// lines override earlier ones, and a path inside an ignored directory is
+// always ignored. Rules are evaluated against canonical tree paths, so every
+// layout ignores the same files.
+type IgnoreMatcher struct {
+	root   string
+	layout Layout                  // maps the paths given to Match to canonical tree paths
+	rules  map[string][]ignoreRule // cached rules per canonical directory
+	open   func(dir string) (io.ReadCloser, error)
+}
+
+// NewIgnoreMatcher creates a matcher for the repository's working tree. It matches
+// working-tree paths, such as assets/1030002.en.txt in the flat layout.
+func (r *Repository) NewIgnoreMatcher() *IgnoreMatcher {
+	m := &IgnoreMatcher{
+		root:   r.Path,
+		layout: r.Layout(),
+		rules:  make(map[string][]ignoreRule),
+	}
+	m.open = func(dir string) (io.ReadCloser, error) {
+		workPath := m.layout.WorkPath(path.Join(dir, IgnoreFileName))
+		return os.Open(filepath.Join(m.root, filepath.FromSlash(workPath)))
+	}
+	return m
+}
+
+// NewTreeIgnoreMatcher creates a matcher for the .rdbignore files committed in a tree.
+// It matches canonical tree paths, such as assets/1030002/en.txt, whatever the layout.
+func (r *Repository) NewTreeIgnoreMatcher(treeHash string) (*IgnoreMatcher, error) {
+	files, err := r.FlattenTree(treeHash)
+	if err != nil {
+		return nil, err
+	}
+
+	blobs := make(map[string]string)
+	for p, entry := range files {
+		if path.Base(p) != IgnoreFileName {
+			continue
+		}
+		dir := path.Dir(p)
+		if dir == "." {
+			dir = ""
+		}
+		blobs[dir] = entry.Object
+	}
+
+	return &IgnoreMatcher{
+		root:   r.Path,
+		layout: treeLayout{},
+		rules:  make(map[string][]ignoreRule),
+		open: func(dir string) (io.ReadCloser, error) {
+			hash, ok := blobs[dir]
+			if !ok {
+				return nil, os.ErrNotExist
+			}
+			return r.OpenBlob(hash)
+		},
+	}, nil
+}
+
+// Match reports whether the slash-separated repository-relative path is ignored
+func (m *IgnoreMatcher) Match(relPath string, isDir bool) bool {
+	relPath = strings.Trim(relPath, "/")
+	if relPath == "" || relPath == "." {
+		return false
+	}
+	relPath = m.layout.TreePath(relPath)
+
+	// A path inside an ignored directory cannot be re-included
+	parts := strings.Split(relPath, "/")
+	for i := 1; i < len(parts); i++ {
+		if m.matchOne(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return m.matchOne(relPath, isDir)
+}
+
+// matchOne applies the rules of every .rdbignore between the root and the path's directory
+func (m *IgnoreMatcher) matchOne(relPath string, isDir bool) bool {
+	dir := path.Dir(relPath)
+	if dir == "." {
+		dir = ""
+	}
+
+	var dirs []string
+	for d := dir; ; d = path.Dir(d) {
+		if d == "." {
+			d = ""
+		}
+		dirs = append([]string{d}, dirs...)
+		if d == "" {
+			break
+		}
+	}
+
+	ignored := false
+	for _, d := range dirs {
+		for _, rule := range m.load(d) {
+			if rule.matches(relPath, isDir) {
+				ignored = !rule.negate
+			}
+		}
+	}
+	return ignored
+}
+
+func (rule ignoreRule) matches(relPath string, isDir bool) bool {
+	if rule.dirOnly && !isDir {
+		return false
+	}
+
+	target := relPath
+	if rule.base != "" {
+		if !strings.HasPrefix(relPath, rule.base+"/") {
+			return false
+		}
+		target = relPath[len(rule.base)+1:]
+	}
+	if !rule.anchored {
+		target = path.Base(target)
+	}
+	return rule.pattern.MatchString(target)
+}
+
+// load reads and caches the rules of the .rdbignore in dir
+func (m *IgnoreMatcher) load(dir string) []ignoreRule {
+	if rules, ok := m.rules[dir]; ok {
+		return rules
+	}
+
+	var rules []ignoreRule
+	file, err := m.open(dir)
+	if err == nil {
+		defer file.Close()
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			if rule, ok := parseIgnoreLine(dir, scanner.Text()); ok {
+				rules = append(rules, rule)
+			}
+		}
+	}
+
+	m.rules[dir] = rules
+	return rules
+}
+
+// parseIgnoreLine parses one line of an .rdbignore file
+func parseIgnoreLine(base, line string) (ignoreRule, bool) {
+	line = strings.TrimPrefix(line, "\ufeff")
+	line = strings.TrimRight(line, "\r")
+	if !strings.HasSuffix(line, "\\ ") {
+		line = strings.TrimRight(line, " \t")
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	rule := ignoreRule{base: base}
+	switch {
+	case strings.HasPrefix(line, "!"):
+		rule.negate = true
+		line = line[1:]
+	case strings.HasPrefix(line, `\!`), strings.HasPrefix(line, `\#`):
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		rule.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+
+	pattern, err := regexp.Compile(globToRegexp(line))
+	if err != nil {
+		return ignoreRule{}, false
+	}
+	rule.pattern = pattern
+	return rule, true
+}
+
+// globToRegexp converts a gitignore glob into an anchored regular expression
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	sb.WriteString("^")
+
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				// "**/" matches zero or more directories, a trailing "**" matches everything below
+				i++
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					i++
+					sb.WriteString("(?:.*/)?")
+				} else {
+					sb.WriteString(".*")
+				}
+			} else {
+				sb.WriteString("[^/]*")
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				sb.WriteString(regexp.QuoteMeta(string(glob[i])))
+			}
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	sb.WriteString("$")
+	return sb.String()
+}
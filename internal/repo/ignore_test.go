@@ -0,0 +1,165 @@
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeIgnoreFile(t *testing.T, dir, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create %s: %v", dir, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, IgnoreFileName), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", IgnoreFileName, err)
+	}
+}
+
+func TestIgnoreMatcher(t *testing.T) {
+	tempDir := t.TempDir()
+	repo := NewRepository(tempDir)
+
+	writeIgnoreFile(t, tempDir, "# DCC leftovers\n*.psd\n!keep.psd\nThumbs.db\n/assets/1030002/tmp/\n**/cache/**\nbuild/\n")
+	writeIgnoreFile(t, filepath.Join(tempDir, "assets", "1030005"), "*.bak\n!keep.psd\n/local.txt\n")
+
+	tests := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"assets/1030002/cover.psd", false, true},
+		{"assets/1030002/keep.psd", false, false},
+		{"assets/1030002/en.txt", false, false},
+		{"assets/1030003/sub/Thumbs.db", false, true},
+		{"assets/1030002/tmp", true, true},
+		{"assets/1030002/tmp/a.txt", false, true},
+		{"assets/1030003/tmp", true, false},
+		{"assets/1030003/cache/x/y.bin", false, true},
+		{"assets/1030005/old.bak", false, true},
+		{"assets/1030002/old.bak", false, false},
+		{"assets/1030005/local.txt", false, true},
+		{"assets/1030005/sub/local.txt", false, false},
+		// Files inside an excluded directory cannot be re-included
+		{"assets/1030004/build/keep.psd", false, true},
+		// A directory-only rule does not match files
+		{"assets/1030004/build", false, false},
+	}
+
+	m := repo.NewIgnoreMatcher()
+	for _, tt := range tests {
+		if got := m.Match(tt.path, tt.isDir); got != tt.ignored {
+			t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.ignored)
+		}
+	}
+}
+
+func TestStatusSkipsIgnoredFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	repo := NewRepository(tempDir)
+	if err := repo.Init("tree", []string{"text"}); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+
+	commitFiles(t, repo, map[string]string{"assets/1030002/en.txt": "hello"}, "first")
+
+	writeIgnoreFile(t, tempDir, "*.psd\n")
+	for _, name := range []string{"cover.psd", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(tempDir, "assets", "1030002", name), []byte("x"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	codes := statusCodes(t, repo)
+	if _, ok := codes["assets/1030002/cover.psd"]; ok {
+		t.Errorf("Expected cover.psd to be ignored, got %v", codes)
+	}
+	if codes["assets/1030002/notes.txt"] != "??" {
+		t.Errorf("Expected notes.txt to be untracked, got %v", codes)
+	}
+}
+
+func TestTreeIgnoreMatcherUsesCommittedRules(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+	hash := commitFiles(t, repo, map[string]string{
+		IgnoreFileName:                     "*.psd\n",
+		"assets/1030005/" + IgnoreFileName: "*.bak\n",
+		"assets/1030005/en.txt":            "hello",
+	}, "rules")
+	commit, err := repo.ReadCommit(hash)
+	if err != nil {
+		t.Fatalf("Failed to read commit: %v", err)
+	}
+
+	// Later edits to the working tree do not change what the commit ignores
+	writeIgnoreFile(t, repo.Path, "*.txt\n")
+	os.Remove(filepath.Join(repo.Path, "assets", "1030005", IgnoreFileName))
+
+	m, err := repo.NewTreeIgnoreMatcher(commit.Tree)
+	if err != nil {
+		t.Fatalf("Failed to load committed rules: %v", err)
+	}
+	for p, ignored := range map[string]bool{
+		"assets/1030002/cover.psd": true,
+		"assets/1030005/old.bak":   true,
+		"assets/1030002/old.bak":   false,
+		"assets/1030005/en.txt":    false,
+	} {
+		if got := m.Match(p, false); got != ignored {
+			t.Errorf("Match(%q) = %v, want %v", p, got, ignored)
+		}
+	}
+}
+
+func TestFlatLayoutIgnoresMatchCommittedRules(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init(LayoutFlat, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+	hash := commitFiles(t, repo, map[string]string{
+		IgnoreFileName:                     "assets/*/drafts/\n",
+		"assets/1030002." + IgnoreFileName: "*.bak\n",
+		"assets/1030002.en.txt":            "hello",
+	}, "rules")
+	commit, err := repo.ReadCommit(hash)
+	if err != nil {
+		t.Fatalf("Failed to read commit: %v", err)
+	}
+
+	files := map[string]bool{
+		"assets/1030002.drafts/wip.txt": true,
+		"assets/1030002.old.bak":        true,
+		"assets/1030003.old.bak":        false,
+		"assets/1030002.notes.txt":      false,
+	}
+	for p := range files {
+		absPath := filepath.Join(repo.Path, filepath.FromSlash(p))
+		os.MkdirAll(filepath.Dir(absPath), 0755)
+		if err := os.WriteFile(absPath, []byte("x"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", p, err)
+		}
+	}
+
+	// add and status use the working-tree rules, build the committed ones; both agree
+	work := repo.NewIgnoreMatcher()
+	committed, err := repo.NewTreeIgnoreMatcher(commit.Tree)
+	if err != nil {
+		t.Fatalf("Failed to load committed rules: %v", err)
+	}
+	codes := statusCodes(t, repo)
+	for p, ignored := range files {
+		if got := work.Match(p, false); got != ignored {
+			t.Errorf("Working-tree Match(%q) = %v, want %v", p, got, ignored)
+		}
+		if got := committed.Match(repo.Layout().TreePath(p), false); got != ignored {
+			t.Errorf("Committed Match(%q) = %v, want %v", p, got, ignored)
+		}
+		if _, listed := codes[p]; listed == ignored {
+			t.Errorf("Expected status to list %s only if not ignored, got %v", p, codes)
+		}
+	}
+}
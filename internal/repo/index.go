@@ -0,0 +1,192 @@
+package repo
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// IndexVersion is the current on-disk format version of .rdb/index
+const IndexVersion = 1
+
+// Index represents the staging index stored at .rdb/index
+type Index struct {
+	Version int          `json:"version"`
+	Entries []IndexEntry `json:"entries"`
+}
+
+// IndexEntry represents a staged file
+type IndexEntry struct {
+	Path    string    `json:"path"`   // slash-separated, relative to the repository root
+	Object  string    `json:"object"` // SHA256 hash of the blob
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+
+	// Conflict is set while the path has an unresolved merge conflict
+	Conflict *Conflict `json:"conflict,omitempty"`
+}
+
+// Conflict records the three versions of a path that could not be merged.
+// An empty hash means the path does not exist on that side.
+type Conflict struct {
+	Base   string `json:"base,omitempty"`
+	Ours   string `json:"ours,omitempty"`
+	Theirs string `json:"theirs,omitempty"`
+
+	// Merged is a best-effort combination written to the working tree, if any
+	Merged string `json:"merged,omitempty"`
+}
+
+// indexPath returns the location of the staging index
+func (r *Repository) indexPath() string {
+	return filepath.Join(r.Path, ".rdb", "index")
+}
+
+// LoadIndex reads the staging index, returning an empty index if none exists yet
+func (r *Repository) LoadIndex() (*Index, error) {
+	data, err := os.ReadFile(r.indexPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Index{Version: IndexVersion}, nil
+		}
+		return nil, fmt.Errorf("failed to read index: %w", err)
+	}
+
+	var idx Index
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal index: %w", err)
+	}
+
+	if idx.Version > IndexVersion {
+		return nil, fmt.Errorf("unsupported index version %d", idx.Version)
+	}
+	idx.Version = IndexVersion
+
+	return &idx, nil
+}
+
+// SaveIndex atomically replaces the staging index on disk. Callers that loaded the
+// index to change it should hold LockIndex from load to save.
+func (r *Repository) SaveIndex(idx *Index) error {
+	idx.sort()
+
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal index: %w", err)
+	}
+
+	if err := writeFileAtomic(r.indexPath(), data); err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+
+	return nil
+}
+
+// Get returns the entry staged at path, if any
+func (idx *Index) Get(path string) (IndexEntry, bool) {
+	for _, e := range idx.Entries {
+		if e.Path == path {
+			return e, true
+		}
+	}
+	return IndexEntry{}, false
+}
+
+// Add stages an entry, replacing any existing entry for the same path
+func (idx *Index) Add(entry IndexEntry) {
+	for i, e := range idx.Entries {
+		if e.Path == entry.Path {
+			idx.Entries[i] = entry
+			return
+		}
+	}
+	idx.Entries = append(idx.Entries, entry)
+	idx.sort()
+}
+
+// Remove unstages the entry at path and reports whether it was present
+func (idx *Index) Remove(path string) bool {
+	for i, e := range idx.Entries {
+		if e.Path == path {
+			idx.Entries = append(idx.Entries[:i], idx.Entries[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// EntriesUnder returns the entries located at or below the given directory
+func (idx *Index) EntriesUnder(dir string) []IndexEntry {
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	var entries []IndexEntry
+	for _, e := range idx.Entries {
+		if e.Path == dir || strings.HasPrefix(e.Path, prefix) {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// Conflicts returns the entries with unresolved merge conflicts
+func (idx *Index) Conflicts() []IndexEntry {
+	var conflicts []IndexEntry
+	for _, e := range idx.Entries {
+		if e.Conflict != nil {
+			conflicts = append(conflicts, e)
+		}
+	}
+	return conflicts
+}
+
+func (idx *Index) sort() {
+	sort.Slice(idx.Entries, func(i, j int) bool {
+		return idx.Entries[i].Path < idx.Entries[j].Path
+	})
+}
+
+// StageFile stores the file at relPath as a blob and returns its index entry
+func (r *Repository) StageFile(relPath string) (IndexEntry, error) {
+	absPath := filepath.Join(r.Path, filepath.FromSlash(relPath))
+
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return IndexEntry{}, fmt.Errorf("failed to stat %s: %w", relPath, err)
+	}
+	if info.IsDir() {
+		return IndexEntry{}, fmt.Errorf("cannot stage directory %s", relPath)
+	}
+
+	file, err := os.Open(absPath)
+	if err != nil {
+		return IndexEntry{}, fmt.Errorf("failed to read %s: %w", relPath, err)
+	}
+	defer file.Close()
+
+	hash, size, err := r.writeBlob(file, r.shouldCompress(relPath))
+	if err != nil {
+		return IndexEntry{}, fmt.Errorf("failed to write blob for %s: %w", relPath, err)
+	}
+
+	return IndexEntry{
+		Path:    relPath,
+		Object:  hash,
+		Size:    size,
+		ModTime: info.ModTime(),
+	}, nil
+}
+
+// RelPath converts an absolute path inside the repository to the slash-separated form used by the index
+func (r *Repository) RelPath(absPath string) (string, error) {
+	rel, err := filepath.Rel(r.Path, absPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to get relative path: %w", err)
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %s is outside the repository", absPath)
+	}
+	return filepath.ToSlash(rel), nil
+}
@@ -0,0 +1,81 @@
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadIndexEmpty(t *testing.T) {
+	tempDir := t.TempDir()
+	repo := NewRepository(tempDir)
+	if err := repo.Init("tree", []string{"text"}); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+
+	idx, err := repo.LoadIndex()
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+
+	if len(idx.Entries) != 0 {
+		t.Errorf("Expected empty index, got %d entries", len(idx.Entries))
+	}
+}
+
+func TestStageFileRoundTrip(t *testing.T) {
+	tempDir := t.TempDir()
+	repo := NewRepository(tempDir)
+	if err := repo.Init("tree", []string{"string"}); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+
+	filePath := filepath.Join(tempDir, "assets", "1030002", "en.txt")
+	if err := os.WriteFile(filePath, []byte("hello"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	entry, err := repo.StageFile("assets/1030002/en.txt")
+	if err != nil {
+		t.Fatalf("Failed to stage file: %v", err)
+	}
+
+	if entry.Size != 5 {
+		t.Errorf("Expected size 5, got %d", entry.Size)
+	}
+
+	// Blob must be readable back from the object store
+	objType, data, err := repo.ReadObject(entry.Object)
+	if err != nil {
+		t.Fatalf("Failed to read blob: %v", err)
+	}
+	if objType != "blob" || string(data) != "hello" {
+		t.Errorf("Unexpected blob %s %q", objType, data)
+	}
+
+	idx, _ := repo.LoadIndex()
+	idx.Add(entry)
+	if err := repo.SaveIndex(idx); err != nil {
+		t.Fatalf("Failed to save index: %v", err)
+	}
+
+	loaded, err := repo.LoadIndex()
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+
+	got, ok := loaded.Get("assets/1030002/en.txt")
+	if !ok {
+		t.Fatal("Expected staged entry to be present")
+	}
+	if got.Object != entry.Object {
+		t.Errorf("Expected object %s, got %s", entry.Object, got.Object)
+	}
+
+	if !loaded.Remove("assets/1030002/en.txt") {
+		t.Error("Expected entry to be removed")
+	}
+	if len(loaded.Entries) != 0 {
+		t.Errorf("Expected empty index, got %d entries", len(loaded.Entries))
+	}
+}
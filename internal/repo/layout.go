@@ -0,0 +1,138 @@
+package repo
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Supported repository layouts
+const (
+	LayoutTree = "tree" // assets/<id>/<file>
+	LayoutFlat = "flat" // assets/<id>.<file>
+)
+
+// Layout maps the paths used in the working tree and index to the canonical paths
+// stored in commit trees, where an asset's files always live under assets/<id>/.
+// Commit trees are therefore identical whatever layout produced them.
+type Layout interface {
+	Name() string
+	TreePath(workPath string) string // working-tree path to canonical tree path
+	WorkPath(treePath string) string // canonical tree path to working-tree path
+}
+
+// LayoutByName returns the layout with the given name
+func LayoutByName(name string) (Layout, error) {
+	switch name {
+	case LayoutTree, "":
+		return treeLayout{}, nil
+	case LayoutFlat:
+		return flatLayout{}, nil
+	}
+	return nil, fmt.Errorf("invalid layout: %s (must be '%s' or '%s')", name, LayoutTree, LayoutFlat)
+}
+
+// Layout returns the repository's configured layout, defaulting to the tree layout
+func (r *Repository) Layout() Layout {
+	layout, err := LayoutByName(r.Config.Core.Layout)
+	if err != nil {
+		return treeLayout{}
+	}
+	return layout
+}
+
+// AssetMetaPath returns the absolute path of the meta.json belonging to the asset
+// whose canonical directory is assetDir (for example "assets/1030002")
+func (r *Repository) AssetMetaPath(assetDir string) string {
+	workPath := r.Layout().WorkPath(path.Join(assetDir, MetaFileName))
+	return filepath.Join(r.Path, filepath.FromSlash(workPath))
+}
+
+// AssetDir returns the canonical directory of the asset holding the canonical tree path
+// treePath, such as "assets/1030002" for assets/1030002/intro/en.txt, or "" if the path
+// does not lie inside an asset folder. Subfolders of an asset belong to that asset.
+func AssetDir(treePath string) string {
+	rest, ok := strings.CutPrefix(treePath, "assets/")
+	if !ok {
+		return ""
+	}
+	id, _, nested := strings.Cut(rest, "/")
+	if !nested || !isAssetID(id) {
+		return ""
+	}
+	return "assets/" + id
+}
+
+// IsMetaPath reports whether the working-tree path is an asset's meta.json
+func (r *Repository) IsMetaPath(workPath string) bool {
+	return path.Base(r.Layout().TreePath(workPath)) == MetaFileName
+}
+
+// flattenWorkTree flattens a root tree and keys its blobs by working-tree path
+func (r *Repository) flattenWorkTree(hash string) (map[string]TreeEntry, error) {
+	files, err := r.FlattenTree(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	layout := r.Layout()
+	if layout.Name() == LayoutTree {
+		return files, nil
+	}
+
+	work := make(map[string]TreeEntry, len(files))
+	for p, entry := range files {
+		work[layout.WorkPath(p)] = entry
+	}
+	return work, nil
+}
+
+// treeLayout stores every asset in its own folder, exactly as in commit trees
+type treeLayout struct{}
+
+func (treeLayout) Name() string                    { return LayoutTree }
+func (treeLayout) TreePath(workPath string) string { return workPath }
+func (treeLayout) WorkPath(treePath string) string { return treePath }
+
+// flatLayout stores assets directly in assets/, prefixing each file with the asset ID:
+// assets/1030002/en.txt is kept as assets/1030002.en.txt and a nested asset
+// assets/1030002/intro/ as assets/1030002.intro/
+type flatLayout struct{}
+
+func (flatLayout) Name() string { return LayoutFlat }
+
+func (flatLayout) TreePath(workPath string) string {
+	rest, ok := strings.CutPrefix(workPath, "assets/")
+	if !ok {
+		return workPath
+	}
+	first, tail, nested := strings.Cut(rest, "/")
+	id, name, ok := strings.Cut(first, ".")
+	if !ok || name == "" || !isAssetID(id) {
+		return workPath
+	}
+	if nested {
+		return "assets/" + id + "/" + name + "/" + tail
+	}
+	return "assets/" + id + "/" + name
+}
+
+func (flatLayout) WorkPath(treePath string) string {
+	rest, ok := strings.CutPrefix(treePath, "assets/")
+	if !ok {
+		return treePath
+	}
+	id, tail, ok := strings.Cut(rest, "/")
+	if !ok || tail == "" || !isAssetID(id) {
+		return treePath
+	}
+	return "assets/" + id + "." + tail
+}
+
+// isAssetID reports whether s is a numeric asset ID
+func isAssetID(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil && !strings.HasPrefix(s, "+") && !strings.HasPrefix(s, "-")
+}
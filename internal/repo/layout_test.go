@@ -0,0 +1,140 @@
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFlatLayoutPaths(t *testing.T) {
+	layout, err := LayoutByName(LayoutFlat)
+	if err != nil {
+		t.Fatalf("Failed to get flat layout: %v", err)
+	}
+
+	tests := []struct {
+		work string
+		tree string
+	}{
+		{"assets/1030002.en.txt", "assets/1030002/en.txt"},
+		{"assets/1030002.meta.json", "assets/1030002/meta.json"},
+		{"assets/1030002.intro/en.txt", "assets/1030002/intro/en.txt"},
+		{"assets/notes.txt", "assets/notes.txt"},
+		{".rdbignore", ".rdbignore"},
+	}
+	for _, tt := range tests {
+		if got := layout.TreePath(tt.work); got != tt.tree {
+			t.Errorf("TreePath(%q) = %q, want %q", tt.work, got, tt.tree)
+		}
+		if got := layout.WorkPath(tt.tree); got != tt.work {
+			t.Errorf("WorkPath(%q) = %q, want %q", tt.tree, got, tt.work)
+		}
+	}
+
+	if _, err := LayoutByName("nested"); err == nil {
+		t.Error("Expected unknown layout to be rejected")
+	}
+}
+
+func TestAssetDir(t *testing.T) {
+	tests := map[string]string{
+		"assets/1030002/en.txt":               "assets/1030002",
+		"assets/1030002/meta.json":            "assets/1030002",
+		"assets/1030002/intro/en.txt":         "assets/1030002",
+		"assets/1030002/intro/2024/voice.wav": "assets/1030002",
+		"assets/1030002/intro/meta.json":      "assets/1030002",
+		"assets/notes.txt":                    "",
+		"assets/1030002":                      "",
+		"assets/shared/1030002/en.txt":        "",
+		"assets/-1/en.txt":                    "",
+		"docs/1030002/en.txt":                 "",
+	}
+	for treePath, want := range tests {
+		if got := AssetDir(treePath); got != want {
+			t.Errorf("AssetDir(%q) = %q, want %q", treePath, got, want)
+		}
+	}
+}
+
+func TestFlatLayoutTreesMatchTreeLayout(t *testing.T) {
+	treeRepo := NewRepository(t.TempDir())
+	if err := treeRepo.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize tree repository: %v", err)
+	}
+	flatRepo := NewRepository(t.TempDir())
+	if err := flatRepo.Init(LayoutFlat, nil); err != nil {
+		t.Fatalf("Failed to initialize flat repository: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(flatRepo.Path, "assets", "1030002")); !os.IsNotExist(err) {
+		t.Error("Expected flat layout to create no per-ID folders")
+	}
+
+	meta := `{"type":"string","id":1030002}`
+	treeHash := commitFiles(t, treeRepo, map[string]string{
+		"assets/1030002/en.txt":    "hello",
+		"assets/1030002/meta.json": meta,
+	}, "first")
+	flatHash := commitFiles(t, flatRepo, map[string]string{
+		"assets/1030002.en.txt":    "hello",
+		"assets/1030002.meta.json": meta,
+	}, "first")
+
+	treeCommit, err := treeRepo.ReadCommit(treeHash)
+	if err != nil {
+		t.Fatalf("Failed to read commit: %v", err)
+	}
+	flatCommit, err := flatRepo.ReadCommit(flatHash)
+	if err != nil {
+		t.Fatalf("Failed to read commit: %v", err)
+	}
+	if treeCommit.Tree != flatCommit.Tree {
+		t.Errorf("Expected identical trees, got %s and %s", treeCommit.Tree, flatCommit.Tree)
+	}
+
+	assets, err := flatRepo.ListAssets(flatCommit.Tree)
+	if err != nil {
+		t.Fatalf("Failed to list assets: %v", err)
+	}
+	if len(assets) != 1 || assets[0].Path != "assets/1030002" {
+		t.Errorf("Expected asset assets/1030002, got %v", assets)
+	}
+
+	if codes := statusCodes(t, flatRepo); len(codes) != 0 {
+		t.Errorf("Expected clean flat status, got %v", codes)
+	}
+	if !flatRepo.IsMetaPath("assets/1030002.meta.json") {
+		t.Error("Expected assets/1030002.meta.json to be a meta path")
+	}
+	if got := flatRepo.AssetMetaPath("assets/1030002"); got != filepath.Join(flatRepo.Path, "assets", "1030002.meta.json") {
+		t.Errorf("Unexpected meta path %s", got)
+	}
+}
+
+func TestInitCreatesSelectedTypeFolders(t *testing.T) {
+	tempDir := t.TempDir()
+	repo := NewRepository(tempDir)
+	if err := repo.Init(LayoutTree, []string{"string", "1000635", "xml_treasure", "shader"}); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+
+	for _, id := range []string{"1030002", "1000635", "1000083"} {
+		if _, err := os.Stat(filepath.Join(tempDir, "assets", id)); err != nil {
+			t.Errorf("Expected folder for %s: %v", id, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "assets", "1000624")); !os.IsNotExist(err) {
+		t.Error("Expected no folder for unselected type 1000624")
+	}
+
+	registry, err := repo.LoadTypes()
+	if err != nil {
+		t.Fatalf("Failed to load types: %v", err)
+	}
+	if len(registry.Types) != len(DefaultTypes()) {
+		t.Errorf("Expected the full registry to be saved, got %d types", len(registry.Types))
+	}
+	if _, unmatched := registry.Select([]string{"string", "shader"}); len(unmatched) != 1 || unmatched[0] != "shader" {
+		t.Errorf("Expected shader to be unmatched, got %v", unmatched)
+	}
+}
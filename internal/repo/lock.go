@@ -0,0 +1,285 @@
+package repo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Several rdb processes may work on the same checkout at once (CI runs jobs in
+// parallel), so the index and every ref are guarded by a lock file under
+// .rdb/locks. A lock file is created exclusively and records its owner. A lock
+// held by a process on this host is stale once that process has exited, and is
+// never broken while it runs; a lock taken on another host, or whose owner cannot
+// be read, is stale once it is older than StaleLockAge. Stale locks are broken by
+// the next process that wants them.
+const (
+	// DefaultLockTimeout is how long to wait for a lock held by another process
+	DefaultLockTimeout = 10 * time.Second
+
+	// StaleLockAge is the age after which a lock whose owner cannot be checked,
+	// such as one taken on another host, is broken
+	StaleLockAge = 10 * time.Minute
+
+	lockRetryInterval = 50 * time.Millisecond
+	lockWriteGrace    = 5 * time.Second
+)
+
+// ErrLocked is returned when a lock is still held by another process after the timeout
+var ErrLocked = errors.New("resource is locked by another rdb process")
+
+// ErrRefChanged is returned when a ref no longer has the value an update expected
+var ErrRefChanged = errors.New("reference was changed by another process")
+
+// Lock is a held lock file; Unlock releases it
+type Lock struct {
+	path string
+	held bool
+}
+
+// lockOwner is the content of a lock file
+type lockOwner struct {
+	PID     int       `json:"pid"`
+	Host    string    `json:"host"`
+	Created time.Time `json:"created"`
+}
+
+// lockPath returns the lock file guarding the named resource, such as "index" or "refs/heads/main"
+func (r *Repository) lockPath(name string) string {
+	return filepath.Join(r.Path, ".rdb", "locks", filepath.FromSlash(name)+".lock")
+}
+
+// LockIndex locks the staging index. Commands that load, change and save the index
+// hold it for the whole update so concurrent changes are not lost.
+func (r *Repository) LockIndex() (*Lock, error) {
+	return r.acquireLock("index", r.lockTimeout())
+}
+
+// TryLockIndex locks the staging index if it is free, returning a nil lock otherwise
+func (r *Repository) TryLockIndex() (*Lock, error) {
+	lock, err := r.acquireLock("index", 0)
+	if errors.Is(err, ErrLocked) {
+		return nil, nil
+	}
+	return lock, err
+}
+
+// lockObjectStore takes the locks needed to delete objects: the index, so that no
+// command is staging or committing objects that are not reachable yet, and the object
+// store, so that gc and repack do not run at the same time. The returned function
+// releases both.
+func (r *Repository) lockObjectStore() (func(), error) {
+	index, err := r.LockIndex()
+	if err != nil {
+		return nil, err
+	}
+	objects, err := r.acquireLock("objects", r.lockTimeout())
+	if err != nil {
+		index.Unlock()
+		return nil, err
+	}
+	return func() {
+		objects.Unlock()
+		index.Unlock()
+	}, nil
+}
+
+// lockTimeout returns how long to wait for a busy lock
+func (r *Repository) lockTimeout() time.Duration {
+	if r.LockTimeout > 0 {
+		return r.LockTimeout
+	}
+	return DefaultLockTimeout
+}
+
+// acquireLock creates the lock file for name, waiting up to timeout for another owner
+// to release it and breaking it if it is stale
+func (r *Repository) acquireLock(name string, timeout time.Duration) (*Lock, error) {
+	path := r.lockPath(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+
+	host, _ := os.Hostname()
+	owner, err := json.Marshal(lockOwner{PID: os.Getpid(), Host: host, Created: time.Now()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal lock owner: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, werr := file.Write(owner)
+			if cerr := file.Close(); werr == nil {
+				werr = cerr
+			}
+			if werr != nil {
+				os.Remove(path)
+				return nil, fmt.Errorf("failed to write lock %s: %w", name, werr)
+			}
+			return &Lock{path: path, held: true}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to create lock %s: %w", name, err)
+		}
+
+		if breakStaleLock(path) {
+			continue
+		}
+		if !time.Now().Before(deadline) {
+			return nil, fmt.Errorf("%w: %s (%s); if no rdb process is running, remove %s", ErrLocked, name, describeLock(path), path)
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// breakStaleLock removes the lock file at path if its owner is gone, reporting whether it did
+func breakStaleLock(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		// Released in the meantime
+		return os.IsNotExist(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return os.IsNotExist(err)
+	}
+
+	var owner lockOwner
+	age := time.Since(info.ModTime())
+	host, _ := os.Hostname()
+	stale := false
+	switch {
+	case json.Unmarshal(data, &owner) != nil:
+		// The owner is written right after the file is created, so an unreadable
+		// lock that is not brand new was left by a crash
+		stale = age > lockWriteGrace
+	case owner.Host == host && owner.PID > 0:
+		// A local owner can be checked directly, however long it has held the lock
+		stale = !processAlive(owner.PID)
+	default:
+		stale = age > StaleLockAge
+	}
+	if !stale {
+		return false
+	}
+
+	// Only remove the lock we judged; another process may have replaced it already
+	if current, err := os.ReadFile(path); err != nil || string(current) != string(data) {
+		return os.IsNotExist(err)
+	}
+	err = os.Remove(path)
+	return err == nil || os.IsNotExist(err)
+}
+
+// describeLock summarizes the owner of the lock file at path for error messages
+func describeLock(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "owner unknown"
+	}
+	var owner lockOwner
+	if err := json.Unmarshal(data, &owner); err != nil {
+		return "owner unknown"
+	}
+	return fmt.Sprintf("held by pid %d on %s since %s", owner.PID, owner.Host, owner.Created.Format(time.RFC3339))
+}
+
+// Unlock releases the lock; releasing it again does nothing
+func (l *Lock) Unlock() error {
+	if l == nil || !l.held {
+		return nil
+	}
+	l.held = false
+	if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	return nil
+}
+
+// writeFileAtomic replaces the file at path with data by writing a temporary file
+// in the same directory, syncing it and renaming it into place, so readers see
+// either the old or the new content
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// isTempRefFile reports whether name is a temporary file left by writeFileAtomic
+func isTempRefFile(name string) bool {
+	return strings.HasPrefix(name, ".") && strings.Contains(name, ".tmp-")
+}
+
+// updateRef points the ref file at path (named name in errors, locks and reflogs) at
+// newHash and records the move in the reflog, and in HEAD's if the ref is the current
+// branch. Unless old is nil, the update only happens if the ref currently holds *old,
+// where an empty string means the ref must not exist yet.
+func (r *Repository) updateRef(name, path string, old *string, newHash string, change RefChange) error {
+	lock, err := r.acquireLock(name, r.lockTimeout())
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	current := ""
+	data, err := os.ReadFile(path)
+	if err == nil {
+		current = strings.TrimSpace(string(data))
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if old != nil && current != *old {
+		return fmt.Errorf("%w: %s is at %s, expected %s", ErrRefChanged, name, refLabel(current), refLabel(*old))
+	}
+
+	if err := writeFileAtomic(path, []byte(newHash)); err != nil {
+		return fmt.Errorf("failed to update %s: %w", name, err)
+	}
+
+	// Tags are not expected to move, so only branches keep a reflog
+	if strings.HasPrefix(name, "refs/tags/") {
+		return nil
+	}
+	if err := r.appendReflog(name, current, newHash, change); err != nil {
+		return err
+	}
+	if branch, err := r.GetCurrentBranch(); err == nil && "refs/heads/"+branch == name {
+		return r.appendReflog("HEAD", current, newHash, change)
+	}
+	return nil
+}
+
+// refLabel abbreviates a ref value for messages
+func refLabel(hash string) string {
+	switch {
+	case hash == "":
+		return "nothing"
+	case len(hash) > 8:
+		return hash[:8]
+	}
+	return hash
+}
@@ -0,0 +1,192 @@
+package repo
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLockExcludesOtherHolders(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+	repo.LockTimeout = 100 * time.Millisecond
+
+	lock, err := repo.LockIndex()
+	if err != nil {
+		t.Fatalf("Failed to lock index: %v", err)
+	}
+	if _, err := repo.LockIndex(); !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected ErrLocked while the index is held, got %v", err)
+	}
+	if other, err := repo.TryLockIndex(); err != nil || other != nil {
+		t.Errorf("Expected TryLockIndex to give up, got %v (%v)", other, err)
+	}
+
+	if err := lock.Unlock(); err != nil {
+		t.Fatalf("Failed to unlock index: %v", err)
+	}
+	if err := lock.Unlock(); err != nil {
+		t.Errorf("Expected a second unlock to do nothing: %v", err)
+	}
+	lock, err = repo.LockIndex()
+	if err != nil {
+		t.Fatalf("Failed to lock released index: %v", err)
+	}
+	lock.Unlock()
+}
+
+func TestStaleLocksAreBroken(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+	repo.LockTimeout = 100 * time.Millisecond
+
+	writeLock := func(owner lockOwner, age time.Duration) {
+		t.Helper()
+		data, _ := json.Marshal(owner)
+		path := repo.lockPath("index")
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("Failed to write lock: %v", err)
+		}
+		past := time.Now().Add(-age)
+		if err := os.Chtimes(path, past, past); err != nil {
+			t.Fatalf("Failed to age lock: %v", err)
+		}
+	}
+	host, _ := os.Hostname()
+
+	// The owner process no longer exists
+	writeLock(lockOwner{PID: 1 << 30, Host: host, Created: time.Now()}, 0)
+	lock, err := repo.LockIndex()
+	if err != nil {
+		t.Fatalf("Expected a lock of a dead process to be broken: %v", err)
+	}
+	lock.Unlock()
+
+	// A live owner on another host is trusted until the lock is old
+	writeLock(lockOwner{PID: os.Getpid(), Host: host + "-elsewhere", Created: time.Now()}, time.Minute)
+	if _, err := repo.LockIndex(); !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected a recent lock from another host to be kept, got %v", err)
+	}
+	writeLock(lockOwner{PID: os.Getpid(), Host: host + "-elsewhere", Created: time.Now()}, 2*StaleLockAge)
+	lock, err = repo.LockIndex()
+	if err != nil {
+		t.Fatalf("Expected an old lock to be broken: %v", err)
+	}
+	lock.Unlock()
+
+	// A live owner on this host keeps its lock however old it is
+	writeLock(lockOwner{PID: os.Getpid(), Host: host, Created: time.Now()}, 2*StaleLockAge)
+	if _, err := repo.LockIndex(); !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected an old lock of a live local process to be kept, got %v", err)
+	}
+	os.Remove(repo.lockPath("index"))
+}
+
+func TestGCAndRepackTakeTheIndexLock(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+	commitFiles(t, repo, map[string]string{"assets/1030002/en.txt": "hello"}, "second")
+	repo.LockTimeout = 100 * time.Millisecond
+
+	lock, err := repo.LockIndex()
+	if err != nil {
+		t.Fatalf("Failed to lock index: %v", err)
+	}
+	if _, err := repo.GC(GCOptions{}); !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected gc to wait for the index lock, got %v", err)
+	}
+	if _, err := repo.Repack(RepackOptions{}); !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected repack to wait for the index lock, got %v", err)
+	}
+	if _, err := repo.GC(GCOptions{DryRun: true}); err != nil {
+		t.Errorf("Expected a dry run to need no lock: %v", err)
+	}
+	lock.Unlock()
+
+	if _, err := repo.Repack(RepackOptions{}); err != nil {
+		t.Fatalf("Failed to repack: %v", err)
+	}
+	if _, err := repo.GC(GCOptions{}); err != nil {
+		t.Fatalf("Failed to gc: %v", err)
+	}
+	if locks, _ := filepath.Glob(filepath.Join(repo.Path, ".rdb", "locks", "*.lock")); len(locks) != 0 {
+		t.Errorf("Expected gc and repack to release their locks, got %v", locks)
+	}
+}
+
+func TestCompareAndSwapBranch(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+	first, err := repo.GetCurrentCommit()
+	if err != nil {
+		t.Fatalf("Failed to get current commit: %v", err)
+	}
+	second := commitFiles(t, repo, map[string]string{"assets/1030002/en.txt": "hello"}, "second")
+
+	if err := repo.CompareAndSwapBranch("main", first, first, RefChange{Command: "test"}); !errors.Is(err, ErrRefChanged) {
+		t.Errorf("Expected ErrRefChanged for a stale expected value, got %v", err)
+	}
+	if err := repo.CompareAndSwapBranch("main", second, first, RefChange{Command: "test"}); err != nil {
+		t.Fatalf("Failed to swap branch: %v", err)
+	}
+	if tip, _ := repo.ReadBranch("main"); tip != first {
+		t.Errorf("Expected main at %s, got %s", first, tip)
+	}
+	if err := repo.CompareAndSwapBranch("topic", "", second, RefChange{Command: "test"}); err != nil {
+		t.Fatalf("Failed to create branch: %v", err)
+	}
+	if err := repo.CompareAndSwapBranch("topic", "", first, RefChange{Command: "test"}); !errors.Is(err, ErrRefChanged) {
+		t.Errorf("Expected creating an existing branch to fail, got %v", err)
+	}
+
+	leftovers, _ := filepath.Glob(filepath.Join(repo.Path, ".rdb", "refs", "heads", ".*"))
+	locks, _ := filepath.Glob(filepath.Join(repo.Path, ".rdb", "locks", "refs", "heads", "*"))
+	if len(leftovers) != 0 || len(locks) != 0 {
+		t.Errorf("Expected no temporary or lock files, got %v %v", leftovers, locks)
+	}
+}
+
+func TestConcurrentCompareAndSwapAllowsOneWinner(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+	tip, err := repo.GetCurrentCommit()
+	if err != nil {
+		t.Fatalf("Failed to get current commit: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	wins := 0
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			next := GenerateID() + string(rune('a'+i))
+			if err := repo.CompareAndSwapBranch("main", tip, next, RefChange{Command: "test"}); err == nil {
+				mu.Lock()
+				wins++
+				mu.Unlock()
+			} else if !errors.Is(err, ErrRefChanged) {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if wins != 1 {
+		t.Errorf("Expected exactly one update to win, got %d", wins)
+	}
+}
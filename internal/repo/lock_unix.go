@@ -0,0 +1,11 @@
+//go:build !windows
+
+package repo
+
+import "syscall"
+
+// processAlive reports whether a process with the given id is running on this host
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
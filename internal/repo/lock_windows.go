@@ -0,0 +1,16 @@
+//go:build windows
+
+package repo
+
+import "os"
+
+// processAlive reports whether a process with the given id is running on this host
+func processAlive(pid int) bool {
+	// FindProcess opens a handle to the process on Windows and fails if it has exited
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
@@ -0,0 +1,620 @@
+package repo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MergeBase returns the most recent commit reachable from both a and b
+func (r *Repository) MergeBase(a, b string) (string, error) {
+	ancestors := make(map[string]bool)
+	err := r.WalkCommits([]string{a}, func(hash string, commit *Commit) error {
+		ancestors[hash] = true
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	base := ""
+	err = r.WalkCommits([]string{b}, func(hash string, commit *Commit) error {
+		if ancestors[hash] {
+			base = hash
+			return ErrStopWalk
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if base == "" {
+		return "", fmt.Errorf("no common ancestor between %s and %s", a, b)
+	}
+	return base, nil
+}
+
+// MergeResult is the outcome of a three-way tree merge
+type MergeResult struct {
+	Files     map[string]TreeEntry // merged content for every path that survives the merge
+	Conflicts map[string]*Conflict // paths that could not be merged automatically
+	Merged    []string             // paths whose content was combined from both sides
+}
+
+// MergeDriver merges three versions of a payload file. base is nil when both sides
+// added the file. It returns the merged content and whether the merge was clean; an
+// error means the content could not be interpreted and the file is left in conflict.
+type MergeDriver func(assetType AssetType, base, ours, theirs []byte) ([]byte, bool, error)
+
+// mergeDrivers holds the merge drivers registered per asset type ID
+var mergeDrivers = map[int]MergeDriver{}
+
+// RegisterMergeDriver makes driver the merge driver for payload files of the asset
+// type with the given ID, replacing any earlier registration
+func RegisterMergeDriver(typeID int, driver MergeDriver) {
+	mergeDrivers[typeID] = driver
+}
+
+// MergeTrees performs a three-way merge of two trees against their common base tree.
+// Result paths are working-tree paths in the repository layout.
+// meta.json files changed on both sides are merged field by field, and payload files
+// of asset types with a registered MergeDriver are merged by that driver; any other
+// file changed on both sides is reported as a conflict.
+func (r *Repository) MergeTrees(baseTree, oursTree, theirsTree string) (*MergeResult, error) {
+	base, err := r.flattenWorkTree(baseTree)
+	if err != nil {
+		return nil, err
+	}
+	ours, err := r.flattenWorkTree(oursTree)
+	if err != nil {
+		return nil, err
+	}
+	theirs, err := r.flattenWorkTree(theirsTree)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make(map[string]bool)
+	for _, files := range []map[string]TreeEntry{base, ours, theirs} {
+		for p := range files {
+			paths[p] = true
+		}
+	}
+
+	result := &MergeResult{
+		Files:     make(map[string]TreeEntry),
+		Conflicts: make(map[string]*Conflict),
+	}
+
+	sorted := make([]string, 0, len(paths))
+	for p := range paths {
+		sorted = append(sorted, p)
+	}
+	sort.Strings(sorted)
+
+	for _, p := range sorted {
+		b, inBase := base[p]
+		o, inOurs := ours[p]
+		t, inTheirs := theirs[p]
+
+		switch {
+		case inOurs && inTheirs && o.Object == t.Object:
+			result.Files[p] = o
+		case !inOurs && !inTheirs:
+			// Deleted on both sides
+		case inBase && inOurs && o.Object == b.Object:
+			// Only theirs changed (or deleted) the path
+			if inTheirs {
+				result.Files[p] = t
+			}
+		case inBase && inTheirs && t.Object == b.Object:
+			// Only ours changed (or deleted) the path
+			if inOurs {
+				result.Files[p] = o
+			}
+		case !inBase && inOurs && !inTheirs:
+			result.Files[p] = o
+		case !inBase && !inOurs && inTheirs:
+			result.Files[p] = t
+		default:
+			conflict := &Conflict{Base: b.Object, Ours: o.Object, Theirs: t.Object}
+			if inOurs && inTheirs && r.IsMetaPath(p) {
+				merged, ok, err := r.mergeMetaObjects(conflict)
+				if err != nil {
+					return nil, fmt.Errorf("failed to merge %s: %w", p, err)
+				}
+				if ok {
+					result.Files[p] = merged
+					result.Merged = append(result.Merged, p)
+					continue
+				}
+				// Keep the field-by-field merge in the working tree, with our values for conflicting fields
+				conflict.Merged = merged.Object
+			} else if inOurs && inTheirs {
+				assetType, driver, ok := r.mergeDriverFor(p, base, ours, theirs)
+				if ok {
+					merged, clean, err := r.mergeWithDriver(p, assetType, driver, conflict)
+					if err != nil {
+						return nil, fmt.Errorf("failed to merge %s: %w", p, err)
+					}
+					if clean {
+						result.Files[p] = merged
+						result.Merged = append(result.Merged, p)
+						continue
+					}
+					conflict.Merged = merged.Object
+				}
+			}
+			result.Conflicts[p] = conflict
+		}
+	}
+
+	return result, nil
+}
+
+// mergeMetaObjects merges three versions of a meta.json blob. It returns the merged blob
+// and whether the merge was clean; when it was not, conflicting fields keep our value.
+func (r *Repository) mergeMetaObjects(c *Conflict) (TreeEntry, bool, error) {
+	load := func(hash string) (map[string]interface{}, error) {
+		meta := map[string]interface{}{}
+		if hash == "" {
+			return meta, nil
+		}
+		_, data, err := r.readObject(hash)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(bytes.TrimPrefix(data, utf8BOM), &meta); err != nil {
+			return nil, err
+		}
+		return meta, nil
+	}
+
+	base, err := load(c.Base)
+	if err != nil {
+		return TreeEntry{}, false, err
+	}
+	ours, err := load(c.Ours)
+	if err != nil {
+		return TreeEntry{}, false, err
+	}
+	theirs, err := load(c.Theirs)
+	if err != nil {
+		return TreeEntry{}, false, err
+	}
+
+	merged, conflicts := MergeAssetMeta(base, ours, theirs)
+
+	data, err := json.MarshalIndent(merged, "", "  ")
+	if err != nil {
+		return TreeEntry{}, false, err
+	}
+	data = append(data, '\n')
+
+	hash, err := r.writeRawObject("blob", data)
+	if err != nil {
+		return TreeEntry{}, false, err
+	}
+
+	return TreeEntry{Name: MetaFileName, Type: "blob", Object: hash, Size: int64(len(data))}, len(conflicts) == 0, nil
+}
+
+// mergeDriverFor returns the type of the asset holding the working-tree path p and the
+// merge driver registered for it, or MergeXML for other types with a key path. The
+// type comes from the asset's meta.json on our side, their side or the base, in that
+// order, or else from the asset folder ID.
+func (r *Repository) mergeDriverFor(p string, base, ours, theirs map[string]TreeEntry) (AssetType, MergeDriver, bool) {
+	reg, err := r.LoadTypes()
+	if err != nil {
+		return AssetType{}, nil, false
+	}
+
+	layout := r.Layout()
+	assetDir := path.Dir(layout.TreePath(p))
+	metaPath := layout.WorkPath(path.Join(assetDir, MetaFileName))
+	assetType, found := AssetType{}, false
+	for _, files := range []map[string]TreeEntry{ours, theirs, base} {
+		entry, ok := files[metaPath]
+		if !ok {
+			continue
+		}
+		_, data, err := r.readObject(entry.Object)
+		if err != nil {
+			continue
+		}
+		if meta, err := ParseAssetMeta(data); err == nil {
+			assetType, found = reg.Resolve(meta)
+			break
+		}
+	}
+	if !found {
+		if id, err := strconv.Atoi(path.Base(assetDir)); err == nil && isAssetID(path.Base(assetDir)) {
+			assetType, found = reg.Get(id)
+		}
+	}
+	if !found {
+		return AssetType{}, nil, false
+	}
+
+	if driver, ok := mergeDrivers[assetType.ID]; ok {
+		return assetType, driver, true
+	}
+	if assetType.KeyPath != "" {
+		return assetType, MergeXML, true
+	}
+	return AssetType{}, nil, false
+}
+
+// mergeWithDriver merges three versions of a payload blob with a merge driver. It
+// returns the merged blob and whether the merge was clean; when the driver cannot
+// read the content, the returned entry is empty and the merge is not clean.
+func (r *Repository) mergeWithDriver(p string, assetType AssetType, driver MergeDriver, c *Conflict) (TreeEntry, bool, error) {
+	load := func(hash string) ([]byte, error) {
+		if hash == "" {
+			return nil, nil
+		}
+		reader, err := r.OpenBlob(hash)
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return io.ReadAll(reader)
+	}
+
+	base, err := load(c.Base)
+	if err != nil {
+		return TreeEntry{}, false, err
+	}
+	ours, err := load(c.Ours)
+	if err != nil {
+		return TreeEntry{}, false, err
+	}
+	theirs, err := load(c.Theirs)
+	if err != nil {
+		return TreeEntry{}, false, err
+	}
+
+	merged, clean, err := driver(assetType, base, ours, theirs)
+	if err != nil {
+		// Leave the file to be resolved by hand
+		return TreeEntry{}, false, nil
+	}
+
+	hash, size, err := r.WriteBlob(bytes.NewReader(merged))
+	if err != nil {
+		return TreeEntry{}, false, err
+	}
+
+	return TreeEntry{Name: path.Base(p), Type: "blob", Object: hash, Size: size}, clean, nil
+}
+
+// MergeAssetMeta merges three decoded meta.json documents field by field.
+// Tags are combined as a set, attributes are merged key by key and the highest
+// version wins. Any other field changed differently on both sides is reported
+// in the returned list and keeps our value.
+func MergeAssetMeta(base, ours, theirs map[string]interface{}) (map[string]interface{}, []string) {
+	merged := make(map[string]interface{})
+	var conflicts []string
+
+	keys := make(map[string]bool)
+	for _, m := range []map[string]interface{}{base, ours, theirs} {
+		for k := range m {
+			keys[k] = true
+		}
+	}
+
+	for key := range keys {
+		b, o, t := base[key], ours[key], theirs[key]
+
+		switch key {
+		case "tags":
+			if tags := mergeTags(b, o, t); len(tags) > 0 {
+				merged[key] = tags
+			}
+			continue
+		case "version":
+			if v := maxNumber(o, t); v != nil {
+				merged[key] = v
+			}
+			continue
+		case "attributes":
+			bm, _ := b.(map[string]interface{})
+			om, oOK := o.(map[string]interface{})
+			tm, tOK := t.(map[string]interface{})
+			if (oOK || o == nil) && (tOK || t == nil) {
+				attrs, attrConflicts := mergeFields(bm, om, tm)
+				for _, c := range attrConflicts {
+					conflicts = append(conflicts, "attributes."+c)
+				}
+				if len(attrs) > 0 {
+					merged[key] = attrs
+				}
+				continue
+			}
+		}
+
+		value, ok := mergeValue(b, o, t)
+		if !ok {
+			conflicts = append(conflicts, key)
+		}
+		if value != nil {
+			merged[key] = value
+		}
+	}
+
+	sort.Strings(conflicts)
+	return merged, conflicts
+}
+
+// mergeFields merges flat maps key by key
+func mergeFields(base, ours, theirs map[string]interface{}) (map[string]interface{}, []string) {
+	merged := make(map[string]interface{})
+	var conflicts []string
+
+	keys := make(map[string]bool)
+	for _, m := range []map[string]interface{}{base, ours, theirs} {
+		for k := range m {
+			keys[k] = true
+		}
+	}
+
+	for key := range keys {
+		value, ok := mergeValue(base[key], ours[key], theirs[key])
+		if !ok {
+			conflicts = append(conflicts, key)
+		}
+		if value != nil {
+			merged[key] = value
+		}
+	}
+
+	return merged, conflicts
+}
+
+// mergeValue performs a three-way merge of a single value; nil means absent
+func mergeValue(base, ours, theirs interface{}) (interface{}, bool) {
+	switch {
+	case reflect.DeepEqual(ours, theirs):
+		return ours, true
+	case reflect.DeepEqual(base, ours):
+		return theirs, true
+	case reflect.DeepEqual(base, theirs):
+		return ours, true
+	default:
+		return ours, false
+	}
+}
+
+// mergeTags combines tag lists, dropping tags that either side removed from the base
+func mergeTags(base, ours, theirs interface{}) []interface{} {
+	toSet := func(v interface{}) map[string]bool {
+		set := make(map[string]bool)
+		if list, ok := v.([]interface{}); ok {
+			for _, item := range list {
+				set[fmt.Sprint(item)] = true
+			}
+		}
+		return set
+	}
+
+	b, o, t := toSet(base), toSet(ours), toSet(theirs)
+	var tags []interface{}
+	seen := make(map[string]bool)
+	for _, list := range []interface{}{ours, theirs} {
+		items, _ := list.([]interface{})
+		for _, item := range items {
+			tag := fmt.Sprint(item)
+			if seen[tag] || (b[tag] && (!o[tag] || !t[tag])) {
+				continue
+			}
+			seen[tag] = true
+			tags = append(tags, item)
+		}
+	}
+	return tags
+}
+
+// maxNumber returns the larger of two JSON numbers, or whichever one is present
+func maxNumber(a, b interface{}) interface{} {
+	af, aOK := a.(float64)
+	bf, bOK := b.(float64)
+	switch {
+	case aOK && bOK:
+		if bf > af {
+			return b
+		}
+		return a
+	case aOK:
+		return a
+	case bOK:
+		return b
+	}
+	if a != nil {
+		return a
+	}
+	return b
+}
+
+// mergeHeadPath is where the commit being merged in is recorded while conflicts are resolved
+func (r *Repository) mergeHeadPath() string {
+	return filepath.Join(r.Path, ".rdb", "MERGE_HEAD")
+}
+
+// mergeMsgPath holds the default message for the pending merge commit
+func (r *Repository) mergeMsgPath() string {
+	return filepath.Join(r.Path, ".rdb", "MERGE_MSG")
+}
+
+// ReadMergeState returns the commit being merged and the prepared message, if a merge is in progress
+func (r *Repository) ReadMergeState() (string, string, error) {
+	head, err := os.ReadFile(r.mergeHeadPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", "", nil
+		}
+		return "", "", fmt.Errorf("failed to read MERGE_HEAD: %w", err)
+	}
+	msg, _ := os.ReadFile(r.mergeMsgPath())
+	return strings.TrimSpace(string(head)), strings.TrimSpace(string(msg)), nil
+}
+
+// WriteMergeState records an in-progress merge
+func (r *Repository) WriteMergeState(theirs, message string) error {
+	if err := os.WriteFile(r.mergeHeadPath(), []byte(theirs), 0644); err != nil {
+		return fmt.Errorf("failed to write MERGE_HEAD: %w", err)
+	}
+	if err := os.WriteFile(r.mergeMsgPath(), []byte(message), 0644); err != nil {
+		return fmt.Errorf("failed to write MERGE_MSG: %w", err)
+	}
+	return nil
+}
+
+// ClearMergeState removes the in-progress merge markers
+func (r *Repository) ClearMergeState() error {
+	for _, p := range []string{r.mergeHeadPath(), r.mergeMsgPath()} {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to clear merge state: %w", err)
+		}
+	}
+	return nil
+}
+
+// ApplyMerge writes a merge result to the working tree and index. The working tree
+// must not have local changes to tracked files. Conflicted paths are recorded in the
+// index and keep our version (or theirs if we deleted the path) in the working tree.
+func (r *Repository) ApplyMerge(oursTree string, result *MergeResult) error {
+	idx, err := r.LoadIndex()
+	if err != nil {
+		return err
+	}
+
+	status, err := r.Status(idx)
+	if err != nil {
+		return err
+	}
+	var dirty []string
+	for _, e := range status.Entries {
+		if e.Staged == StatusUntracked {
+			if _, ok := result.Files[e.Path]; ok {
+				dirty = append(dirty, e.Path)
+			} else if _, ok := result.Conflicts[e.Path]; ok {
+				dirty = append(dirty, e.Path)
+			}
+			continue
+		}
+		dirty = append(dirty, e.Path)
+	}
+	if len(dirty) > 0 {
+		sort.Strings(dirty)
+		return &CheckoutConflictError{Paths: dirty}
+	}
+
+	ours, err := r.flattenWorkTree(oursTree)
+	if err != nil {
+		return err
+	}
+
+	// Remove paths the merge deleted
+	for p := range ours {
+		_, kept := result.Files[p]
+		_, conflicted := result.Conflicts[p]
+		if kept || conflicted {
+			continue
+		}
+		absPath := filepath.Join(r.Path, filepath.FromSlash(p))
+		if err := os.Remove(absPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", p, err)
+		}
+		r.removeEmptyAssetDirs(filepath.Dir(absPath))
+		idx.Remove(p)
+	}
+
+	write := func(p, hash string, conflict *Conflict) error {
+		absPath := filepath.Join(r.Path, filepath.FromSlash(p))
+		if ours[p].Object != hash {
+			if err := r.checkoutBlob(hash, absPath); err != nil {
+				return fmt.Errorf("failed to write %s: %w", p, err)
+			}
+		}
+		info, err := os.Stat(absPath)
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %w", p, err)
+		}
+		idx.Add(IndexEntry{
+			Path:     p,
+			Object:   hash,
+			Size:     info.Size(),
+			ModTime:  info.ModTime(),
+			Conflict: conflict,
+		})
+		return nil
+	}
+
+	for p, entry := range result.Files {
+		if err := write(p, entry.Object, nil); err != nil {
+			return err
+		}
+	}
+	for p, conflict := range result.Conflicts {
+		hash := conflict.Merged
+		if hash == "" {
+			hash = conflict.Ours
+		}
+		if hash == "" {
+			hash = conflict.Theirs
+		}
+		if err := write(p, hash, conflict); err != nil {
+			return err
+		}
+	}
+
+	return r.SaveIndex(idx)
+}
+
+// ResolveConflict settles a conflicted path by taking our or their version.
+// If the chosen side deleted the path it is removed from the working tree and index.
+func (r *Repository) ResolveConflict(idx *Index, p string, theirs bool) error {
+	entry, ok := idx.Get(p)
+	if !ok || entry.Conflict == nil {
+		return fmt.Errorf("path is not in conflict: %s", p)
+	}
+
+	hash := entry.Conflict.Ours
+	if theirs {
+		hash = entry.Conflict.Theirs
+	}
+
+	absPath := filepath.Join(r.Path, filepath.FromSlash(p))
+	if hash == "" {
+		if err := os.Remove(absPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", p, err)
+		}
+		r.removeEmptyAssetDirs(filepath.Dir(absPath))
+		idx.Remove(p)
+		return nil
+	}
+
+	if err := r.checkoutBlob(hash, absPath); err != nil {
+		return fmt.Errorf("failed to write %s: %w", p, err)
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", p, err)
+	}
+	idx.Add(IndexEntry{
+		Path:    p,
+		Object:  hash,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	})
+	return nil
+}
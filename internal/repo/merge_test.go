@@ -0,0 +1,120 @@
+package repo
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMergeAssetMeta(t *testing.T) {
+	decode := func(s string) map[string]interface{} {
+		m := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(s), &m); err != nil {
+			t.Fatalf("Failed to decode %s: %v", s, err)
+		}
+		return m
+	}
+
+	base := decode(`{"name":"Intro","tags":["ui","old"],"version":1,"attributes":{"lang":"en"}}`)
+	ours := decode(`{"name":"Intro","tags":["ui","hud"],"version":2,"attributes":{"lang":"en","font":"x"}}`)
+	theirs := decode(`{"name":"Intro","tags":["ui","old","winter"],"version":5,"attributes":{"lang":"en","season":"winter"}}`)
+
+	merged, conflicts := MergeAssetMeta(base, ours, theirs)
+	if len(conflicts) != 0 {
+		t.Fatalf("Expected clean merge, got conflicts %v", conflicts)
+	}
+
+	tags, _ := merged["tags"].([]interface{})
+	if len(tags) != 3 || tags[0] != "ui" || tags[1] != "hud" || tags[2] != "winter" {
+		t.Errorf("Expected tags [ui hud winter], got %v", merged["tags"])
+	}
+	if merged["version"] != float64(5) {
+		t.Errorf("Expected version 5, got %v", merged["version"])
+	}
+	attrs, _ := merged["attributes"].(map[string]interface{})
+	if attrs["font"] != "x" || attrs["season"] != "winter" || attrs["lang"] != "en" {
+		t.Errorf("Unexpected attributes %v", attrs)
+	}
+
+	// Both sides renaming the asset differently is a conflict
+	theirs["name"] = "Outro"
+	ours["name"] = "Prologue"
+	merged, conflicts = MergeAssetMeta(base, ours, theirs)
+	if len(conflicts) != 1 || conflicts[0] != "name" {
+		t.Errorf("Expected a conflict on name, got %v", conflicts)
+	}
+	if merged["name"] != "Prologue" {
+		t.Errorf("Expected our name to be kept, got %v", merged["name"])
+	}
+}
+
+func TestMergeTreesAndBase(t *testing.T) {
+	tempDir := t.TempDir()
+	repo := NewRepository(tempDir)
+	if err := repo.Init("tree", []string{"text"}); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+
+	base := commitFiles(t, repo, map[string]string{
+		"assets/1030002/meta.json": `{"type":"string","id":1030002,"tags":["ui"]}`,
+		"assets/1030002/a.bin":     "base",
+		"assets/1030002/b.bin":     "base",
+	}, "base")
+
+	if err := repo.CreateBranch("winter", base); err != nil {
+		t.Fatalf("Failed to create branch: %v", err)
+	}
+
+	ours := commitFiles(t, repo, map[string]string{
+		"assets/1030002/meta.json": `{"type":"string","id":1030002,"tags":["ui","hud"]}`,
+		"assets/1030002/a.bin":     "ours",
+	}, "ours")
+
+	if err := repo.SetHead("winter", RefChange{Command: "switch"}); err != nil {
+		t.Fatalf("Failed to switch HEAD: %v", err)
+	}
+	theirs := commitFiles(t, repo, map[string]string{
+		"assets/1030002/meta.json": `{"type":"string","id":1030002,"tags":["ui","winter"]}`,
+		"assets/1030002/a.bin":     "theirs",
+		"assets/1030002/b.bin":     "theirs",
+	}, "theirs")
+
+	mergeBase, err := repo.MergeBase(ours, theirs)
+	if err != nil {
+		t.Fatalf("Failed to find merge base: %v", err)
+	}
+	if mergeBase != base {
+		t.Errorf("Expected merge base %s, got %s", base, mergeBase)
+	}
+
+	baseCommit, _ := repo.ReadCommit(base)
+	oursCommit, _ := repo.ReadCommit(ours)
+	theirsCommit, _ := repo.ReadCommit(theirs)
+
+	result, err := repo.MergeTrees(baseCommit.Tree, oursCommit.Tree, theirsCommit.Tree)
+	if err != nil {
+		t.Fatalf("Failed to merge trees: %v", err)
+	}
+
+	if _, ok := result.Conflicts["assets/1030002/a.bin"]; !ok {
+		t.Error("Expected a.bin to conflict")
+	}
+	if len(result.Conflicts) != 1 {
+		t.Errorf("Expected exactly one conflict, got %v", result.Conflicts)
+	}
+	if len(result.Merged) != 1 || result.Merged[0] != "assets/1030002/meta.json" {
+		t.Errorf("Expected meta.json to be auto-merged, got %v", result.Merged)
+	}
+
+	theirsFiles, _ := repo.FlattenTree(theirsCommit.Tree)
+	if result.Files["assets/1030002/b.bin"].Object != theirsFiles["assets/1030002/b.bin"].Object {
+		t.Error("Expected b.bin to take their change")
+	}
+
+	meta, err := repo.readAssetMetaObject(result.Files["assets/1030002/meta.json"].Object)
+	if err != nil {
+		t.Fatalf("Failed to read merged metadata: %v", err)
+	}
+	if len(meta.Tags) != 3 {
+		t.Errorf("Expected combined tags, got %v", meta.Tags)
+	}
+}
@@ -0,0 +1,727 @@
+package repo
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Pack files bundle many objects into .rdb/packs/pack-<checksum>.pack, with a sorted
+// index in the matching .idx file.
+//
+// A pack is packMagic, uint32 version, uint32 object count, the entries, and the SHA-256
+// of everything before it. Each entry is a kind byte followed by either
+// uvarint-prefixed type and data (packEntryFull) or the 32-byte hash of a base object in
+// the same pack and a uvarint-prefixed delta against it (packEntryDelta). When the kind
+// has packEntryZlib set, the data or delta is preceded by its uncompressed size and
+// stored zlib-compressed.
+//
+// An index is packIndexMagic, uint32 version, uint32 count, a 256-entry fan-out table of
+// cumulative counts by first hash byte, count records of 32-byte hash and uint64 offset
+// sorted by hash, and the checksum of the pack it describes.
+const (
+	packMagic      = "RDBPACK\x00"
+	packIndexMagic = "RDBPIDX\x00"
+	packVersion    = 1
+
+	packEntryFull  = 1
+	packEntryDelta = 2
+	packEntryZlib  = 0x80
+
+	packHeaderSize      = len(packMagic) + 8
+	packIndexHeaderSize = len(packIndexMagic) + 8 + 256*4
+	packRecordSize      = sha256.Size + 8
+
+	// DefaultPackWindow is how many preceding blobs repack tries as delta bases
+	DefaultPackWindow = 10
+
+	// DefaultPackDepth limits delta chains so that reads stay fast
+	DefaultPackDepth = 50
+
+	// MaxPackDepth is the longest delta chain repack writes and readers resolve
+	MaxPackDepth = 100
+
+	// maxDeltaSize is the largest blob repack considers for delta compression
+	maxDeltaSize = 32 << 20
+)
+
+// packIndex is an in-memory pack index used for binary-search lookups
+type packIndex struct {
+	packPath string
+	fanout   [256]uint32
+	records  []byte
+}
+
+// packsDir returns the directory holding pack files
+func (r *Repository) packsDir() string {
+	return filepath.Join(r.Path, ".rdb", "packs")
+}
+
+// loadPackIndex reads and checks the index file at idxPath
+func loadPackIndex(idxPath string) (*packIndex, error) {
+	data, err := os.ReadFile(idxPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pack index: %w", err)
+	}
+	if len(data) < packIndexHeaderSize+sha256.Size || string(data[:len(packIndexMagic)]) != packIndexMagic {
+		return nil, fmt.Errorf("invalid pack index %s", filepath.Base(idxPath))
+	}
+	if version := binary.BigEndian.Uint32(data[len(packIndexMagic):]); version != packVersion {
+		return nil, fmt.Errorf("unsupported pack index version %d", version)
+	}
+	count := int(binary.BigEndian.Uint32(data[len(packIndexMagic)+4:]))
+	if len(data) != packIndexHeaderSize+count*packRecordSize+sha256.Size {
+		return nil, fmt.Errorf("truncated pack index %s", filepath.Base(idxPath))
+	}
+
+	idx := &packIndex{
+		packPath: strings.TrimSuffix(idxPath, ".idx") + ".pack",
+		records:  data[packIndexHeaderSize : packIndexHeaderSize+count*packRecordSize],
+	}
+	for i := range idx.fanout {
+		idx.fanout[i] = binary.BigEndian.Uint32(data[len(packIndexMagic)+8+i*4:])
+	}
+	if int(idx.fanout[255]) != count {
+		return nil, fmt.Errorf("corrupt fan-out table in %s", filepath.Base(idxPath))
+	}
+	return idx, nil
+}
+
+// count returns the number of objects in the pack
+func (idx *packIndex) count() int {
+	return len(idx.records) / packRecordSize
+}
+
+// hash returns the name of the i-th object in index order
+func (idx *packIndex) hash(i int) string {
+	return hex.EncodeToString(idx.records[i*packRecordSize : i*packRecordSize+sha256.Size])
+}
+
+// lookup returns the pack offset of an object, searching only its fan-out bucket
+func (idx *packIndex) lookup(hash string) (int64, bool) {
+	raw, err := hex.DecodeString(hash)
+	if err != nil || len(raw) != sha256.Size {
+		return 0, false
+	}
+
+	lo := 0
+	if raw[0] > 0 {
+		lo = int(idx.fanout[raw[0]-1])
+	}
+	hi := int(idx.fanout[raw[0]])
+	i := lo + sort.Search(hi-lo, func(k int) bool {
+		rec := idx.records[(lo+k)*packRecordSize:]
+		return bytes.Compare(rec[:sha256.Size], raw) >= 0
+	})
+	if i >= hi {
+		return 0, false
+	}
+	rec := idx.records[i*packRecordSize : (i+1)*packRecordSize]
+	if !bytes.Equal(rec[:sha256.Size], raw) {
+		return 0, false
+	}
+	return int64(binary.BigEndian.Uint64(rec[sha256.Size:])), true
+}
+
+// withPrefix returns the names of the objects in the pack that start with a hex prefix
+// of at least two characters, scanning only the prefix's fan-out bucket
+func (idx *packIndex) withPrefix(prefix string) []string {
+	first, err := hex.DecodeString(prefix[:2])
+	if err != nil {
+		return nil
+	}
+	lo := 0
+	if first[0] > 0 {
+		lo = int(idx.fanout[first[0]-1])
+	}
+	var hashes []string
+	for i := lo; i < int(idx.fanout[first[0]]); i++ {
+		if hash := idx.hash(i); strings.HasPrefix(hash, prefix) {
+			hashes = append(hashes, hash)
+		}
+	}
+	return hashes
+}
+
+// read returns the object stored at offset, resolving delta chains
+func (idx *packIndex) read(offset int64) (string, []byte, error) {
+	file, err := os.Open(idx.packPath)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to open pack: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to stat pack: %w", err)
+	}
+	return idx.readEntry(file, info.Size(), offset, 0)
+}
+
+func (idx *packIndex) readEntry(file *os.File, size, offset int64, depth int) (string, []byte, error) {
+	if offset < int64(packHeaderSize) || offset >= size {
+		return "", nil, fmt.Errorf("pack offset %d out of range", offset)
+	}
+	br := bufio.NewReader(io.NewSectionReader(file, offset, size-offset))
+	limit := uint64(size - offset)
+
+	kind, err := br.ReadByte()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read pack entry: %w", err)
+	}
+
+	compressed := kind&packEntryZlib != 0
+	switch kind &^ packEntryZlib {
+	case packEntryFull:
+		objType, err := readPackBytes(br, limit)
+		if err != nil {
+			return "", nil, err
+		}
+		data, err := readPackPayload(br, limit, compressed)
+		if err != nil {
+			return "", nil, err
+		}
+		return string(objType), data, nil
+
+	case packEntryDelta:
+		var base [sha256.Size]byte
+		if _, err := io.ReadFull(br, base[:]); err != nil {
+			return "", nil, fmt.Errorf("failed to read delta base: %w", err)
+		}
+		delta, err := readPackPayload(br, limit, compressed)
+		if err != nil {
+			return "", nil, err
+		}
+		if depth >= MaxPackDepth {
+			return "", nil, fmt.Errorf("delta chain too long")
+		}
+		baseOffset, ok := idx.lookup(hex.EncodeToString(base[:]))
+		if !ok {
+			return "", nil, fmt.Errorf("delta base %x not in pack", base)
+		}
+		objType, baseData, err := idx.readEntry(file, size, baseOffset, depth+1)
+		if err != nil {
+			return "", nil, err
+		}
+		data, err := applyDelta(baseData, delta)
+		if err != nil {
+			return "", nil, err
+		}
+		return objType, data, nil
+	}
+
+	return "", nil, fmt.Errorf("unknown pack entry kind %d", kind)
+}
+
+// open returns the type, size and a reader for the object at offset. Whole objects are
+// streamed from the pack file; deltas are resolved in memory, which repack only
+// produces for blobs up to maxDeltaSize.
+func (idx *packIndex) open(offset int64) (string, int64, io.ReadCloser, error) {
+	file, err := os.Open(idx.packPath)
+	if err != nil {
+		return "", 0, nil, fmt.Errorf("failed to open pack: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return "", 0, nil, fmt.Errorf("failed to stat pack: %w", err)
+	}
+	if offset < int64(packHeaderSize) || offset >= info.Size() {
+		file.Close()
+		return "", 0, nil, fmt.Errorf("pack offset %d out of range", offset)
+	}
+
+	// Kind, type and length of a whole entry fit in a small prefix
+	prefix := make([]byte, 64)
+	n, err := file.ReadAt(prefix, offset)
+	if err != nil && err != io.EOF {
+		file.Close()
+		return "", 0, nil, fmt.Errorf("failed to read pack entry: %w", err)
+	}
+	br := bytes.NewReader(prefix[:n])
+	if kind, _ := br.ReadByte(); kind&^packEntryZlib == packEntryFull {
+		typeLen, err := binary.ReadUvarint(br)
+		if err == nil && typeLen <= uint64(br.Len()) {
+			objType := make([]byte, typeLen)
+			br.Read(objType)
+			size, err := binary.ReadUvarint(br)
+			stored := size
+			if err == nil && kind&packEntryZlib != 0 {
+				stored, err = binary.ReadUvarint(br)
+			}
+			start := offset + int64(n-br.Len())
+			if err == nil && start+int64(stored) <= info.Size() {
+				section := io.NewSectionReader(file, start, int64(stored))
+				if kind&packEntryZlib == 0 {
+					return string(objType), int64(size), &packedReader{SectionReader: section, file: file}, nil
+				}
+				zr, err := zlib.NewReader(bufio.NewReader(section))
+				if err == nil {
+					return string(objType), int64(size), &zlibReadCloser{ReadCloser: zr, src: file}, nil
+				}
+			}
+		}
+	}
+
+	objType, data, err := idx.readEntry(file, info.Size(), offset, 0)
+	file.Close()
+	if err != nil {
+		return "", 0, nil, err
+	}
+	return objType, int64(len(data)), io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// packedReader streams one whole object out of a pack file
+type packedReader struct {
+	*io.SectionReader
+	file *os.File
+}
+
+func (p *packedReader) Close() error {
+	return p.file.Close()
+}
+
+// readPackPayload reads entry data, decompressing it if the entry is compressed
+func readPackPayload(br *bufio.Reader, limit uint64, compressed bool) ([]byte, error) {
+	if !compressed {
+		return readPackBytes(br, limit)
+	}
+	size, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pack entry length")
+	}
+	stored, err := readPackBytes(br, limit)
+	if err != nil {
+		return nil, err
+	}
+	data, err := inflate(stored, int64(size))
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(data)) != size {
+		return nil, fmt.Errorf("pack entry size mismatch: expected %d, got %d", size, len(data))
+	}
+	return data, nil
+}
+
+// readPackBytes reads a uvarint length followed by that many bytes
+func readPackBytes(br *bufio.Reader, limit uint64) ([]byte, error) {
+	n, err := binary.ReadUvarint(br)
+	if err != nil || n > limit {
+		return nil, fmt.Errorf("invalid pack entry length")
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(br, data); err != nil {
+		return nil, fmt.Errorf("failed to read pack entry: %w", err)
+	}
+	return data, nil
+}
+
+// loadPacks returns the indexes of all packs in the repository, caching them
+func (r *Repository) loadPacks() ([]*packIndex, error) {
+	if r.packs != nil {
+		return r.packs, nil
+	}
+
+	idxPaths, err := filepath.Glob(filepath.Join(r.packsDir(), "pack-*.idx"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list packs: %w", err)
+	}
+	sort.Strings(idxPaths)
+
+	packs := []*packIndex{}
+	for _, idxPath := range idxPaths {
+		idx, err := loadPackIndex(idxPath)
+		if err != nil {
+			return nil, err
+		}
+		packs = append(packs, idx)
+	}
+	r.packs = packs
+	return packs, nil
+}
+
+// readPackedObject reads an object from the pack files, reporting whether any pack has it
+func (r *Repository) readPackedObject(hash string) (string, []byte, bool, error) {
+	packs, err := r.loadPacks()
+	if err != nil {
+		return "", nil, false, err
+	}
+	for _, idx := range packs {
+		if offset, ok := idx.lookup(hash); ok {
+			objType, data, err := idx.read(offset)
+			if err != nil {
+				return "", nil, true, fmt.Errorf("failed to read packed object %s: %w", hash, err)
+			}
+			return objType, data, true, nil
+		}
+	}
+	return "", nil, false, nil
+}
+
+// ListPackedObjects returns the hashes of all objects stored in packs in sorted order
+func (r *Repository) ListPackedObjects() ([]string, error) {
+	packs, err := r.loadPacks()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var hashes []string
+	for _, idx := range packs {
+		for i := 0; i < idx.count(); i++ {
+			hash := idx.hash(i)
+			if !seen[hash] {
+				seen[hash] = true
+				hashes = append(hashes, hash)
+			}
+		}
+	}
+	sort.Strings(hashes)
+	return hashes, nil
+}
+
+// RepackOptions controls how objects are packed
+type RepackOptions struct {
+	Window int // preceding blobs tried as delta bases (0 disables deltas)
+	Depth  int // maximum delta chain length
+}
+
+// RepackReport describes the pack written by Repack
+type RepackReport struct {
+	Pack         string `json:"pack"`          // file name of the new pack
+	Objects      int    `json:"objects"`       // objects in the new pack
+	Deltas       int    `json:"deltas"`        // objects stored as deltas
+	Size         int64  `json:"size"`          // bytes in the new pack file
+	LooseRemoved int    `json:"loose_removed"` // loose objects now covered by the pack
+	PacksRemoved int    `json:"packs_removed"` // older packs replaced by the new one
+	Unpacked     int    `json:"unpacked"`      // unreachable objects moved from old packs back to loose objects
+}
+
+// packCandidate is a recently packed blob that later blobs may be stored as deltas against
+type packCandidate struct {
+	hash  string
+	data  []byte
+	depth int
+}
+
+// Repack writes every reachable object into a single new pack, replacing older packs
+// and the loose copies of packed objects. Unreachable objects stay (or become) loose so
+// that gc can prune them after the grace period.
+func (r *Repository) Repack(opts RepackOptions) (*RepackReport, error) {
+	unlock, err := r.lockObjectStore()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if opts.Depth > MaxPackDepth {
+		return nil, fmt.Errorf("delta depth %d exceeds the maximum of %d", opts.Depth, MaxPackDepth)
+	}
+
+	reachable, err := r.reachableObjects(time.Time{})
+	if err != nil {
+		return nil, fmt.Errorf("refusing to repack: %w", err)
+	}
+	oldPacks, err := r.loadPacks()
+	if err != nil {
+		return nil, err
+	}
+
+	// Group objects by kind, then blobs by file name and path so that revisions of the
+	// same file sit next to each other, newest first
+	hashes := make([]string, 0, len(reachable))
+	for hash := range reachable {
+		hashes = append(hashes, hash)
+	}
+	rank := map[string]int{"tag": 0, "commit": 1, "tree": 2, "blob": 3}
+	sort.Slice(hashes, func(i, j int) bool {
+		a, b := reachable[hashes[i]], reachable[hashes[j]]
+		if rank[a.Type] != rank[b.Type] {
+			return rank[a.Type] < rank[b.Type]
+		}
+		if path.Base(a.Path) != path.Base(b.Path) {
+			return path.Base(a.Path) < path.Base(b.Path)
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Order < b.Order
+	})
+
+	if err := os.MkdirAll(r.packsDir(), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create packs directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(r.packsDir(), "tmp-pack-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pack: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	sum := sha256.New()
+	w := bufio.NewWriter(io.MultiWriter(tmp, sum))
+	var offset int64
+	write := func(p []byte) {
+		w.Write(p)
+		offset += int64(len(p))
+	}
+	putUvarint := func(v uint64) {
+		var tmp [binary.MaxVarintLen64]byte
+		write(tmp[:binary.PutUvarint(tmp[:], v)])
+	}
+	writeBytes := func(p []byte) {
+		putUvarint(uint64(len(p)))
+		write(p)
+	}
+	// writePayload writes an entry, compressing p when allowed and worthwhile
+	writePayload := func(kind byte, prefix []byte, p []byte, compress bool) {
+		if compress {
+			if z := deflate(p); len(z) < len(p) {
+				write([]byte{kind | packEntryZlib})
+				write(prefix)
+				putUvarint(uint64(len(p)))
+				writeBytes(z)
+				return
+			}
+		}
+		write([]byte{kind})
+		write(prefix)
+		writeBytes(p)
+	}
+
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header, packVersion)
+	binary.BigEndian.PutUint32(header[4:], uint32(len(hashes)))
+	write([]byte(packMagic))
+	write(header)
+
+	report := &RepackReport{Objects: len(hashes)}
+	offsets := make(map[string]int64, len(hashes))
+	var window []packCandidate
+	for _, hash := range hashes {
+		objType, data, err := r.readStoredObject(hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read object %s: %w", hash, err)
+		}
+		if err := checkObject(hash, objType, data); err != nil {
+			return nil, fmt.Errorf("object %s is corrupt (run 'rdb fsck'): %w", hash, err)
+		}
+		offsets[hash] = offset
+
+		var best []byte
+		var bestBase packCandidate
+		if objType == "blob" && len(data) <= maxDeltaSize && len(data) >= 2*deltaBlock {
+			for _, base := range window {
+				if base.depth >= opts.Depth {
+					continue
+				}
+				delta := computeDelta(base.data, data)
+				if best == nil || len(delta) < len(best) {
+					best, bestBase = delta, base
+				}
+			}
+		}
+
+		depth := 0
+		if best != nil && len(best) < len(data)/2 {
+			raw, _ := hex.DecodeString(bestBase.hash)
+			writePayload(packEntryDelta, raw, best, r.compressionEnabled())
+			depth = bestBase.depth + 1
+			report.Deltas++
+		} else {
+			var typePrefix bytes.Buffer
+			writeUvarint(&typePrefix, uint64(len(objType)))
+			typePrefix.WriteString(objType)
+			compress := r.compressionEnabled()
+			if objType == "blob" {
+				compress = r.shouldCompress(reachable[hash].Path)
+			}
+			writePayload(packEntryFull, typePrefix.Bytes(), data, compress)
+		}
+
+		if objType == "blob" && opts.Window > 0 && len(data) <= maxDeltaSize {
+			window = append(window, packCandidate{hash: hash, data: data, depth: depth})
+			if len(window) > opts.Window {
+				window = window[1:]
+			}
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to write pack: %w", err)
+	}
+	checksum := sum.Sum(nil)
+	if _, err := tmp.Write(checksum); err != nil {
+		return nil, fmt.Errorf("failed to write pack: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return nil, fmt.Errorf("failed to write pack: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write pack: %w", err)
+	}
+	report.Size = offset + sha256.Size
+
+	name := "pack-" + hex.EncodeToString(checksum)
+	packPath := filepath.Join(r.packsDir(), name+".pack")
+	if err := os.Rename(tmp.Name(), packPath); err != nil {
+		return nil, fmt.Errorf("failed to install pack: %w", err)
+	}
+	idxPath := filepath.Join(r.packsDir(), name+".idx")
+	if err := writePackIndex(idxPath, offsets, checksum); err != nil {
+		return nil, err
+	}
+	report.Pack = name + ".pack"
+
+	// Nothing is deleted until every object reads back from the new pack
+	if err := verifyPack(idxPath, offsets); err != nil {
+		replaced := false
+		for _, idx := range oldPacks {
+			replaced = replaced || idx.packPath == packPath
+		}
+		if !replaced {
+			os.Remove(idxPath)
+			os.Remove(packPath)
+		}
+		return nil, fmt.Errorf("new pack failed verification, nothing was removed: %w", err)
+	}
+
+	// Keep unreachable objects from the old packs around as loose objects for gc
+	for _, idx := range oldPacks {
+		if idx.packPath == packPath {
+			continue
+		}
+		for i := 0; i < idx.count(); i++ {
+			hash := idx.hash(i)
+			if _, ok := reachable[hash]; ok {
+				continue
+			}
+			if _, err := os.Stat(r.objectPath(hash)); err == nil {
+				continue
+			}
+			offset, _ := idx.lookup(hash)
+			objType, data, err := idx.read(offset)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unpack object %s: %w", hash, err)
+			}
+			if _, err := r.writeRawObject(objType, data); err != nil {
+				return nil, err
+			}
+			report.Unpacked++
+		}
+	}
+	for _, idx := range oldPacks {
+		if idx.packPath == packPath {
+			continue
+		}
+		if err := os.Remove(strings.TrimSuffix(idx.packPath, ".pack") + ".idx"); err != nil {
+			return nil, fmt.Errorf("failed to remove old pack index: %w", err)
+		}
+		if err := os.Remove(idx.packPath); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to remove old pack: %w", err)
+		}
+		report.PacksRemoved++
+	}
+	r.packs = nil
+
+	loose, err := r.ListObjects()
+	if err != nil {
+		return nil, err
+	}
+	for _, hash := range loose {
+		if _, ok := offsets[hash]; !ok {
+			continue
+		}
+		objPath := r.objectPath(hash)
+		if err := os.Remove(objPath); err != nil {
+			return nil, fmt.Errorf("failed to remove loose object %s: %w", hash, err)
+		}
+		// Drop the fan-out directory once it is empty; failure just means it is not
+		os.Remove(filepath.Dir(objPath))
+		report.LooseRemoved++
+	}
+
+	return report, nil
+}
+
+// verifyPack reads every object of a freshly written pack back and checks its content
+func verifyPack(idxPath string, offsets map[string]int64) error {
+	idx, err := loadPackIndex(idxPath)
+	if err != nil {
+		return err
+	}
+	if idx.count() != len(offsets) {
+		return fmt.Errorf("pack index lists %d objects, expected %d", idx.count(), len(offsets))
+	}
+	for hash := range offsets {
+		offset, ok := idx.lookup(hash)
+		if !ok {
+			return fmt.Errorf("object %s missing from pack index", hash)
+		}
+		objType, data, err := idx.read(offset)
+		if err != nil {
+			return fmt.Errorf("failed to read packed object %s: %w", hash, err)
+		}
+		if err := checkObject(hash, objType, data); err != nil {
+			return fmt.Errorf("packed object %s is corrupt: %w", hash, err)
+		}
+	}
+	return nil
+}
+
+// writePackIndex writes the index for a pack whose objects start at the given offsets
+func writePackIndex(idxPath string, offsets map[string]int64, checksum []byte) error {
+	raws := make([][]byte, 0, len(offsets))
+	for hash := range offsets {
+		raw, err := hex.DecodeString(hash)
+		if err != nil {
+			return fmt.Errorf("invalid object name %s", hash)
+		}
+		raws = append(raws, raw)
+	}
+	sort.Slice(raws, func(i, j int) bool {
+		return bytes.Compare(raws[i], raws[j]) < 0
+	})
+
+	var buf bytes.Buffer
+	buf.WriteString(packIndexMagic)
+	binary.Write(&buf, binary.BigEndian, uint32(packVersion))
+	binary.Write(&buf, binary.BigEndian, uint32(len(raws)))
+
+	var fanout [256]uint32
+	for _, raw := range raws {
+		fanout[raw[0]]++
+	}
+	for i := 1; i < len(fanout); i++ {
+		fanout[i] += fanout[i-1]
+	}
+	binary.Write(&buf, binary.BigEndian, fanout)
+
+	for _, raw := range raws {
+		buf.Write(raw)
+		binary.Write(&buf, binary.BigEndian, uint64(offsets[hex.EncodeToString(raw)]))
+	}
+	buf.Write(checksum)
+
+	tmpPath := idxPath + ".tmp"
+	if err := os.WriteFile(tmpPath, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write pack index: %w", err)
+	}
+	if err := os.Rename(tmpPath, idxPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to install pack index: %w", err)
+	}
+	return nil
+}
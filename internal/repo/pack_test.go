@@ -0,0 +1,200 @@
+package repo
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// xmlRevision returns a sizable XML document that differs slightly per revision
+func xmlRevision(rev int) string {
+	var b strings.Builder
+	b.WriteString("<treasures>\n")
+	for i := 0; i < 200; i++ {
+		amount := i * 10
+		if i == 100 {
+			amount = rev
+		}
+		fmt.Fprintf(&b, "  <treasure id=\"%d\" item=\"gil\" amount=\"%d\"/>\n", i, amount)
+	}
+	b.WriteString("</treasures>\n")
+	return b.String()
+}
+
+func TestRepackReadsObjectsFromPack(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+	var commits []string
+	for rev := 0; rev < 5; rev++ {
+		commits = append(commits, commitFiles(t, repo, map[string]string{
+			"assets/1000083/treasure.xml": xmlRevision(rev),
+		}, fmt.Sprintf("revision %d", rev)))
+	}
+	garbage, err := repo.writeRawObject("blob", []byte("unreachable"))
+	if err != nil {
+		t.Fatalf("Failed to write blob: %v", err)
+	}
+
+	report, err := repo.Repack(RepackOptions{Window: DefaultPackWindow, Depth: DefaultPackDepth})
+	if err != nil {
+		t.Fatalf("Repack failed: %v", err)
+	}
+	if report.Deltas < 4 {
+		t.Errorf("Expected the XML revisions to be stored as deltas, got %+v", report)
+	}
+
+	loose, err := repo.ListObjects()
+	if err != nil {
+		t.Fatalf("Failed to list objects: %v", err)
+	}
+	if len(loose) != 1 || loose[0] != garbage {
+		t.Errorf("Expected only the unreachable object to stay loose, got %v", loose)
+	}
+
+	for rev, hash := range commits {
+		commit, err := repo.ReadCommit(hash)
+		if err != nil {
+			t.Fatalf("Failed to read packed commit: %v", err)
+		}
+		files, err := repo.FlattenTree(commit.Tree)
+		if err != nil {
+			t.Fatalf("Failed to read packed tree: %v", err)
+		}
+		_, data, err := repo.ReadObject(files["assets/1000083/treasure.xml"].Object)
+		if err != nil {
+			t.Fatalf("Failed to read packed blob: %v", err)
+		}
+		if string(data) != xmlRevision(rev) {
+			t.Errorf("Revision %d read back wrong content", rev)
+		}
+	}
+
+	fsck, err := repo.Fsck()
+	if err != nil {
+		t.Fatalf("Fsck failed: %v", err)
+	}
+	if !fsck.Healthy() {
+		t.Errorf("Expected healthy packed repository, got %v", fsck.Issues)
+	}
+}
+
+func TestRepackDeepDeltaChainsReadBack(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+	var blobs []string
+	for rev := 0; rev < MaxPackDepth+30; rev++ {
+		hash := commitFiles(t, repo, map[string]string{
+			"assets/1000083/treasure.xml": xmlRevision(rev),
+		}, fmt.Sprintf("revision %d", rev))
+		commit, _ := repo.ReadCommit(hash)
+		files, _ := repo.FlattenTree(commit.Tree)
+		blobs = append(blobs, files["assets/1000083/treasure.xml"].Object)
+	}
+	loose, _ := repo.ListObjects()
+
+	// A depth the reader could not resolve is refused before anything changes
+	if _, err := repo.Repack(RepackOptions{Window: 1, Depth: 2 * MaxPackDepth}); err == nil {
+		t.Fatal("Expected a depth above MaxPackDepth to be rejected")
+	}
+	if after, _ := repo.ListObjects(); len(after) != len(loose) {
+		t.Fatalf("Expected a rejected repack to keep all %d loose objects, got %d", len(loose), len(after))
+	}
+
+	report, err := repo.Repack(RepackOptions{Window: 1, Depth: MaxPackDepth})
+	if err != nil {
+		t.Fatalf("Repack failed: %v", err)
+	}
+	if report.Deltas < MaxPackDepth {
+		t.Errorf("Expected a delta chain of the maximum depth, got %+v", report)
+	}
+	for rev, hash := range blobs {
+		_, data, err := repo.ReadObject(hash)
+		if err != nil {
+			t.Fatalf("Failed to read revision %d: %v", rev, err)
+		}
+		if string(data) != xmlRevision(rev) {
+			t.Errorf("Revision %d read back wrong content", rev)
+		}
+	}
+	if fsck, err := repo.Fsck(); err != nil || !fsck.Healthy() {
+		t.Errorf("Expected healthy packed repository, got %v (%v)", fsck, err)
+	}
+}
+
+func TestRepackUnpacksUnreachableObjects(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+	first := commitFiles(t, repo, map[string]string{"assets/1030002/en.txt": "hello"}, "first")
+	second := commitFiles(t, repo, map[string]string{"assets/1030002/en.txt": "goodbye"}, "second")
+
+	if _, err := repo.Repack(RepackOptions{Window: DefaultPackWindow, Depth: DefaultPackDepth}); err != nil {
+		t.Fatalf("Repack failed: %v", err)
+	}
+	if err := repo.UpdateBranch("main", first, RefChange{Command: "reset"}); err != nil {
+		t.Fatalf("Failed to reset branch: %v", err)
+	}
+	// The reflog keeps the reset commit alive until it expires
+	if _, err := repo.ExpireReflogs(time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("Failed to expire reflogs: %v", err)
+	}
+
+	report, err := repo.Repack(RepackOptions{Window: DefaultPackWindow, Depth: DefaultPackDepth})
+	if err != nil {
+		t.Fatalf("Repack failed: %v", err)
+	}
+	if report.PacksRemoved != 1 || report.Unpacked == 0 {
+		t.Errorf("Expected the old pack to be replaced and its garbage unpacked, got %+v", report)
+	}
+	if _, err := os.Stat(repo.objectPath(second)); err != nil {
+		t.Errorf("Expected unreachable commit to become a loose object: %v", err)
+	}
+
+	packed, err := repo.ListPackedObjects()
+	if err != nil {
+		t.Fatalf("Failed to list packed objects: %v", err)
+	}
+	for _, hash := range packed {
+		if hash == second {
+			t.Error("Expected unreachable commit to leave the pack")
+		}
+	}
+}
+
+func TestFsckRepairsFromPack(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+	commitFiles(t, repo, map[string]string{"assets/1030002/en.txt": "hello"}, "first")
+	if _, err := repo.Repack(RepackOptions{Window: DefaultPackWindow, Depth: DefaultPackDepth}); err != nil {
+		t.Fatalf("Repack failed: %v", err)
+	}
+
+	// A damaged loose copy shadows the intact packed one
+	hello, err := repo.writeRawObject("blob", []byte("hello"))
+	if err != nil {
+		t.Fatalf("Failed to write blob: %v", err)
+	}
+	if err := os.WriteFile(repo.objectPath(hello), []byte("blob 5\000jello"), 0644); err != nil {
+		t.Fatalf("Failed to corrupt object: %v", err)
+	}
+
+	report, err := repo.FsckRepair(nil)
+	if err != nil {
+		t.Fatalf("FsckRepair failed: %v", err)
+	}
+	if !report.Healthy() {
+		t.Errorf("Expected repair from the pack, got %v", report.Issues)
+	}
+	if _, data, err := repo.readObject(hello); err != nil || string(data) != "hello" {
+		t.Errorf("Expected packed content, got %q (%v)", data, err)
+	}
+}
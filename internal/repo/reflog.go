@@ -0,0 +1,254 @@
+package repo
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultAuthor is recorded when no author is given
+const DefaultAuthor = "RDB <rdb@localhost>"
+
+// ReflogEntry records one movement of a ref. Reflogs are stored below .rdb/logs,
+// one JSON entry per line, oldest first: logs/HEAD for HEAD and
+// logs/refs/heads/<branch> for each branch.
+type ReflogEntry struct {
+	Old     string    `json:"old"` // empty when the ref was created
+	New     string    `json:"new"`
+	Author  string    `json:"author"`
+	Time    time.Time `json:"time"`
+	Command string    `json:"command"`
+	Message string    `json:"message,omitempty"`
+}
+
+// RefChange describes why a ref is moved; it becomes the reflog entry
+type RefChange struct {
+	Command string // the rdb command, such as "commit --amend"
+	Message string
+	Author  string // empty means DefaultAuthor
+}
+
+// reflogPath returns the log file of a ref such as "HEAD" or "refs/heads/main"
+func (r *Repository) reflogPath(ref string) string {
+	return filepath.Join(r.Path, ".rdb", "logs", filepath.FromSlash(ref))
+}
+
+// appendReflog records that ref moved from oldHash to newHash
+func (r *Repository) appendReflog(ref, oldHash, newHash string, change RefChange) error {
+	author := change.Author
+	if author == "" {
+		author = DefaultAuthor
+	}
+	line, err := json.Marshal(ReflogEntry{
+		Old:     oldHash,
+		New:     newHash,
+		Author:  author,
+		Time:    time.Now(),
+		Command: change.Command,
+		Message: change.Message,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal reflog entry: %w", err)
+	}
+
+	path := r.reflogPath(ref)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create reflog directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open reflog: %w", err)
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write reflog: %w", err)
+	}
+	return file.Close()
+}
+
+// ReadReflog returns the reflog of a ref such as "HEAD" or "refs/heads/main", newest
+// first, so entry n is the ref's value n moves ago. A ref without a log has no entries.
+func (r *Repository) ReadReflog(ref string) ([]ReflogEntry, error) {
+	file, err := os.Open(r.reflogPath(ref))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open reflog: %w", err)
+	}
+	defer file.Close()
+
+	var entries []ReflogEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var entry ReflogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("invalid reflog entry at %s line %d: %w", ref, line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read reflog: %w", err)
+	}
+
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, nil
+}
+
+// ReflogRef returns the ref whose reflog a name refers to: HEAD or a branch
+func ReflogRef(name string) string {
+	if name == "HEAD" || strings.HasPrefix(name, "refs/") {
+		return name
+	}
+	return "refs/heads/" + name
+}
+
+// ResolveReflog returns the value ref had n moves ago
+func (r *Repository) ResolveReflog(ref string, n int) (string, error) {
+	entries, err := r.ReadReflog(ref)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case n < len(entries):
+		return entries[n].New, nil
+	case n == len(entries) && n > 0 && entries[n-1].Old != "":
+		return entries[n-1].Old, nil
+	}
+	return "", fmt.Errorf("log for %s only has %d entries", strings.TrimPrefix(ref, "refs/heads/"), len(entries))
+}
+
+// reflogRefs returns every ref that has a reflog
+func (r *Repository) reflogRefs() ([]string, error) {
+	refs := []string{"HEAD"}
+	names, err := r.listRefs(filepath.Join(r.Path, ".rdb", "logs", "refs"))
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		refs = append(refs, "refs/"+name)
+	}
+	return refs, nil
+}
+
+// reflogRoots adds every value recorded in the reflogs since cutoff to roots, keyed
+// as <ref>@{n}, so gc keeps the commits a reflog can still name
+func (r *Repository) reflogRoots(roots map[string]string, cutoff time.Time) error {
+	refs, err := r.reflogRefs()
+	if err != nil {
+		return err
+	}
+	for _, ref := range refs {
+		entries, err := r.ReadReflog(ref)
+		if err != nil {
+			return err
+		}
+		for n, entry := range entries {
+			if entry.Time.Before(cutoff) {
+				break
+			}
+			if entry.New != "" {
+				roots[fmt.Sprintf("%s@{%d}", ref, n)] = entry.New
+			}
+			// The previous value is normally the next entry's new value, but not if the
+			// ref was moved without a reflog entry or the next entry is the oldest
+			if entry.Old != "" && (n+1 == len(entries) || entries[n+1].New != entry.Old) {
+				key := fmt.Sprintf("%s@{%d}", ref, n+1)
+				if n+1 < len(entries) {
+					key = fmt.Sprintf("%s@{%d} old", ref, n)
+				}
+				roots[key] = entry.Old
+			}
+		}
+	}
+	return nil
+}
+
+// ExpireReflogs drops reflog entries recorded before cutoff and returns how many it dropped
+func (r *Repository) ExpireReflogs(cutoff time.Time) (int, error) {
+	refs, err := r.reflogRefs()
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, ref := range refs {
+		n, err := r.expireReflog(ref, cutoff)
+		if err != nil {
+			return expired, err
+		}
+		expired += n
+	}
+	return expired, nil
+}
+
+// expireReflog rewrites one reflog without the entries recorded before cutoff
+func (r *Repository) expireReflog(ref string, cutoff time.Time) (int, error) {
+	lock, err := r.acquireLock(ref, r.lockTimeout())
+	if err != nil {
+		return 0, err
+	}
+	defer lock.Unlock()
+
+	entries, err := r.ReadReflog(ref)
+	if err != nil {
+		return 0, err
+	}
+	keep := 0
+	for keep < len(entries) && !entries[keep].Time.Before(cutoff) {
+		keep++
+	}
+	if keep == len(entries) {
+		return 0, nil
+	}
+	if keep == 0 {
+		if err := os.Remove(r.reflogPath(ref)); err != nil && !os.IsNotExist(err) {
+			return 0, fmt.Errorf("failed to expire reflog: %w", err)
+		}
+		return len(entries), nil
+	}
+
+	var buf []byte
+	for i := keep - 1; i >= 0; i-- {
+		line, err := json.Marshal(entries[i])
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal reflog entry: %w", err)
+		}
+		buf = append(append(buf, line...), '\n')
+	}
+	if err := writeFileAtomic(r.reflogPath(ref), buf); err != nil {
+		return 0, fmt.Errorf("failed to expire reflog: %w", err)
+	}
+	return len(entries) - keep, nil
+}
+
+// renameReflog moves the reflog of a renamed branch
+func (r *Repository) renameReflog(oldName, newName string) error {
+	oldPath := r.reflogPath(ReflogRef(oldName))
+	newPath := r.reflogPath(ReflogRef(newName))
+	if err := os.MkdirAll(filepath.Dir(newPath), 0755); err != nil {
+		return fmt.Errorf("failed to create reflog directory: %w", err)
+	}
+	if err := os.Rename(oldPath, newPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to rename reflog: %w", err)
+	}
+	return nil
+}
+
+// deleteReflog removes the reflog of a deleted branch
+func (r *Repository) deleteReflog(name string) error {
+	if err := os.Remove(r.reflogPath(ReflogRef(name))); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete reflog: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package repo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReflogRecordsRefMovements(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+	initial, err := repo.GetCurrentCommit()
+	if err != nil {
+		t.Fatalf("Failed to get current commit: %v", err)
+	}
+	first := commitFiles(t, repo, map[string]string{"assets/1030002/en.txt": "hello"}, "first")
+
+	// An amend replaces the tip; the reflog still names the replaced commit
+	amended, err := repo.WriteObject("commit", &Commit{ID: GenerateID(), Message: "amended", Parents: []string{initial}})
+	if err != nil {
+		t.Fatalf("Failed to write commit: %v", err)
+	}
+	change := RefChange{Command: "commit --amend", Message: "amended", Author: "Jane <jane@example.com>"}
+	if err := repo.CompareAndSwapBranch("main", first, amended, change); err != nil {
+		t.Fatalf("Failed to amend: %v", err)
+	}
+
+	entries, err := repo.ReadReflog("refs/heads/main")
+	if err != nil {
+		t.Fatalf("Failed to read reflog: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 reflog entries, got %+v", entries)
+	}
+	if e := entries[0]; e.Old != first || e.New != amended || e.Command != "commit --amend" || e.Author != change.Author {
+		t.Errorf("Unexpected newest entry: %+v", e)
+	}
+	if e := entries[2]; e.Old != "" || e.New != initial || e.Command != "init" || e.Author != DefaultAuthor {
+		t.Errorf("Unexpected oldest entry: %+v", e)
+	}
+	if head, _ := repo.ReadReflog("HEAD"); len(head) != 3 {
+		t.Errorf("Expected HEAD to follow the current branch, got %+v", head)
+	}
+
+	for rev, want := range map[string]string{
+		"main@{0}": amended,
+		"main@{1}": first,
+		"@{2}":     initial,
+		"HEAD@{1}": first,
+		"main":     amended,
+		"HEAD":     amended,
+	} {
+		got, err := repo.ResolveRevision(rev)
+		if err != nil || got != want {
+			t.Errorf("ResolveRevision(%q) = %s (%v), want %s", rev, got, err, want)
+		}
+	}
+	if _, err := repo.ResolveRevision("main@{3}"); err == nil {
+		t.Error("Expected an error past the end of the reflog")
+	}
+}
+
+func TestReflogFollowsBranchesAndKeepsCommits(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+	first := commitFiles(t, repo, map[string]string{"assets/1030002/en.txt": "hello"}, "first")
+	second := commitFiles(t, repo, map[string]string{"assets/1030002/en.txt": "goodbye"}, "second")
+
+	if err := repo.CreateBranch("topic", second); err != nil {
+		t.Fatalf("Failed to create branch: %v", err)
+	}
+	if err := repo.RenameBranch("topic", "feature"); err != nil {
+		t.Fatalf("Failed to rename branch: %v", err)
+	}
+	if entries, _ := repo.ReadReflog("refs/heads/feature"); len(entries) != 2 || entries[1].Command != "branch" {
+		t.Errorf("Expected the reflog to move with the branch, got %+v", entries)
+	}
+	if err := repo.DeleteBranch("feature"); err != nil {
+		t.Fatalf("Failed to delete branch: %v", err)
+	}
+	if entries, _ := repo.ReadReflog("refs/heads/feature"); len(entries) != 0 {
+		t.Errorf("Expected the reflog to be deleted with the branch, got %+v", entries)
+	}
+
+	// Resetting main leaves the second commit reachable only from the reflog
+	if err := repo.UpdateBranch("main", first, RefChange{Command: "reset"}); err != nil {
+		t.Fatalf("Failed to reset branch: %v", err)
+	}
+	report, err := repo.GC(GCOptions{ReflogExpiry: DefaultReflogExpiry})
+	if err != nil {
+		t.Fatalf("GC failed: %v", err)
+	}
+	if len(report.Pruned) != 0 || report.Expired != 0 {
+		t.Errorf("Expected gc to keep commits named by the reflog, got %+v", report)
+	}
+	if _, err := repo.ReadCommit(second); err != nil {
+		t.Errorf("Expected the reset commit to survive: %v", err)
+	}
+
+	// Once the entries expire the commit is garbage
+	report, err = repo.GC(GCOptions{ReflogExpiry: -time.Second})
+	if err != nil {
+		t.Fatalf("GC failed: %v", err)
+	}
+	if report.Expired == 0 || len(report.Pruned) == 0 {
+		t.Errorf("Expected expired reflog entries to release the commit, got %+v", report)
+	}
+	if _, err := repo.ReadCommit(second); err == nil {
+		t.Error("Expected the reset commit to be removed")
+	}
+	if entries, _ := repo.ReadReflog("HEAD"); len(entries) != 0 {
+		t.Errorf("Expected HEAD's reflog to be empty, got %+v", entries)
+	}
+}
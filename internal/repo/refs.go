@@ -0,0 +1,250 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// branchRefPath returns the file holding the tip of a branch. The name is validated
+// so that it cannot point outside refs/heads.
+func (r *Repository) branchRefPath(name string) (string, error) {
+	if err := ValidateRefName(name); err != nil {
+		return "", err
+	}
+	return filepath.Join(r.Path, ".rdb", "refs", "heads", filepath.FromSlash(name)), nil
+}
+
+// ValidateRefName checks that name can be used as a branch or tag name
+func ValidateRefName(name string) error {
+	if name == "" {
+		return fmt.Errorf("name must not be empty")
+	}
+	if name == "HEAD" || name == "@" || strings.HasPrefix(name, "-") || strings.HasPrefix(name, "/") || strings.HasSuffix(name, "/") {
+		return fmt.Errorf("invalid name: %s", name)
+	}
+	if strings.Contains(name, "..") || strings.Contains(name, "//") || strings.Contains(name, "@{") {
+		return fmt.Errorf("invalid name: %s", name)
+	}
+	for _, c := range name {
+		if c <= ' ' || strings.ContainsRune(`\~^:?*[`, c) {
+			return fmt.Errorf("invalid character %q in name: %s", c, name)
+		}
+	}
+	return nil
+}
+
+// BranchExists reports whether a branch with the given name exists
+func (r *Repository) BranchExists(name string) bool {
+	refPath, err := r.branchRefPath(name)
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(refPath)
+	return err == nil && !info.IsDir()
+}
+
+// ReadBranch returns the commit hash a branch points to
+func (r *Repository) ReadBranch(name string) (string, error) {
+	refPath, err := r.branchRefPath(name)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(refPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("branch not found: %s", name)
+		}
+		return "", fmt.Errorf("failed to read branch ref: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// UpdateBranch points a branch at the given commit, creating it if needed
+func (r *Repository) UpdateBranch(name, commitHash string, change RefChange) error {
+	refPath, err := r.branchRefPath(name)
+	if err != nil {
+		return err
+	}
+	return r.updateRef("refs/heads/"+name, refPath, nil, commitHash, change)
+}
+
+// CompareAndSwapBranch points a branch at newHash only if it still points at oldHash,
+// returning an error wrapping ErrRefChanged otherwise. An empty oldHash means the
+// branch must not exist yet.
+func (r *Repository) CompareAndSwapBranch(name, oldHash, newHash string, change RefChange) error {
+	refPath, err := r.branchRefPath(name)
+	if err != nil {
+		return err
+	}
+	return r.updateRef("refs/heads/"+name, refPath, &oldHash, newHash, change)
+}
+
+// ListBranches returns all branch names in sorted order
+func (r *Repository) ListBranches() ([]string, error) {
+	return r.listRefs(filepath.Join(r.Path, ".rdb", "refs", "heads"))
+}
+
+// listRefs returns the slash-separated names of all ref files below dir
+func (r *Repository) listRefs(dir string) ([]string, error) {
+	var names []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasSuffix(d.Name(), ".lock") || isTempRefFile(d.Name()) {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list refs: %w", err)
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// CreateBranch creates a new branch pointing at the given commit
+func (r *Repository) CreateBranch(name, commitHash string) error {
+	if err := ValidateRefName(name); err != nil {
+		return err
+	}
+	if r.BranchExists(name) {
+		return fmt.Errorf("branch already exists: %s", name)
+	}
+	if _, err := r.ReadCommit(commitHash); err != nil {
+		return fmt.Errorf("invalid start point: %w", err)
+	}
+	change := RefChange{Command: "branch", Message: "created at " + refLabel(commitHash)}
+	if err := r.CompareAndSwapBranch(name, "", commitHash, change); err != nil {
+		if errors.Is(err, ErrRefChanged) {
+			return fmt.Errorf("branch already exists: %s", name)
+		}
+		return err
+	}
+	return nil
+}
+
+// DeleteBranch removes a branch; the current branch cannot be deleted
+func (r *Repository) DeleteBranch(name string) error {
+	refPath, err := r.branchRefPath(name)
+	if err != nil {
+		return err
+	}
+	if !r.BranchExists(name) {
+		return fmt.Errorf("branch not found: %s", name)
+	}
+	if current, err := r.GetCurrentBranch(); err == nil && current == name {
+		return fmt.Errorf("cannot delete the current branch: %s", name)
+	}
+
+	lock, err := r.acquireLock("refs/heads/"+name, r.lockTimeout())
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	if err := os.Remove(refPath); err != nil {
+		return fmt.Errorf("failed to delete branch: %w", err)
+	}
+	r.removeEmptyRefDirs(filepath.Dir(refPath))
+	return r.deleteReflog(name)
+}
+
+// RenameBranch renames a branch, following HEAD if it pointed at the old name
+func (r *Repository) RenameBranch(oldName, newName string) error {
+	oldPath, err := r.branchRefPath(oldName)
+	if err != nil {
+		return err
+	}
+	if err := ValidateRefName(newName); err != nil {
+		return err
+	}
+	if !r.BranchExists(oldName) {
+		return fmt.Errorf("branch not found: %s", oldName)
+	}
+	if r.BranchExists(newName) {
+		return fmt.Errorf("branch already exists: %s", newName)
+	}
+
+	commitHash, err := r.ReadBranch(oldName)
+	if err != nil {
+		return err
+	}
+	if err := r.renameReflog(oldName, newName); err != nil {
+		return err
+	}
+	change := RefChange{Command: "branch -m", Message: "renamed from " + oldName}
+	if err := r.CompareAndSwapBranch(newName, "", commitHash, change); err != nil {
+		return err
+	}
+	if err := os.Remove(oldPath); err != nil {
+		return fmt.Errorf("failed to remove old branch: %w", err)
+	}
+	r.removeEmptyRefDirs(filepath.Dir(oldPath))
+
+	if current, err := r.GetCurrentBranch(); err == nil && current == oldName {
+		return r.SetHead(newName, change)
+	}
+	return nil
+}
+
+// removeEmptyRefDirs prunes directories left behind by hierarchical branch and tag
+// names, keeping the namespace directories such as refs/heads
+func (r *Repository) removeEmptyRefDirs(dir string) {
+	refsPath := filepath.Join(r.Path, ".rdb", "refs")
+	for strings.HasPrefix(dir, refsPath+string(filepath.Separator)) && filepath.Dir(dir) != refsPath {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// SetHead points HEAD at the given branch, recording the switch in HEAD's reflog
+func (r *Repository) SetHead(branch string, change RefChange) error {
+	lock, err := r.acquireLock("HEAD", r.lockTimeout())
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	oldHash, _ := r.GetCurrentCommit()
+	newHash, _ := r.ReadBranch(branch)
+
+	headPath := filepath.Join(r.Path, ".rdb", "HEAD")
+	if err := writeFileAtomic(headPath, []byte("ref: refs/heads/"+branch)); err != nil {
+		return fmt.Errorf("failed to write HEAD: %w", err)
+	}
+
+	if newHash == "" {
+		return nil
+	}
+	return r.appendReflog("HEAD", oldHash, newHash, change)
+}
+
+// IsAncestor reports whether ancestor is reachable from descendant
+func (r *Repository) IsAncestor(ancestor, descendant string) (bool, error) {
+	found := false
+	err := r.WalkCommits([]string{descendant}, func(hash string, commit *Commit) error {
+		if hash == ancestor {
+			found = true
+			return ErrStopWalk
+		}
+		return nil
+	})
+	return found, err
+}
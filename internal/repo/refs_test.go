@@ -0,0 +1,111 @@
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBranchLifecycle(t *testing.T) {
+	tempDir := t.TempDir()
+	repo := NewRepository(tempDir)
+	if err := repo.Init("tree", []string{"text"}); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+
+	head, err := repo.GetCurrentCommit()
+	if err != nil {
+		t.Fatalf("Failed to get current commit: %v", err)
+	}
+
+	if err := repo.CreateBranch("season/winter", head); err != nil {
+		t.Fatalf("Failed to create branch: %v", err)
+	}
+	if err := repo.CreateBranch("season/winter", head); err == nil {
+		t.Error("Expected error when creating an existing branch")
+	}
+	if err := repo.CreateBranch("bad..name", head); err == nil {
+		t.Error("Expected error for invalid branch name")
+	}
+
+	branches, err := repo.ListBranches()
+	if err != nil {
+		t.Fatalf("Failed to list branches: %v", err)
+	}
+	if len(branches) != 2 || branches[0] != "main" || branches[1] != "season/winter" {
+		t.Errorf("Unexpected branches: %v", branches)
+	}
+
+	if err := repo.RenameBranch("main", "trunk"); err != nil {
+		t.Fatalf("Failed to rename branch: %v", err)
+	}
+	current, err := repo.GetCurrentBranch()
+	if err != nil {
+		t.Fatalf("Failed to get current branch: %v", err)
+	}
+	if current != "trunk" {
+		t.Errorf("Expected HEAD to follow rename to 'trunk', got '%s'", current)
+	}
+
+	if err := repo.DeleteBranch("trunk"); err == nil {
+		t.Error("Expected error when deleting the current branch")
+	}
+	if err := repo.DeleteBranch("season/winter"); err != nil {
+		t.Fatalf("Failed to delete branch: %v", err)
+	}
+	if repo.BranchExists("season/winter") {
+		t.Error("Expected branch to be deleted")
+	}
+}
+
+func TestBranchNamesCannotEscapeRefs(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init("tree", []string{"text"}); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+	head, _ := repo.GetCurrentCommit()
+	configPath := filepath.Join(repo.Path, ".rdb", "config.json")
+
+	for _, name := range []string{"../../config.json", "../HEAD", "main/../../../config.json"} {
+		if repo.BranchExists(name) {
+			t.Errorf("BranchExists(%q) reported a file outside refs/heads", name)
+		}
+		if _, err := repo.ReadBranch(name); err == nil {
+			t.Errorf("Expected ReadBranch(%q) to fail", name)
+		}
+		if err := repo.DeleteBranch(name); err == nil {
+			t.Errorf("Expected DeleteBranch(%q) to fail", name)
+		}
+		if err := repo.RenameBranch(name, "stolen"); err == nil {
+			t.Errorf("Expected RenameBranch(%q) to fail", name)
+		}
+		if err := repo.UpdateBranch(name, head, RefChange{}); err == nil {
+			t.Errorf("Expected UpdateBranch(%q) to fail", name)
+		}
+	}
+
+	if _, err := os.Stat(configPath); err != nil {
+		t.Errorf("Expected config.json to survive: %v", err)
+	}
+	if !IsRepository(repo.Path) {
+		t.Error("Expected the repository to stay intact")
+	}
+}
+
+func TestIsAncestor(t *testing.T) {
+	tempDir := t.TempDir()
+	repo := NewRepository(tempDir)
+	if err := repo.Init("tree", []string{"text"}); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+
+	first := commitFiles(t, repo, map[string]string{"assets/1030002/en.txt": "one"}, "first")
+	second := commitFiles(t, repo, map[string]string{"assets/1030002/en.txt": "two"}, "second")
+
+	if ok, err := repo.IsAncestor(first, second); err != nil || !ok {
+		t.Errorf("Expected %s to be an ancestor of %s", first, second)
+	}
+	if ok, err := repo.IsAncestor(second, first); err != nil || ok {
+		t.Errorf("Expected %s not to be an ancestor of %s", second, first)
+	}
+}
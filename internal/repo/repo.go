@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -16,6 +17,12 @@ import (
 type Repository struct {
 	Path   string
 	Config *Config
+	
+	// LockTimeout is how long to wait for a lock held by another process;
+	// zero means DefaultLockTimeout
+	LockTimeout time.Duration
+	
+	packs []*packIndex // pack indexes, loaded on first use by loadPacks
 }
 
 // Config represents the repository configuration
@@ -25,6 +32,9 @@ type Config struct {
 		AutoCRLF string `json:"autocrlf"` // "true", "false", or "input"
 	} `json:"core"`
 	Types []string `json:"types,omitempty"`
+	
+	// Storage controls compression of new objects
+	Storage StorageConfig `json:"storage"`
 }
 
 // Asset represents a typed asset in the repository
@@ -33,6 +43,9 @@ type Asset struct {
 	ID   int    `json:"id"`
 	Name string `json:"name,omitempty"`
 	
+	// Status is "draft" for work in progress that must not ship; empty means ready
+	Status string `json:"status,omitempty"`
+	
 	// Metadata
 	Tags       []string               `json:"tags,omitempty"`
 	Version    int                    `json:"version,omitempty"`
@@ -44,6 +57,14 @@ type Asset struct {
 	ETag  string      `json:"etag,omitempty"`
 }
 
+// AssetStatusDraft marks an asset as work in progress
+const AssetStatusDraft = "draft"
+
+// IsDraft reports whether the asset is marked as a draft
+func (a *Asset) IsDraft() bool {
+	return a.Status == AssetStatusDraft
+}
+
 // AssetPath represents a logical path to content
 type AssetPath struct {
 	Logical string `json:"logical"`
@@ -64,10 +85,22 @@ type Commit struct {
 	Timestamp time.Time `json:"timestamp"`
 	Message   string    `json:"message"`
 	Branch    string    `json:"branch"`
-	Parent    string    `json:"parent,omitempty"`
+	Parent    string    `json:"parent,omitempty"`  // single parent written by older versions
+	Parents   []string  `json:"parents,omitempty"` // first parent is the previous branch tip; merges add more
 	Tree      string    `json:"tree"` // SHA256 of the tree object
 }
 
+// ParentHashes returns the commit's parents, first parent first
+func (c *Commit) ParentHashes() []string {
+	if len(c.Parents) > 0 {
+		return c.Parents
+	}
+	if c.Parent != "" {
+		return []string{c.Parent}
+	}
+	return nil
+}
+
 // Tree represents a directory tree
 type Tree struct {
 	Entries []TreeEntry `json:"entries"`
@@ -91,8 +124,19 @@ func NewRepository(path string) *Repository {
 	}
 }
 
-// Init initializes a new RDB repository
+// Init initializes a new RDB repository with the built-in asset types
 func (r *Repository) Init(layout string, types []string) error {
+	return r.InitFromTemplate(layout, types, nil)
+}
+
+// InitFromTemplate initializes a new RDB repository seeded from tmpl, or from the
+// built-in asset types when tmpl is nil. With the tree layout, only the types
+// selected by types (see TypeRegistry.Select) get an asset folder.
+func (r *Repository) InitFromTemplate(layout string, types []string, tmpl *Template) error {
+	if _, err := LayoutByName(layout); err != nil {
+		return err
+	}
+	
 	// Create .rdb directory structure
 	rdbPath := filepath.Join(r.Path, ".rdb")
 	
@@ -117,14 +161,14 @@ func (r *Repository) Init(layout string, types []string) error {
 	r.Config.Core.Layout = layout
 	r.Config.Core.AutoCRLF = "true"
 	r.Config.Types = types
+	r.Config.Storage = StorageConfig{Compression: EncodingZlib, SkipCompression: DefaultSkipCompression}
 	
 	if err := r.SaveConfig(); err != nil {
 		return fmt.Errorf("failed to save config: %w", err)
 	}
 	
 	// Create HEAD file pointing to main branch
-	headPath := filepath.Join(rdbPath, "HEAD")
-	if err := os.WriteFile(headPath, []byte("ref: refs/heads/main"), 0644); err != nil {
+	if err := r.SetHead("main", RefChange{Command: "init"}); err != nil {
 		return fmt.Errorf("failed to create HEAD: %w", err)
 	}
 	
@@ -134,37 +178,29 @@ func (r *Repository) Init(layout string, types []string) error {
 		return fmt.Errorf("failed to create assets directory: %w", err)
 	}
 	
-	// Create asset directories for all predefined asset types
-	assetIDs := []int{
-		1000624, // Flash Images
-		1030002, // Strings
-		1010042, // Loading Screens
-		1000083, // XML Treasure Data
-		1000087, // XML Zone Transition Points
-		1000090, // XML Resurrection Points
-		1000635, // USM Video Files
-		1000636, // Images
-		1070003, // Playfields
-		1010013, // Maps
-		1010210, // (no name specified)
-		1010211, // (no name specified)
-		1000623, // Misc Text Files
-		1066603, // Unknown Textures
-		1020001, // (no name specified)
-		1020002, // Sound Effects
-		1020005, // Music
-		1020006, // Sounds - Tones
-		1010207, // Particle Effects
-		1000010, // File Names Index / FME Files
-		1000007, // PhysX XML
-		1020003, // Dialog Audio
-		1010008, // Miscellaneous Images
-	}
-	
-	for _, assetID := range assetIDs {
-		assetDir := filepath.Join(assetsPath, strconv.Itoa(assetID))
-		if err := os.MkdirAll(assetDir, 0755); err != nil {
-			return fmt.Errorf("failed to create asset directory %d: %w", assetID, err)
+	// Create the type registry and a folder for every selected type
+	registry := &TypeRegistry{Version: TypesVersion, Types: DefaultTypes()}
+	if tmpl != nil {
+		registry.Types = append([]AssetType(nil), tmpl.Types...)
+	}
+	if err := r.SaveTypes(registry); err != nil {
+		return err
+	}
+	
+	if r.Layout().Name() == LayoutTree {
+		selected, _ := registry.Select(types)
+		for _, assetType := range selected {
+			assetDir := filepath.Join(assetsPath, strconv.Itoa(assetType.ID))
+			if err := os.MkdirAll(assetDir, 0755); err != nil {
+				return fmt.Errorf("failed to create asset directory %d: %w", assetType.ID, err)
+			}
+		}
+	}
+	
+	// Copy the template's starter files
+	if tmpl != nil {
+		if err := tmpl.seed(r); err != nil {
+			return fmt.Errorf("failed to apply template %s: %w", tmpl.Name, err)
 		}
 	}
 	
@@ -220,7 +256,7 @@ func (r *Repository) createInitialCommit() error {
 	// Create initial commit
 	commit := &Commit{
 		ID:        generateID(),
-		Author:    "RDB <rdb@localhost>",
+		Author:    DefaultAuthor,
 		Timestamp: time.Now(),
 		Message:   "Initial commit",
 		Branch:    "main",
@@ -233,8 +269,7 @@ func (r *Repository) createInitialCommit() error {
 	}
 	
 	// Update HEAD to point to main branch
-	headPath := filepath.Join(r.Path, ".rdb", "refs", "heads", "main")
-	if err := os.WriteFile(headPath, []byte(commitHash), 0644); err != nil {
+	if err := r.UpdateBranch("main", commitHash, RefChange{Command: "init", Message: commit.Message}); err != nil {
 		return fmt.Errorf("failed to write HEAD: %w", err)
 	}
 	
@@ -248,22 +283,18 @@ func (r *Repository) writeObject(objType string, obj interface{}) (string, error
 		return "", fmt.Errorf("failed to marshal object: %w", err)
 	}
 	
+	return r.writeRawObject(objType, data)
+}
+
+// writeRawObject writes already-encoded object data to the repository
+func (r *Repository) writeRawObject(objType string, data []byte) (string, error) {
 	// Calculate SHA256 hash
 	hash := sha256.Sum256(data)
 	hashStr := hex.EncodeToString(hash[:])
 	
-	// Create object path
-	objPath := filepath.Join(r.Path, ".rdb", "objects", hashStr[:2], hashStr[2:])
-	if err := os.MkdirAll(filepath.Dir(objPath), 0755); err != nil {
-		return "", fmt.Errorf("failed to create object directory: %w", err)
-	}
-	
-	// Write object with type prefix
-	content := fmt.Sprintf("%s %d\000", objType, len(data))
-	content += string(data)
-	
-	if err := os.WriteFile(objPath, []byte(content), 0644); err != nil {
-		return "", fmt.Errorf("failed to write object: %w", err)
+	// Write object with a versioned header, compressed unless disabled
+	if err := r.storeObject(hashStr, objType, data, r.compressionEnabled()); err != nil {
+		return "", err
 	}
 	
 	return hashStr, nil
@@ -341,15 +372,35 @@ func (r *Repository) GetCurrentCommit() (string, error) {
 		return "", fmt.Errorf("failed to read branch ref: %w", err)
 	}
 	
-	return string(data), nil
+	return strings.TrimSpace(string(data)), nil
 }
 
-// readObject reads an object from the repository
+// objectPath returns the location of a loose object
+func (r *Repository) objectPath(hash string) string {
+	return filepath.Join(r.Path, ".rdb", "objects", hash[:2], hash[2:])
+}
+
+// readObject reads an object from the repository, reassembling chunked blobs
 func (r *Repository) readObject(hash string) (string, []byte, error) {
-	objPath := filepath.Join(r.Path, ".rdb", "objects", hash[:2], hash[2:])
+	objType, data, err := r.readStoredObject(hash)
+	if err != nil || objType != ChunkedType {
+		return objType, data, err
+	}
+	return r.readChunkedBlob(data)
+}
+
+// readStoredObject reads an object as stored, whether loose or packed
+func (r *Repository) readStoredObject(hash string) (string, []byte, error) {
+	objPath := r.objectPath(hash)
 	
 	file, err := os.Open(objPath)
 	if err != nil {
+		// Fall back to the pack files
+		if os.IsNotExist(err) {
+			if objType, data, ok, perr := r.readPackedObject(hash); ok || perr != nil {
+				return objType, data, perr
+			}
+		}
 		return "", nil, fmt.Errorf("failed to open object: %w", err)
 	}
 	defer file.Close()
@@ -360,37 +411,7 @@ func (r *Repository) readObject(hash string) (string, []byte, error) {
 		return "", nil, fmt.Errorf("failed to read object: %w", err)
 	}
 	
-	// Find the null byte separator
-	nullIndex := -1
-	for i, b := range data {
-		if b == 0 {
-			nullIndex = i
-			break
-		}
-	}
-	
-	if nullIndex == -1 {
-		return "", nil, fmt.Errorf("invalid object format: no null separator found")
-	}
-	
-	// Parse header
-	headerStr := string(data[:nullIndex])
-	var objType string
-	var size int
-	_, err = fmt.Sscanf(headerStr, "%s %d", &objType, &size)
-	if err != nil {
-		return "", nil, fmt.Errorf("failed to parse object header: %w", err)
-	}
-	
-	// Extract object data
-	objData := data[nullIndex+1:]
-	
-	// Verify size
-	if len(objData) != size {
-		return "", nil, fmt.Errorf("object size mismatch: expected %d, got %d", size, len(objData))
-	}
-	
-	return objType, objData, nil
+	return parseObject(data)
 }
 
 // ReadObject reads an object from the repository (public method)
@@ -135,4 +135,21 @@ func TestGenerateID(t *testing.T) {
 	if len(id1) != 16 {
 		t.Errorf("Expected ID length 16, got %d", len(id1))
 	}
-} 
\ No newline at end of file
+}
+
+func TestCommitParentHashes(t *testing.T) {
+	legacy := &Commit{Parent: "abc"}
+	if got := legacy.ParentHashes(); len(got) != 1 || got[0] != "abc" {
+		t.Errorf("Expected legacy parent [abc], got %v", got)
+	}
+	
+	merge := &Commit{Parents: []string{"abc", "def"}}
+	if got := merge.ParentHashes(); len(got) != 2 || got[1] != "def" {
+		t.Errorf("Expected merge parents [abc def], got %v", got)
+	}
+	
+	root := &Commit{}
+	if got := root.ParentHashes(); len(got) != 0 {
+		t.Errorf("Expected no parents, got %v", got)
+	}
+}
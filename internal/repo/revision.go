@@ -0,0 +1,410 @@
+package repo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MinAbbrevLength is the shortest hash prefix accepted as a revision
+const MinAbbrevLength = 4
+
+// Revision is what a revision expression names: a commit and, for asset:<id>@<rev>,
+// the single asset it is limited to
+type Revision struct {
+	Commit string
+	Asset  int // asset ID, or 0 for the whole commit
+}
+
+// AssetPath returns the canonical tree path of the revision's asset, or "" for a whole commit
+func (rev *Revision) AssetPath() string {
+	if rev.Asset == 0 {
+		return ""
+	}
+	return "assets/" + strconv.Itoa(rev.Asset)
+}
+
+// ResolveRevision returns the commit named by a revision expression (see ParseRevision).
+// Expressions limited to an asset are rejected, since the caller wants a whole commit.
+func (r *Repository) ResolveRevision(expr string) (string, error) {
+	rev, err := r.ParseRevision(expr)
+	if err != nil {
+		return "", err
+	}
+	if rev.Asset != 0 {
+		return "", fmt.Errorf("%s names an asset, not a commit", expr)
+	}
+	return rev.Commit, nil
+}
+
+// ParseRevision resolves a revision expression. It accepts
+//
+//	HEAD or @            the current commit
+//	<branch>, <tag>      the commit a branch or tag points to (annotated tags are peeled)
+//	<hash>               a full commit hash, or an unambiguous prefix of at least MinAbbrevLength
+//	<ref>@{<n>}          the value of a branch (or HEAD) n moves ago, from its reflog;
+//	                     @{<n>} uses the current branch
+//	<ref>@{<date>}       the value of a branch at a date, such as @{yesterday} or main@{2024-05-01}
+//	<rev>~<n>            the n-th first-parent ancestor (~ alone means ~1)
+//	<rev>^<n>            the n-th parent of a merge (^ alone means ^1, ^0 the commit itself)
+//	asset:<id>@<rev>     asset <id> as of <rev>; asset:<id> alone means HEAD
+//
+// Suffixes can be chained, as in main@{1}~2^2.
+func (r *Repository) ParseRevision(expr string) (*Revision, error) {
+	if rest, ok := strings.CutPrefix(expr, "asset:"); ok {
+		return r.parseAssetRevision(expr, rest)
+	}
+
+	base, ops := splitRevisionSuffixes(expr)
+	hash, err := r.resolveRevisionBase(base)
+	if err != nil {
+		return nil, err
+	}
+	if hash, err = r.PeelTag(hash); err != nil {
+		return nil, err
+	}
+	if _, err := r.ReadCommit(hash); err != nil {
+		return nil, fmt.Errorf("%s does not name a commit: %w", base, err)
+	}
+
+	for ops != "" {
+		op := ops[0]
+		digits := 0
+		for digits+1 < len(ops) && ops[digits+1] >= '0' && ops[digits+1] <= '9' {
+			digits++
+		}
+		n := 1
+		if digits > 0 {
+			n, err = strconv.Atoi(ops[1 : 1+digits])
+			if err != nil {
+				return nil, fmt.Errorf("invalid revision %s", expr)
+			}
+		}
+		ops = ops[1+digits:]
+
+		switch op {
+		case '~':
+			for i := 0; i < n; i++ {
+				if hash, err = r.nthParent(hash, 1); err != nil {
+					return nil, fmt.Errorf("%s: %w", expr, err)
+				}
+			}
+		case '^':
+			if hash, err = r.nthParent(hash, n); err != nil {
+				return nil, fmt.Errorf("%s: %w", expr, err)
+			}
+		default:
+			return nil, fmt.Errorf("invalid revision %s", expr)
+		}
+	}
+
+	return &Revision{Commit: hash}, nil
+}
+
+// parseAssetRevision resolves the part of asset:<id>@<rev> after "asset:"
+func (r *Repository) parseAssetRevision(expr, rest string) (*Revision, error) {
+	idText, revText, found := strings.Cut(rest, "@")
+	switch {
+	case !found:
+		revText = "HEAD"
+	case strings.HasPrefix(revText, "{"):
+		// asset:<id>@{1} is the asset in the current branch one move ago
+		revText = "@" + revText
+	}
+	id, err := strconv.Atoi(idText)
+	if err != nil || id <= 0 || !isAssetID(idText) {
+		return nil, fmt.Errorf("invalid asset ID in %s", expr)
+	}
+	if strings.HasPrefix(revText, "asset:") {
+		return nil, fmt.Errorf("invalid revision %s", expr)
+	}
+
+	rev, err := r.ParseRevision(revText)
+	if err != nil {
+		return nil, err
+	}
+	rev.Asset = id
+
+	commit, err := r.ReadCommit(rev.Commit)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok, err := r.lookupTreePath(commit.Tree, rev.AssetPath()); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, fmt.Errorf("asset %d does not exist in %s", id, revText)
+	}
+	return rev, nil
+}
+
+// splitRevisionSuffixes splits an expression into its base and its ~ and ^ suffixes
+func splitRevisionSuffixes(expr string) (string, string) {
+	depth := 0
+	for i, c := range expr {
+		switch c {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case '~', '^':
+			if depth == 0 {
+				return expr[:i], expr[i:]
+			}
+		}
+	}
+	return expr, ""
+}
+
+// resolveRevisionBase resolves an expression without ~ and ^ suffixes to an object hash
+func (r *Repository) resolveRevisionBase(base string) (string, error) {
+	if name, selector, ok := cutReflogSelector(base); ok {
+		return r.resolveReflogSelector(name, selector)
+	}
+
+	switch {
+	case base == "":
+		return "", fmt.Errorf("empty revision")
+	case base == "HEAD" || base == "@":
+		return r.GetCurrentCommit()
+	case r.BranchExists(base):
+		return r.ReadBranch(base)
+	case r.TagExists(base):
+		return r.ReadTag(base)
+	case isObjectHash(base):
+		if !r.hasObject(base) {
+			return "", fmt.Errorf("unknown revision: %s", base)
+		}
+		return base, nil
+	case isHashPrefix(base):
+		return r.resolveAbbrev(base)
+	}
+	return "", fmt.Errorf("unknown revision: %s", base)
+}
+
+// cutReflogSelector splits "<name>@{<selector>}" into name and selector
+func cutReflogSelector(base string) (string, string, bool) {
+	at := strings.LastIndex(base, "@{")
+	if at < 0 || !strings.HasSuffix(base, "}") {
+		return "", "", false
+	}
+	return base[:at], base[at+2 : len(base)-1], true
+}
+
+// resolveReflogSelector resolves <name>@{<n>} or <name>@{<date>} from the reflog
+func (r *Repository) resolveReflogSelector(name, selector string) (string, error) {
+	if name == "" {
+		current, err := r.GetCurrentBranch()
+		if err != nil {
+			return "", err
+		}
+		name = current
+	}
+	if name != "HEAD" && !r.BranchExists(name) {
+		return "", fmt.Errorf("branch not found: %s", name)
+	}
+	ref := ReflogRef(name)
+
+	if n, err := strconv.Atoi(selector); err == nil {
+		if n < 0 {
+			return "", fmt.Errorf("invalid reflog entry %s@{%s}", name, selector)
+		}
+		return r.ResolveReflog(ref, n)
+	}
+
+	at, err := ParseDate(selector, true)
+	if err != nil {
+		return "", fmt.Errorf("invalid reflog selector %s@{%s}: %w", name, selector, err)
+	}
+	return r.ResolveReflogAt(ref, at)
+}
+
+// ResolveReflogAt returns the value ref had at the given time according to its reflog
+func (r *Repository) ResolveReflogAt(ref string, at time.Time) (string, error) {
+	entries, err := r.ReadReflog(ref)
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range entries {
+		if !entry.Time.After(at) {
+			return entry.New, nil
+		}
+	}
+	if n := len(entries); n > 0 && entries[n-1].Old != "" {
+		return entries[n-1].Old, nil
+	}
+	return "", fmt.Errorf("log for %s does not go back to %s", strings.TrimPrefix(ref, "refs/heads/"), at.Format(time.RFC3339))
+}
+
+// isHashPrefix reports whether s could be an abbreviated object hash
+func isHashPrefix(s string) bool {
+	if len(s) < MinAbbrevLength || len(s) >= 64 {
+		return false
+	}
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveAbbrev expands an abbreviated hash to the one commit or tag it names
+func (r *Repository) resolveAbbrev(prefix string) (string, error) {
+	hashes, err := r.objectsWithPrefix(prefix)
+	if err != nil {
+		return "", err
+	}
+	if len(hashes) == 0 {
+		return "", fmt.Errorf("unknown revision: %s", prefix)
+	}
+
+	var commits, candidates []string
+	for _, hash := range hashes {
+		objType, err := r.storedType(hash)
+		if err != nil {
+			return "", err
+		}
+		if objType == "commit" || objType == "tag" {
+			commits = append(commits, hash)
+		}
+		candidates = append(candidates, fmt.Sprintf("%s (%s)", hash[:12], objType))
+	}
+	switch len(commits) {
+	case 1:
+		return commits[0], nil
+	case 0:
+		if len(hashes) == 1 {
+			return hashes[0], nil
+		}
+	}
+	return "", fmt.Errorf("short hash %s is ambiguous; candidates are:\n  %s", prefix, strings.Join(candidates, "\n  "))
+}
+
+// objectsWithPrefix returns the loose and packed objects whose names start with prefix
+func (r *Repository) objectsWithPrefix(prefix string) ([]string, error) {
+	found := make(map[string]bool)
+
+	dir := filepath.Join(r.Path, ".rdb", "objects", prefix[:2])
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to list objects: %w", err)
+	}
+	for _, entry := range entries {
+		if hash := prefix[:2] + entry.Name(); isObjectHash(hash) && strings.HasPrefix(hash, prefix) {
+			found[hash] = true
+		}
+	}
+
+	packs, err := r.loadPacks()
+	if err != nil {
+		return nil, err
+	}
+	for _, idx := range packs {
+		for _, hash := range idx.withPrefix(prefix) {
+			found[hash] = true
+		}
+	}
+
+	hashes := make([]string, 0, len(found))
+	for hash := range found {
+		hashes = append(hashes, hash)
+	}
+	sort.Strings(hashes)
+	return hashes, nil
+}
+
+// nthParent returns the n-th parent of a commit, or the commit itself for n = 0
+func (r *Repository) nthParent(hash string, n int) (string, error) {
+	if n == 0 {
+		return hash, nil
+	}
+	commit, err := r.ReadCommit(hash)
+	if err != nil {
+		return "", err
+	}
+	parents := commit.ParentHashes()
+	if n > len(parents) {
+		if len(parents) == 0 {
+			return "", fmt.Errorf("commit %s has no parent", hash[:8])
+		}
+		return "", fmt.Errorf("commit %s has only %d parent(s)", hash[:8], len(parents))
+	}
+	return parents[n-1], nil
+}
+
+// lookupTreePath returns the entry at a slash-separated path below a root tree
+func (r *Repository) lookupTreePath(treeHash, p string) (TreeEntry, bool, error) {
+	entry := TreeEntry{Type: "tree", Object: treeHash}
+	for _, name := range strings.Split(p, "/") {
+		if entry.Type != "tree" && entry.Type != "asset" {
+			return TreeEntry{}, false, nil
+		}
+		tree, err := r.ReadTree(entry.Object)
+		if err != nil {
+			return TreeEntry{}, false, err
+		}
+		found := false
+		for _, e := range tree.Entries {
+			if e.Name == name {
+				entry, found = e, true
+				break
+			}
+		}
+		if !found {
+			return TreeEntry{}, false, nil
+		}
+	}
+	return entry, true, nil
+}
+
+var relativeDatePattern = regexp.MustCompile(`^(\d+)[ .](minute|hour|day|week|month|year)s?[ .]ago$`)
+
+// ParseDate accepts YYYY-MM-DD, RFC 3339, "now", "yesterday" and "<n> <unit>s ago".
+// A bare date used as an upper bound covers the whole day.
+func ParseDate(value string, endOfDay bool) (time.Time, error) {
+	value = strings.TrimSpace(strings.ToLower(value))
+	now := time.Now()
+
+	switch value {
+	case "now":
+		return now, nil
+	case "yesterday":
+		return now.AddDate(0, 0, -1), nil
+	}
+
+	if m := relativeDatePattern.FindStringSubmatch(value); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		switch m[2] {
+		case "minute":
+			return now.Add(-time.Duration(n) * time.Minute), nil
+		case "hour":
+			return now.Add(-time.Duration(n) * time.Hour), nil
+		case "day":
+			return now.AddDate(0, 0, -n), nil
+		case "week":
+			return now.AddDate(0, 0, -7*n), nil
+		case "month":
+			return now.AddDate(0, -n, 0), nil
+		default:
+			return now.AddDate(-n, 0, 0), nil
+		}
+	}
+
+	if t, err := time.Parse(time.RFC3339, strings.ToUpper(value)); err == nil {
+		return t, nil
+	}
+
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return t, nil
+}
@@ -0,0 +1,203 @@
+package repo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRevisionAncestors(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+	first := commitFiles(t, repo, map[string]string{"assets/1030002/en.txt": "one"}, "first")
+	second := commitFiles(t, repo, map[string]string{"assets/1030002/en.txt": "two"}, "second")
+
+	// A merge commit whose second parent is a side commit on top of first
+	side, err := repo.WriteObject("commit", &Commit{ID: GenerateID(), Message: "side", Parents: []string{first}})
+	if err != nil {
+		t.Fatalf("Failed to write commit: %v", err)
+	}
+	merge, err := repo.WriteObject("commit", &Commit{ID: GenerateID(), Message: "merge", Parents: []string{second, side}})
+	if err != nil {
+		t.Fatalf("Failed to write commit: %v", err)
+	}
+	if err := repo.CompareAndSwapBranch("main", second, merge, RefChange{Command: "merge"}); err != nil {
+		t.Fatalf("Failed to update branch: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(repo.Path, ".rdb", "refs", "tags"), 0755); err != nil {
+		t.Fatalf("Failed to create tags directory: %v", err)
+	}
+	tagPath, _ := repo.tagRefPath("v1")
+	if err := os.WriteFile(tagPath, []byte(second), 0644); err != nil {
+		t.Fatalf("Failed to write tag: %v", err)
+	}
+
+	for rev, want := range map[string]string{
+		"HEAD":          merge,
+		"@":             merge,
+		"main":          merge,
+		"v1":            second,
+		"v1~":           first,
+		"HEAD~1":        second,
+		"HEAD~2":        first,
+		"HEAD^":         second,
+		"HEAD^2":        side,
+		"HEAD^0":        merge,
+		"HEAD^2~1":      first,
+		"main@{1}~1":    first,
+		"main@{now}":    merge,
+		merge:           merge,
+		second[:10]:     second,
+		"HEAD~1^1~0":    first,
+		"@{1 hour ago}": "",
+	} {
+		got, err := repo.ResolveRevision(rev)
+		if want == "" {
+			if err == nil {
+				t.Errorf("ResolveRevision(%q) = %s, expected an error before the reflog starts", rev, got)
+			}
+			continue
+		}
+		if err != nil || got != want {
+			t.Errorf("ResolveRevision(%q) = %s (%v), want %s", rev, got, err, want)
+		}
+	}
+
+	for _, rev := range []string{"HEAD^3", "HEAD~5", "nope", "main~x", "0000000000", ""} {
+		if _, err := repo.ResolveRevision(rev); err == nil {
+			t.Errorf("Expected ResolveRevision(%q) to fail", rev)
+		}
+	}
+}
+
+func TestParseRevisionAbbreviatedHashes(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+
+	// Write commits until two share a prefix of MinAbbrevLength characters
+	seen := make(map[string]string)
+	var first, second string
+	for i := 0; first == ""; i++ {
+		hash, err := repo.WriteObject("commit", &Commit{ID: fmt.Sprintf("c%d", i), Message: "probe"})
+		if err != nil {
+			t.Fatalf("Failed to write commit: %v", err)
+		}
+		prefix := hash[:MinAbbrevLength]
+		if other, ok := seen[prefix]; ok {
+			first, second = other, hash
+		}
+		seen[prefix] = hash
+	}
+
+	_, err := repo.ResolveRevision(first[:MinAbbrevLength])
+	if err == nil || !strings.Contains(err.Error(), "ambiguous") || !strings.Contains(err.Error(), second[:12]) {
+		t.Errorf("Expected an ambiguity error listing the candidates, got %v", err)
+	}
+
+	// A longer prefix tells them apart, also once they are packed
+	common := 0
+	for first[common] == second[common] {
+		common++
+	}
+	for _, pack := range []bool{false, true} {
+		if pack {
+			if _, err := repo.Repack(RepackOptions{}); err != nil {
+				t.Fatalf("Failed to repack: %v", err)
+			}
+		}
+		for _, want := range []string{first, second} {
+			if got, err := repo.ResolveRevision(want[:common+1]); err != nil || got != want {
+				t.Errorf("ResolveRevision(%q) = %s (%v), want %s (packed: %v)", want[:common+1], got, err, want, pack)
+			}
+		}
+	}
+
+	if _, err := repo.ResolveRevision(first[:MinAbbrevLength-1]); err == nil {
+		t.Error("Expected a prefix shorter than MinAbbrevLength to be rejected")
+	}
+}
+
+func TestParseRevisionAsset(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+	first := commitFiles(t, repo, map[string]string{"assets/1030002/en.txt": "one"}, "first")
+	commitFiles(t, repo, map[string]string{"assets/1000624/image.png": "png"}, "second")
+
+	rev, err := repo.ParseRevision("asset:1030002@HEAD~1")
+	if err != nil {
+		t.Fatalf("Failed to parse asset revision: %v", err)
+	}
+	if rev.Commit != first || rev.Asset != 1030002 || rev.AssetPath() != "assets/1030002" {
+		t.Errorf("Unexpected revision: %+v", rev)
+	}
+	if rev, err := repo.ParseRevision("asset:1000624"); err != nil || rev.Asset != 1000624 {
+		t.Errorf("Expected asset:<id> to default to HEAD, got %+v (%v)", rev, err)
+	}
+
+	for _, expr := range []string{"asset:1000624@HEAD~1", "asset:abc@HEAD", "asset:1030002@nope"} {
+		if _, err := repo.ParseRevision(expr); err == nil {
+			t.Errorf("Expected ParseRevision(%q) to fail", expr)
+		}
+	}
+	if _, err := repo.ResolveRevision("asset:1030002"); err == nil {
+		t.Error("Expected ResolveRevision to reject an asset revision")
+	}
+}
+
+func TestResolveReflogAt(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+	first := commitFiles(t, repo, map[string]string{"assets/1030002/en.txt": "one"}, "first")
+	between := time.Now()
+	time.Sleep(10 * time.Millisecond)
+	second := commitFiles(t, repo, map[string]string{"assets/1030002/en.txt": "two"}, "second")
+
+	if got, err := repo.ResolveReflogAt("refs/heads/main", between); err != nil || got != first {
+		t.Errorf("ResolveReflogAt(between) = %s (%v), want %s", got, err, first)
+	}
+	if got, err := repo.ResolveReflogAt("refs/heads/main", time.Now()); err != nil || got != second {
+		t.Errorf("ResolveReflogAt(now) = %s (%v), want %s", got, err, second)
+	}
+	if _, err := repo.ResolveRevision("main@{2000-01-01}"); err == nil {
+		t.Error("Expected an error for a date before the reflog starts")
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	now := time.Now()
+	for value, want := range map[string]time.Time{
+		"now":         now,
+		"yesterday":   now.AddDate(0, 0, -1),
+		"2 days ago":  now.AddDate(0, 0, -2),
+		"1.week.ago":  now.AddDate(0, 0, -7),
+		"3 hours ago": now.Add(-3 * time.Hour),
+	} {
+		got, err := ParseDate(value, false)
+		if err != nil || got.Sub(want).Abs() > time.Minute {
+			t.Errorf("ParseDate(%q) = %v (%v), want about %v", value, got, err, want)
+		}
+	}
+
+	start, err := ParseDate("2024-05-01", false)
+	if err != nil || !start.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("Unexpected start of day: %v (%v)", start, err)
+	}
+	end, err := ParseDate("2024-05-01", true)
+	if err != nil || end.Day() != 1 || end.Hour() != 23 {
+		t.Errorf("Unexpected end of day: %v (%v)", end, err)
+	}
+	if _, err := ParseDate("someday", false); err == nil {
+		t.Error("Expected an invalid date to be rejected")
+	}
+}
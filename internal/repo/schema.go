@@ -0,0 +1,405 @@
+package repo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Schema is a compiled JSON Schema. The supported keywords are type, enum, const,
+// properties, required, additionalProperties, items, minItems, maxItems, uniqueItems,
+// minLength, maxLength, pattern, minimum, maximum, exclusiveMinimum, exclusiveMaximum,
+// allOf, anyOf, oneOf and not; other keywords are ignored.
+type Schema struct {
+	root map[string]interface{}
+}
+
+// SchemaViolation is a single schema error, located by JSON pointer
+type SchemaViolation struct {
+	Pointer string // JSON pointer of the offending value ("" for the document)
+	Message string
+}
+
+// CompileSchema parses a JSON Schema document
+func CompileSchema(data []byte) (*Schema, error) {
+	var root map[string]interface{}
+	if err := decodeJSON(data, &root); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	if err := checkSchemaPatterns(root); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	return &Schema{root: root}, nil
+}
+
+// Validate checks a decoded JSON value (as produced by decodeJSON) against the schema
+func (s *Schema) Validate(value interface{}) []SchemaViolation {
+	var violations []SchemaViolation
+	validateSchema(s.root, value, "", &violations)
+	return violations
+}
+
+// decodeJSON decodes data keeping numbers as json.Number so integers can be told apart
+func decodeJSON(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(bytes.TrimPrefix(data, utf8BOM)))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err == nil {
+		return fmt.Errorf("unexpected data after top-level value")
+	}
+	return nil
+}
+
+// checkSchemaPatterns verifies that every "pattern" keyword is a valid regular expression
+func checkSchemaPatterns(node interface{}) error {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		for key, value := range n {
+			if pattern, ok := value.(string); ok && key == "pattern" {
+				if _, err := regexp.Compile(pattern); err != nil {
+					return fmt.Errorf("bad pattern %q: %w", pattern, err)
+				}
+				continue
+			}
+			if err := checkSchemaPatterns(value); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, value := range n {
+			if err := checkSchemaPatterns(value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateSchema(schema map[string]interface{}, value interface{}, pointer string, out *[]SchemaViolation) {
+	report := func(format string, args ...interface{}) {
+		*out = append(*out, SchemaViolation{Pointer: pointer, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if t, ok := schema["type"]; ok && !matchesType(t, value) {
+		report("expected %s, got %s", describeType(t), jsonTypeName(value))
+		return
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, candidate := range enum {
+			if jsonEqual(candidate, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			report("value must be one of %s", compactJSON(enum))
+		}
+	}
+	if c, ok := schema["const"]; ok && !jsonEqual(c, value) {
+		report("value must be %s", compactJSON(c))
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		validateObject(schema, v, pointer, out)
+	case []interface{}:
+		validateArray(schema, v, pointer, out)
+	case string:
+		length := utf8.RuneCountInString(v)
+		if n, ok := schemaNumber(schema, "minLength"); ok && float64(length) < n {
+			report("string is shorter than %v characters", n)
+		}
+		if n, ok := schemaNumber(schema, "maxLength"); ok && float64(length) > n {
+			report("string is longer than %v characters", n)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(v) {
+				report("string does not match pattern %q", pattern)
+			}
+		}
+	case json.Number:
+		f, _ := v.Float64()
+		if n, ok := schemaNumber(schema, "minimum"); ok && f < n {
+			report("value must be at least %v", n)
+		}
+		if n, ok := schemaNumber(schema, "maximum"); ok && f > n {
+			report("value must be at most %v", n)
+		}
+		if n, ok := schemaNumber(schema, "exclusiveMinimum"); ok && f <= n {
+			report("value must be greater than %v", n)
+		}
+		if n, ok := schemaNumber(schema, "exclusiveMaximum"); ok && f >= n {
+			report("value must be less than %v", n)
+		}
+	}
+
+	if all, ok := schema["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			if subSchema, ok := sub.(map[string]interface{}); ok {
+				validateSchema(subSchema, value, pointer, out)
+			}
+		}
+	}
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok && countMatches(anyOf, value, pointer) == 0 {
+		report("value does not match any of the allowed schemas")
+	}
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		if n := countMatches(oneOf, value, pointer); n != 1 {
+			report("value must match exactly one schema, matched %d", n)
+		}
+	}
+	if not, ok := schema["not"].(map[string]interface{}); ok && countMatches([]interface{}{not}, value, pointer) == 1 {
+		report("value must not match the excluded schema")
+	}
+}
+
+func validateObject(schema map[string]interface{}, obj map[string]interface{}, pointer string, out *[]SchemaViolation) {
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, name := range required {
+			if key, ok := name.(string); ok {
+				if _, present := obj[key]; !present {
+					*out = append(*out, SchemaViolation{Pointer: pointer, Message: fmt.Sprintf("missing required property %q", key)})
+				}
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		childPointer := pointer + "/" + escapePointer(key)
+		if propSchema, ok := properties[key].(map[string]interface{}); ok {
+			validateSchema(propSchema, obj[key], childPointer, out)
+			continue
+		}
+		if _, declared := properties[key]; declared {
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				*out = append(*out, SchemaViolation{Pointer: childPointer, Message: fmt.Sprintf("property %q is not allowed", key)})
+			}
+		case map[string]interface{}:
+			validateSchema(additional, obj[key], childPointer, out)
+		}
+	}
+}
+
+func validateArray(schema map[string]interface{}, arr []interface{}, pointer string, out *[]SchemaViolation) {
+	if n, ok := schemaNumber(schema, "minItems"); ok && float64(len(arr)) < n {
+		*out = append(*out, SchemaViolation{Pointer: pointer, Message: fmt.Sprintf("array must have at least %v items", n)})
+	}
+	if n, ok := schemaNumber(schema, "maxItems"); ok && float64(len(arr)) > n {
+		*out = append(*out, SchemaViolation{Pointer: pointer, Message: fmt.Sprintf("array must have at most %v items", n)})
+	}
+	if unique, _ := schema["uniqueItems"].(bool); unique {
+		for i := range arr {
+			for j := 0; j < i; j++ {
+				if jsonEqual(arr[i], arr[j]) {
+					*out = append(*out, SchemaViolation{Pointer: pointer + "/" + strconv.Itoa(i), Message: fmt.Sprintf("duplicate of item %d", j)})
+					break
+				}
+			}
+		}
+	}
+	if items, ok := schema["items"].(map[string]interface{}); ok {
+		for i, item := range arr {
+			validateSchema(items, item, pointer+"/"+strconv.Itoa(i), out)
+		}
+	}
+}
+
+// countMatches returns how many of the schemas value satisfies
+func countMatches(schemas []interface{}, value interface{}, pointer string) int {
+	n := 0
+	for _, sub := range schemas {
+		subSchema, ok := sub.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var violations []SchemaViolation
+		validateSchema(subSchema, value, pointer, &violations)
+		if len(violations) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func matchesType(t interface{}, value interface{}) bool {
+	switch t := t.(type) {
+	case string:
+		return matchesTypeName(t, value)
+	case []interface{}:
+		for _, name := range t {
+			if s, ok := name.(string); ok && matchesTypeName(s, value) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func matchesTypeName(name string, value interface{}) bool {
+	actual := jsonTypeName(value)
+	switch name {
+	case "number":
+		return actual == "integer" || actual == "number"
+	default:
+		return actual == name
+	}
+}
+
+func describeType(t interface{}) string {
+	if names, ok := t.([]interface{}); ok {
+		parts := make([]string, 0, len(names))
+		for _, name := range names {
+			parts = append(parts, fmt.Sprint(name))
+		}
+		return strings.Join(parts, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+// jsonTypeName returns the JSON Schema type of a decoded value
+func jsonTypeName(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if f, err := v.Float64(); err == nil && f == math.Trunc(f) && !strings.ContainsAny(v.String(), ".eE") {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func schemaNumber(schema map[string]interface{}, key string) (float64, bool) {
+	n, ok := schema[key].(json.Number)
+	if !ok {
+		return 0, false
+	}
+	f, err := n.Float64()
+	return f, err == nil
+}
+
+// jsonEqual compares decoded JSON values, treating numbers by value
+func jsonEqual(a, b interface{}) bool {
+	an, aok := a.(json.Number)
+	bn, bok := b.(json.Number)
+	if aok && bok {
+		af, _ := an.Float64()
+		bf, _ := bn.Float64()
+		return af == bf
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func compactJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// escapePointer escapes a key for use in a JSON pointer
+func escapePointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
+
+// jsonLines maps the JSON pointer of every value in data to the line it starts on
+func jsonLines(data []byte) map[string]int {
+	lines := make(map[string]int)
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	lineAt := func(offset int64) int {
+		i := int(offset)
+		for i < len(data) && strings.IndexByte(" \t\r\n:,", data[i]) >= 0 {
+			i++
+		}
+		return bytes.Count(data[:i], []byte("\n")) + 1
+	}
+
+	var walk func(pointer string) error
+	walk = func(pointer string) error {
+		lines[pointer] = lineAt(dec.InputOffset())
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'):
+			for dec.More() {
+				keyOffset := dec.InputOffset()
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				name, _ := key.(string)
+				childPointer := pointer + "/" + escapePointer(name)
+				if err := walk(childPointer); err != nil {
+					return err
+				}
+				// Report object members on the line of their key
+				lines[childPointer] = lineAt(keyOffset)
+			}
+			_, err = dec.Token()
+		case json.Delim('['):
+			for i := 0; dec.More(); i++ {
+				if err := walk(pointer + "/" + strconv.Itoa(i)); err != nil {
+					return err
+				}
+			}
+			_, err = dec.Token()
+		}
+		return err
+	}
+	walk("")
+
+	return lines
+}
+
+// jsonErrorLine returns the line a decoding error occurred on, or 0 if unknown
+func jsonErrorLine(data []byte, err error) int {
+	var offset int64
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		offset = e.Offset
+	case *json.UnmarshalTypeError:
+		offset = e.Offset
+	default:
+		return 0
+	}
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
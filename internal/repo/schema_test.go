@@ -0,0 +1,83 @@
+package repo
+
+import (
+	"testing"
+)
+
+func TestSchemaValidate(t *testing.T) {
+	schema, err := CompileSchema([]byte(`{
+		"type": "object",
+		"required": ["name", "level"],
+		"additionalProperties": false,
+		"properties": {
+			"name": {"type": "string", "pattern": "^Q_", "maxLength": 8},
+			"level": {"type": "integer", "minimum": 1, "maximum": 60},
+			"tags": {"type": "array", "items": {"enum": ["main", "side"]}, "uniqueItems": true},
+			"reward": {"oneOf": [{"type": "integer"}, {"type": "string"}]}
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("Failed to compile schema: %v", err)
+	}
+
+	tests := []struct {
+		doc      string
+		pointers []string
+	}{
+		{`{"name": "Q_intro", "level": 3, "tags": ["main"], "reward": 10}`, nil},
+		{`{"name": "Q_intro"}`, []string{""}},
+		{`{"name": "intro", "level": 3}`, []string{"/name"}},
+		{`{"name": "Q_intro", "level": 3.5}`, []string{"/level"}},
+		{`{"name": "Q_intro", "level": 61}`, []string{"/level"}},
+		{`{"name": "Q_intro", "level": 3, "tags": ["main", "main", "daily"]}`, []string{"/tags/1", "/tags/2"}},
+		{`{"name": "Q_intro", "level": 3, "extra": true}`, []string{"/extra"}},
+		{`{"name": "Q_intro", "level": 3, "reward": true}`, []string{"/reward"}},
+		{`[]`, []string{""}},
+	}
+
+	for _, tt := range tests {
+		var doc interface{}
+		if err := decodeJSON([]byte(tt.doc), &doc); err != nil {
+			t.Fatalf("Failed to decode %s: %v", tt.doc, err)
+		}
+		violations := schema.Validate(doc)
+		if len(violations) != len(tt.pointers) {
+			t.Errorf("Validate(%s) = %v, want violations at %v", tt.doc, violations, tt.pointers)
+			continue
+		}
+		for i, v := range violations {
+			if v.Pointer != tt.pointers[i] {
+				t.Errorf("Validate(%s) violation %d at %q, want %q", tt.doc, i, v.Pointer, tt.pointers[i])
+			}
+		}
+	}
+
+	if _, err := CompileSchema([]byte(`{"pattern": "("}`)); err == nil {
+		t.Error("Expected invalid pattern to be rejected")
+	}
+}
+
+func TestJSONLines(t *testing.T) {
+	data := []byte("{\n  \"type\": \"string\",\n  \"tags\": [\n    \"a\",\n    \"b\"\n  ],\n  \"attributes\": {\"lang\": \"en\"}\n}\n")
+	lines := jsonLines(data)
+
+	want := map[string]int{
+		"":                 1,
+		"/type":            2,
+		"/tags":            3,
+		"/tags/1":          5,
+		"/attributes/lang": 7,
+	}
+	for pointer, line := range want {
+		if lines[pointer] != line {
+			t.Errorf("line of %q = %d, want %d", pointer, lines[pointer], line)
+		}
+	}
+
+	var doc interface{}
+	bad := []byte("{\n  \"type\": \"string\",\n}\n")
+	err := decodeJSON(bad, &doc)
+	if line := jsonErrorLine(bad, err); line != 3 {
+		t.Errorf("Expected syntax error on line 3, got %d (%v)", line, err)
+	}
+}
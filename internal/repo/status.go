@@ -0,0 +1,301 @@
+package repo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Status codes reported for each side of a StatusEntry
+const (
+	StatusUnmodified byte = ' '
+	StatusAdded      byte = 'A'
+	StatusModified   byte = 'M'
+	StatusDeleted    byte = 'D'
+	StatusRenamed    byte = 'R'
+	StatusUnmerged   byte = 'U'
+	StatusUntracked  byte = '?'
+)
+
+// StatusEntry describes how a single path differs between HEAD, the index and the working tree
+type StatusEntry struct {
+	Staged   byte   // HEAD tree vs index
+	Unstaged byte   // index vs working tree
+	Path     string // slash-separated, relative to the repository root
+	OrigPath string // source path of a rename
+}
+
+// Status is the result of comparing HEAD, the index and the working tree
+type Status struct {
+	Entries []StatusEntry
+	// Refreshed is set when unchanged files had their cached stat data
+	// updated in the index, so callers may want to save it
+	Refreshed bool
+}
+
+// Clean reports whether there is nothing to commit and no untracked file
+func (s *Status) Clean() bool {
+	return len(s.Entries) == 0
+}
+
+// HashFile computes the blob hash of a file without storing it
+func HashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// Status compares the HEAD tree, the given index and the files under assets/.
+// Files whose size and modification time match the index are not rehashed, and
+// untracked files matched by .rdbignore are left out.
+func (r *Repository) Status(idx *Index) (*Status, error) {
+	head := map[string]TreeEntry{}
+	if commitHash, err := r.GetCurrentCommit(); err == nil && commitHash != "" {
+		commit, err := r.ReadCommit(commitHash)
+		if err != nil {
+			return nil, err
+		}
+		head, err = r.flattenWorkTree(commit.Tree)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	status := &Status{}
+	entries := make(map[string]*StatusEntry)
+	get := func(path string) *StatusEntry {
+		e, ok := entries[path]
+		if !ok {
+			e = &StatusEntry{Staged: StatusUnmodified, Unstaged: StatusUnmodified, Path: path}
+			entries[path] = e
+		}
+		return e
+	}
+
+	// HEAD vs index
+	indexed := make(map[string]IndexEntry, len(idx.Entries))
+	for _, entry := range idx.Entries {
+		indexed[entry.Path] = entry
+		old, ok := head[entry.Path]
+		switch {
+		case entry.Conflict != nil:
+			e := get(entry.Path)
+			e.Staged = StatusUnmerged
+			e.Unstaged = StatusUnmerged
+		case !ok:
+			get(entry.Path).Staged = StatusAdded
+		case old.Object != entry.Object:
+			get(entry.Path).Staged = StatusModified
+		}
+	}
+	for path := range head {
+		if _, ok := indexed[path]; !ok {
+			get(path).Staged = StatusDeleted
+		}
+	}
+
+	// Index vs working tree
+	working, err := r.scanWorkingTree()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range idx.Entries {
+		entry := &idx.Entries[i]
+		if entry.Conflict != nil {
+			continue
+		}
+		info, ok := working[entry.Path]
+		if !ok {
+			get(entry.Path).Unstaged = StatusDeleted
+			continue
+		}
+		if info.Size() == entry.Size && info.ModTime().Equal(entry.ModTime) {
+			continue
+		}
+
+		hash, err := HashFile(filepath.Join(r.Path, filepath.FromSlash(entry.Path)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash %s: %w", entry.Path, err)
+		}
+		if hash != entry.Object {
+			get(entry.Path).Unstaged = StatusModified
+			continue
+		}
+
+		// Content unchanged; remember the new stat data so it is not hashed again
+		entry.Size = info.Size()
+		entry.ModTime = info.ModTime()
+		status.Refreshed = true
+	}
+
+	// Untracked files matching .rdbignore rules are not reported
+	ignore := r.NewIgnoreMatcher()
+	untracked := make(map[string]bool)
+	for path := range working {
+		if _, ok := indexed[path]; !ok && !ignore.Match(path, false) {
+			untracked[path] = true
+		}
+	}
+
+	if err := r.detectRenames(entries, untracked, head, indexed, working); err != nil {
+		return nil, err
+	}
+
+	for _, e := range entries {
+		status.Entries = append(status.Entries, *e)
+	}
+	for path := range untracked {
+		status.Entries = append(status.Entries, StatusEntry{Staged: StatusUntracked, Unstaged: StatusUntracked, Path: path})
+	}
+	sort.SliceStable(status.Entries, func(i, j int) bool {
+		a, b := status.Entries[i], status.Entries[j]
+		if (a.Staged == StatusUntracked) != (b.Staged == StatusUntracked) {
+			return b.Staged == StatusUntracked
+		}
+		return a.Path < b.Path
+	})
+
+	return status, nil
+}
+
+// detectRenames pairs deletions with additions of identical content
+func (r *Repository) detectRenames(entries map[string]*StatusEntry, untracked map[string]bool, head map[string]TreeEntry, indexed map[string]IndexEntry, working map[string]fs.FileInfo) error {
+	paths := make([]string, 0, len(entries))
+	for path := range entries {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	// Staged renames: deleted from HEAD, added to the index with the same blob
+	stagedDeleted := make(map[string]string)
+	for _, path := range paths {
+		if entries[path].Staged == StatusDeleted {
+			if _, taken := stagedDeleted[head[path].Object]; !taken {
+				stagedDeleted[head[path].Object] = path
+			}
+		}
+	}
+	for _, path := range paths {
+		e, ok := entries[path]
+		if !ok || e.Staged != StatusAdded {
+			continue
+		}
+		orig, ok := stagedDeleted[indexed[path].Object]
+		if !ok {
+			continue
+		}
+		delete(stagedDeleted, indexed[path].Object)
+		e.Staged = StatusRenamed
+		e.OrigPath = orig
+		if old := entries[orig]; old.Unstaged == StatusUnmodified {
+			delete(entries, orig)
+		} else {
+			old.Staged = StatusUnmodified
+		}
+	}
+
+	// Unstaged renames: missing from the working tree, present as an untracked file with the same content
+	type candidate struct {
+		path string
+		size int64
+	}
+	var missing []candidate
+	for _, path := range paths {
+		if e, ok := entries[path]; ok && e.Unstaged == StatusDeleted {
+			missing = append(missing, candidate{path: path, size: indexed[path].Size})
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	untrackedPaths := make([]string, 0, len(untracked))
+	for path := range untracked {
+		untrackedPaths = append(untrackedPaths, path)
+	}
+	sort.Strings(untrackedPaths)
+
+	for _, path := range untrackedPaths {
+		size := working[path].Size()
+		hash := ""
+		for i, m := range missing {
+			if m.size != size {
+				continue
+			}
+			if hash == "" {
+				var err error
+				hash, err = HashFile(filepath.Join(r.Path, filepath.FromSlash(path)))
+				if err != nil {
+					return fmt.Errorf("failed to hash %s: %w", path, err)
+				}
+			}
+			if hash != indexed[m.path].Object {
+				continue
+			}
+			delete(untracked, path)
+			entries[path] = &StatusEntry{
+				Staged:   StatusUnmodified,
+				Unstaged: StatusRenamed,
+				Path:     path,
+				OrigPath: m.path,
+			}
+			if old := entries[m.path]; old.Staged == StatusUnmodified {
+				delete(entries, m.path)
+			} else {
+				old.Unstaged = StatusUnmodified
+			}
+			missing = append(missing[:i], missing[i+1:]...)
+			break
+		}
+	}
+
+	return nil
+}
+
+// scanWorkingTree returns the files under assets/ keyed by slash-separated path
+func (r *Repository) scanWorkingTree() (map[string]fs.FileInfo, error) {
+	files := make(map[string]fs.FileInfo)
+	assetsPath := filepath.Join(r.Path, "assets")
+
+	err := filepath.WalkDir(assetsPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == assetsPath {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		relPath, err := r.RelPath(path)
+		if err != nil {
+			return err
+		}
+		files[relPath] = info
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan working tree: %w", err)
+	}
+
+	return files, nil
+}
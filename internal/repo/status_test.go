@@ -0,0 +1,128 @@
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func statusCodes(t *testing.T, repo *Repository) map[string]string {
+	t.Helper()
+
+	idx, err := repo.LoadIndex()
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+	status, err := repo.Status(idx)
+	if err != nil {
+		t.Fatalf("Failed to compute status: %v", err)
+	}
+
+	codes := make(map[string]string)
+	for _, e := range status.Entries {
+		codes[e.Path] = string([]byte{e.Staged, e.Unstaged})
+	}
+	return codes
+}
+
+func TestStatusClean(t *testing.T) {
+	tempDir := t.TempDir()
+	repo := NewRepository(tempDir)
+	if err := repo.Init("tree", []string{"text"}); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+
+	commitFiles(t, repo, map[string]string{"assets/1030002/en.txt": "hello"}, "first")
+
+	if codes := statusCodes(t, repo); len(codes) != 0 {
+		t.Errorf("Expected clean status, got %v", codes)
+	}
+}
+
+func TestStatusReportsChanges(t *testing.T) {
+	tempDir := t.TempDir()
+	repo := NewRepository(tempDir)
+	if err := repo.Init("tree", []string{"text"}); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+
+	commitFiles(t, repo, map[string]string{
+		"assets/1030002/en.txt": "hello",
+		"assets/1030002/fr.txt": "bonjour",
+		"assets/1030002/de.txt": "hallo",
+	}, "first")
+
+	assetDir := filepath.Join(tempDir, "assets", "1030002")
+
+	// Modified in the working tree
+	if err := os.WriteFile(filepath.Join(assetDir, "en.txt"), []byte("hello world"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	// Deleted from the working tree
+	if err := os.Remove(filepath.Join(assetDir, "fr.txt")); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+	// Renamed in the working tree
+	if err := os.Rename(filepath.Join(assetDir, "de.txt"), filepath.Join(assetDir, "de-DE.txt")); err != nil {
+		t.Fatalf("Failed to rename file: %v", err)
+	}
+	// Untracked
+	if err := os.WriteFile(filepath.Join(assetDir, "es.txt"), []byte("hola"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	codes := statusCodes(t, repo)
+	expected := map[string]string{
+		"assets/1030002/en.txt":    " M",
+		"assets/1030002/fr.txt":    " D",
+		"assets/1030002/de-DE.txt": " R",
+		"assets/1030002/es.txt":    "??",
+	}
+	for path, code := range expected {
+		if codes[path] != code {
+			t.Errorf("Expected %q for %s, got %q", code, path, codes[path])
+		}
+	}
+	if len(codes) != len(expected) {
+		t.Errorf("Expected %d entries, got %v", len(expected), codes)
+	}
+}
+
+func TestStatusStagedRename(t *testing.T) {
+	tempDir := t.TempDir()
+	repo := NewRepository(tempDir)
+	if err := repo.Init("tree", []string{"text"}); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+
+	commitFiles(t, repo, map[string]string{"assets/1030002/en.txt": "hello"}, "first")
+
+	assetDir := filepath.Join(tempDir, "assets", "1030002")
+	if err := os.Rename(filepath.Join(assetDir, "en.txt"), filepath.Join(assetDir, "en-US.txt")); err != nil {
+		t.Fatalf("Failed to rename file: %v", err)
+	}
+
+	idx, _ := repo.LoadIndex()
+	idx.Remove("assets/1030002/en.txt")
+	entry, err := repo.StageFile("assets/1030002/en-US.txt")
+	if err != nil {
+		t.Fatalf("Failed to stage file: %v", err)
+	}
+	idx.Add(entry)
+	if err := repo.SaveIndex(idx); err != nil {
+		t.Fatalf("Failed to save index: %v", err)
+	}
+
+	status, err := repo.Status(idx)
+	if err != nil {
+		t.Fatalf("Failed to compute status: %v", err)
+	}
+	if len(status.Entries) != 1 {
+		t.Fatalf("Expected a single rename entry, got %+v", status.Entries)
+	}
+
+	e := status.Entries[0]
+	if e.Staged != StatusRenamed || e.OrigPath != "assets/1030002/en.txt" || e.Path != "assets/1030002/en-US.txt" {
+		t.Errorf("Unexpected rename entry: %+v", e)
+	}
+}
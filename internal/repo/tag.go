@@ -0,0 +1,159 @@
+package repo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Tag is an annotated tag object. Lightweight tags are plain refs below refs/tags
+// that point straight at a commit; annotated tags point at a tag object instead.
+type Tag struct {
+	Object    string    `json:"object"` // SHA256 of the tagged object
+	Type      string    `json:"type"`   // type of the tagged object, normally "commit"
+	Name      string    `json:"tag"`
+	Tagger    string    `json:"tagger"`
+	Timestamp time.Time `json:"timestamp"`
+	Message   string    `json:"message"`
+}
+
+// tagRefPath returns the file holding the object a tag points to. The name is
+// validated so that it cannot point outside refs/tags.
+func (r *Repository) tagRefPath(name string) (string, error) {
+	if err := ValidateRefName(name); err != nil {
+		return "", err
+	}
+	return filepath.Join(r.Path, ".rdb", "refs", "tags", filepath.FromSlash(name)), nil
+}
+
+// TagExists reports whether a tag with the given name exists
+func (r *Repository) TagExists(name string) bool {
+	refPath, err := r.tagRefPath(name)
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(refPath)
+	return err == nil && !info.IsDir()
+}
+
+// ReadTag returns the object hash a tag points to: a commit for a lightweight tag,
+// a tag object for an annotated one
+func (r *Repository) ReadTag(name string) (string, error) {
+	refPath, err := r.tagRefPath(name)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(refPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("tag not found: %s", name)
+		}
+		return "", fmt.Errorf("failed to read tag ref: %w", err)
+	}
+	hash := strings.TrimSpace(string(data))
+	if !isObjectHash(hash) {
+		return "", fmt.Errorf("tag %s has invalid object name %q", name, hash)
+	}
+	return hash, nil
+}
+
+// ReadTagObject reads an annotated tag object
+func (r *Repository) ReadTagObject(hash string) (*Tag, error) {
+	objType, data, err := r.readObject(hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tag object: %w", err)
+	}
+
+	if objType != "tag" {
+		return nil, fmt.Errorf("object %s is not a tag", hash)
+	}
+
+	var tag Tag
+	if err := json.Unmarshal(data, &tag); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tag: %w", err)
+	}
+
+	return &tag, nil
+}
+
+// PeelTag follows annotated tag objects until it reaches the object they tag.
+// Any other object is returned unchanged.
+func (r *Repository) PeelTag(hash string) (string, error) {
+	for {
+		if !isObjectHash(hash) {
+			return "", fmt.Errorf("invalid object name %q", hash)
+		}
+		objType, err := r.storedType(hash)
+		if err != nil {
+			return "", err
+		}
+		if objType != "tag" {
+			return hash, nil
+		}
+		tag, err := r.ReadTagObject(hash)
+		if err != nil {
+			return "", err
+		}
+		hash = tag.Object
+	}
+}
+
+// ListTags returns all tag names in sorted order
+func (r *Repository) ListTags() ([]string, error) {
+	return r.listRefs(filepath.Join(r.Path, ".rdb", "refs", "tags"))
+}
+
+// CreateTag points a new tag at target, a commit or an annotated tag object for
+// that commit. Tags are meant to stay put, so an existing tag is only replaced with force.
+func (r *Repository) CreateTag(name, target string, force bool) error {
+	refPath, err := r.tagRefPath(name)
+	if err != nil {
+		return err
+	}
+	commitHash, err := r.PeelTag(target)
+	if err != nil {
+		return fmt.Errorf("invalid tag target: %w", err)
+	}
+	if _, err := r.ReadCommit(commitHash); err != nil {
+		return fmt.Errorf("invalid tag target: %w", err)
+	}
+
+	var old *string
+	if !force {
+		old = new(string)
+	}
+	if err := r.updateRef("refs/tags/"+name, refPath, old, target, RefChange{}); err != nil {
+		if errors.Is(err, ErrRefChanged) {
+			return fmt.Errorf("tag already exists: %s", name)
+		}
+		return err
+	}
+	return nil
+}
+
+// DeleteTag removes a tag. An annotated tag's object stays in the store until gc.
+func (r *Repository) DeleteTag(name string) error {
+	refPath, err := r.tagRefPath(name)
+	if err != nil {
+		return err
+	}
+	if !r.TagExists(name) {
+		return fmt.Errorf("tag not found: %s", name)
+	}
+
+	lock, err := r.acquireLock("refs/tags/"+name, r.lockTimeout())
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	if err := os.Remove(refPath); err != nil {
+		return fmt.Errorf("failed to delete tag: %w", err)
+	}
+	r.removeEmptyRefDirs(filepath.Dir(refPath))
+	return nil
+}
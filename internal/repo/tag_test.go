@@ -0,0 +1,190 @@
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTagLifecycle(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+	first := commitFiles(t, repo, map[string]string{"assets/1030002/en.txt": "one"}, "first")
+	second := commitFiles(t, repo, map[string]string{"assets/1030002/en.txt": "two"}, "second")
+
+	if err := repo.CreateTag("v1", first, false); err != nil {
+		t.Fatalf("Failed to create lightweight tag: %v", err)
+	}
+	tagHash, err := repo.WriteObject("tag", &Tag{Object: second, Type: "commit", Name: "release/v2", Tagger: DefaultAuthor, Timestamp: time.Now(), Message: "Second release"})
+	if err != nil {
+		t.Fatalf("Failed to write tag object: %v", err)
+	}
+	if err := repo.CreateTag("release/v2", tagHash, false); err != nil {
+		t.Fatalf("Failed to create annotated tag: %v", err)
+	}
+
+	tags, err := repo.ListTags()
+	if err != nil {
+		t.Fatalf("Failed to list tags: %v", err)
+	}
+	if len(tags) != 2 || tags[0] != "release/v2" || tags[1] != "v1" {
+		t.Errorf("Unexpected tags: %v", tags)
+	}
+
+	tag, err := repo.ReadTagObject(tagHash)
+	if err != nil || tag.Object != second || tag.Message != "Second release" {
+		t.Errorf("Unexpected tag object: %+v (%v)", tag, err)
+	}
+	if peeled, err := repo.PeelTag(tagHash); err != nil || peeled != second {
+		t.Errorf("PeelTag = %s (%v), want %s", peeled, err, second)
+	}
+	for rev, want := range map[string]string{"v1": first, "release/v2": second, "release/v2~1": first, tagHash[:12]: second} {
+		if got, err := repo.ResolveRevision(rev); err != nil || got != want {
+			t.Errorf("ResolveRevision(%q) = %s (%v), want %s", rev, got, err, want)
+		}
+	}
+
+	// Tags stay put unless forced
+	if err := repo.CreateTag("v1", second, false); err == nil {
+		t.Error("Expected error when moving an existing tag without force")
+	}
+	if err := repo.CreateTag("v1", second, true); err != nil {
+		t.Fatalf("Failed to move tag with force: %v", err)
+	}
+	if got, _ := repo.ReadTag("v1"); got != second {
+		t.Errorf("Expected v1 to point at %s, got %s", second, got)
+	}
+	if entries, _ := repo.ReadReflog("refs/tags/v1"); len(entries) != 0 {
+		t.Errorf("Expected tags to keep no reflog, got %+v", entries)
+	}
+
+	tree, _ := repo.ReadCommit(first)
+	if err := repo.CreateTag("tree", tree.Tree, false); err == nil {
+		t.Error("Expected error when tagging a tree")
+	}
+	if err := repo.CreateTag("bad~name", first, false); err == nil {
+		t.Error("Expected error for an invalid tag name")
+	}
+
+	if err := repo.DeleteTag("release/v2"); err != nil {
+		t.Fatalf("Failed to delete tag: %v", err)
+	}
+	if repo.TagExists("release/v2") {
+		t.Error("Expected tag to be deleted")
+	}
+	if _, err := os.Stat(filepath.Join(repo.Path, ".rdb", "refs", "tags", "release")); !os.IsNotExist(err) {
+		t.Error("Expected empty tag directory to be removed")
+	}
+	if _, err := os.Stat(filepath.Join(repo.Path, ".rdb", "refs", "tags")); err != nil {
+		t.Errorf("Expected refs/tags to be kept: %v", err)
+	}
+	if err := repo.DeleteTag("release/v2"); err == nil {
+		t.Error("Expected error when deleting a missing tag")
+	}
+}
+
+func TestTagsKeepObjectsAlive(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+	initial, _ := repo.GetCurrentCommit()
+	shipped := commitFiles(t, repo, map[string]string{"assets/1030002/en.txt": "shipped"}, "shipped")
+
+	// Rewind the branch so that only the annotated tag still reaches the shipped commit
+	tagHash, err := repo.WriteObject("tag", &Tag{Object: shipped, Type: "commit", Name: "v1", Tagger: DefaultAuthor, Timestamp: time.Now(), Message: "Ship it"})
+	if err != nil {
+		t.Fatalf("Failed to write tag object: %v", err)
+	}
+	if err := repo.CreateTag("v1", tagHash, false); err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+	if err := repo.CompareAndSwapBranch("main", shipped, initial, RefChange{Command: "reset"}); err != nil {
+		t.Fatalf("Failed to reset branch: %v", err)
+	}
+	if _, err := repo.ExpireReflogs(time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("Failed to expire reflogs: %v", err)
+	}
+
+	report, err := repo.GC(GCOptions{})
+	if err != nil {
+		t.Fatalf("GC failed: %v", err)
+	}
+	if len(report.Pruned) != 0 {
+		t.Errorf("Expected the tag to keep every object alive, pruned %+v", report.Pruned)
+	}
+
+	if _, err := repo.Repack(RepackOptions{Window: 10, Depth: 50}); err != nil {
+		t.Fatalf("Failed to repack: %v", err)
+	}
+	if _, err := os.Stat(repo.objectPath(tagHash)); !os.IsNotExist(err) {
+		t.Error("Expected the tag object to be packed")
+	}
+	if tag, err := repo.ReadTagObject(tagHash); err != nil || tag.Object != shipped {
+		t.Errorf("Failed to read packed tag: %+v (%v)", tag, err)
+	}
+
+	fsck, err := repo.Fsck()
+	if err != nil {
+		t.Fatalf("Fsck failed: %v", err)
+	}
+	if len(fsck.Issues) != 0 || fsck.Reachable != fsck.Objects {
+		t.Errorf("Expected every object to be reachable, got %+v", fsck)
+	}
+
+	// A tag ref at a missing object is reported
+	brokenPath, _ := repo.tagRefPath("broken")
+	if err := os.WriteFile(brokenPath, []byte(strings.Repeat("ab", 32)), 0644); err != nil {
+		t.Fatalf("Failed to write tag: %v", err)
+	}
+	if fsck, _ := repo.Fsck(); fsck.Healthy() {
+		t.Error("Expected a tag at a missing object to be reported")
+	}
+}
+
+func TestTagNamesAndDamagedRefs(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+	head, _ := repo.GetCurrentCommit()
+
+	for _, name := range []string{"../heads/main", "../../config.json", "v1/../../heads/main"} {
+		if repo.TagExists(name) {
+			t.Errorf("TagExists(%q) reported a file outside refs/tags", name)
+		}
+		if _, err := repo.ReadTag(name); err == nil {
+			t.Errorf("Expected ReadTag(%q) to fail", name)
+		}
+		if err := repo.DeleteTag(name); err == nil {
+			t.Errorf("Expected DeleteTag(%q) to fail", name)
+		}
+		if err := repo.CreateTag(name, head, true); err == nil {
+			t.Errorf("Expected CreateTag(%q) to fail", name)
+		}
+	}
+	if !repo.BranchExists("main") {
+		t.Fatal("Expected the main branch to survive")
+	}
+
+	// Empty and truncated refs are reported instead of being looked up
+	for _, content := range []string{"", "ab"} {
+		refPath, _ := repo.tagRefPath("damaged")
+		if err := os.WriteFile(refPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write tag: %v", err)
+		}
+		if _, err := repo.ReadTag("damaged"); err == nil {
+			t.Errorf("Expected ReadTag to reject %q", content)
+		}
+		if _, err := repo.ResolveRevision("damaged"); err == nil {
+			t.Errorf("Expected ResolveRevision to reject a tag holding %q", content)
+		}
+	}
+	if err := repo.DeleteTag("damaged"); err != nil {
+		t.Errorf("Expected a damaged tag to be deletable: %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package repo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Template seeds a new repository with a type registry and, for local templates,
+// the .rdbignore and files under assets/ of an existing directory
+type Template struct {
+	Name  string
+	Types []AssetType
+
+	dir    string // local template directory; empty for bundled templates
+	layout Layout // layout the local template's assets/ are stored in
+}
+
+// bundledTemplates are the templates built into rdb
+var bundledTemplates = map[string]func() *Template{
+	"default": func() *Template {
+		return &Template{Name: "default", Types: DefaultTypes()}
+	},
+	"empty": func() *Template {
+		return &Template{Name: "empty", Types: []AssetType{}}
+	},
+}
+
+// BundledTemplates returns the names of the templates built into rdb
+func BundledTemplates() []string {
+	names := make([]string, 0, len(bundledTemplates))
+	for name := range bundledTemplates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadTemplate returns the bundled template with the given name, or reads a local
+// template directory. A local template's registry is taken from .rdb/types.json or
+// types.json, falling back to the built-in types.
+func LoadTemplate(name string) (*Template, error) {
+	if bundled, ok := bundledTemplates[name]; ok {
+		return bundled(), nil
+	}
+
+	dir, err := filepath.Abs(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve template path: %w", err)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("unknown template %s (bundled templates: %s)", name, strings.Join(BundledTemplates(), ", "))
+	}
+
+	tmpl := &Template{Name: filepath.Base(dir), Types: DefaultTypes(), dir: dir, layout: treeLayout{}}
+
+	for _, typesPath := range []string{filepath.Join(dir, ".rdb", TypesFileName), filepath.Join(dir, TypesFileName)} {
+		data, err := os.ReadFile(typesPath)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template types: %w", err)
+		}
+		registry, err := decodeTypes(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template types %s: %w", typesPath, err)
+		}
+		tmpl.Types = registry.Types
+		break
+	}
+
+	// A template that is itself a repository may use the flat layout
+	if data, err := os.ReadFile(filepath.Join(dir, ".rdb", "config.json")); err == nil {
+		var config Config
+		if err := json.Unmarshal(data, &config); err == nil {
+			if layout, err := LayoutByName(config.Core.Layout); err == nil {
+				tmpl.layout = layout
+			}
+		}
+	}
+
+	return tmpl, nil
+}
+
+// seed copies the local template's .rdbignore and assets/ into the repository,
+// converting paths to the repository's layout
+func (t *Template) seed(r *Repository) error {
+	if t.dir == "" {
+		return nil
+	}
+
+	if err := copyFile(filepath.Join(t.dir, IgnoreFileName), filepath.Join(r.Path, IgnoreFileName)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to copy %s: %w", IgnoreFileName, err)
+	}
+
+	assetsPath := filepath.Join(t.dir, "assets")
+	if _, err := os.Stat(assetsPath); os.IsNotExist(err) {
+		return nil
+	}
+
+	layout := r.Layout()
+	return filepath.WalkDir(assetsPath, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(t.dir, p)
+		if err != nil {
+			return err
+		}
+		workPath := layout.WorkPath(t.layout.TreePath(filepath.ToSlash(rel)))
+		target := filepath.Join(r.Path, filepath.FromSlash(workPath))
+
+		if d.IsDir() {
+			// Flat repositories have no per-asset folders; file copies create what is needed
+			if layout.Name() == LayoutTree {
+				return os.MkdirAll(target, 0755)
+			}
+			return nil
+		}
+		if err := copyFile(p, target); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", rel, err)
+		}
+		return nil
+	})
+}
+
+// copyFile copies src to dst, creating dst's parent directories
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,77 @@
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadBundledTemplate(t *testing.T) {
+	tmpl, err := LoadTemplate("empty")
+	if err != nil {
+		t.Fatalf("Failed to load bundled template: %v", err)
+	}
+
+	tempDir := t.TempDir()
+	repo := NewRepository(tempDir)
+	if err := repo.InitFromTemplate(LayoutTree, nil, tmpl); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+
+	registry, err := repo.LoadTypes()
+	if err != nil {
+		t.Fatalf("Failed to load types: %v", err)
+	}
+	if len(registry.Types) != 0 {
+		t.Errorf("Expected empty registry, got %d types", len(registry.Types))
+	}
+
+	if _, err := LoadTemplate(filepath.Join(tempDir, "missing")); err == nil {
+		t.Error("Expected unknown template to fail")
+	}
+}
+
+func TestLocalTemplateSeedsRepository(t *testing.T) {
+	templateDir := t.TempDir()
+	files := map[string]string{
+		"types.json":                 `{"version":1,"types":[{"id":1040001,"slug":"quest_xml","name":"Quest Data","extensions":[".xml"]}]}`,
+		".rdbignore":                 "*.psd\n",
+		"assets/1040001/meta.json":   `{"type":"quest_xml","id":1040001}`,
+		"assets/1040001/start.xml":   "<quest/>",
+		"assets/1040001/empty/.keep": "",
+	}
+	for name, content := range files {
+		p := filepath.Join(templateDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	tmpl, err := LoadTemplate(templateDir)
+	if err != nil {
+		t.Fatalf("Failed to load template: %v", err)
+	}
+
+	tempDir := t.TempDir()
+	repo := NewRepository(tempDir)
+	if err := repo.InitFromTemplate(LayoutFlat, nil, tmpl); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+
+	registry, err := repo.LoadTypes()
+	if err != nil {
+		t.Fatalf("Failed to load types: %v", err)
+	}
+	if len(registry.Types) != 1 || registry.Types[0].Slug != "quest_xml" {
+		t.Errorf("Expected template registry, got %v", registry.Types)
+	}
+
+	for _, name := range []string{".rdbignore", "assets/1040001.meta.json", "assets/1040001.start.xml", "assets/1040001.empty/.keep"} {
+		if _, err := os.Stat(filepath.Join(tempDir, filepath.FromSlash(name))); err != nil {
+			t.Errorf("Expected %s to be seeded: %v", name, err)
+		}
+	}
+}
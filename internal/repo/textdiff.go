@@ -0,0 +1,214 @@
+package repo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DiffContext is the number of unchanged lines shown around each change in a unified diff
+const DiffContext = 3
+
+// maxDiffEdits bounds the edit distance searched for a minimal diff. Memory grows with
+// its square, so beyond it the remaining lines are shown as removed and re-added.
+const maxDiffEdits = 2000
+
+// lineEdit is one step of an edit script: a kept (' '), deleted ('-') or inserted ('+') line
+type lineEdit struct {
+	Op   byte
+	Line string // including its line break, if any
+}
+
+// splitLines splits text into lines, keeping each line's terminating newline
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns an edit script turning a into b
+func diffLines(a, b []string) []lineEdit {
+	// Common leading and trailing lines are kept as they are
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	edits := make([]lineEdit, 0, len(a)+len(b)-prefix-suffix)
+	for _, line := range a[:prefix] {
+		edits = append(edits, lineEdit{Op: ' ', Line: line})
+	}
+	edits = append(edits, myersDiff(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		edits = append(edits, lineEdit{Op: ' ', Line: line})
+	}
+	return edits
+}
+
+// myersDiff computes a shortest edit script with Myers' O(ND) algorithm
+func myersDiff(a, b []string) []lineEdit {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil
+	}
+
+	// v[k+offset] is the furthest x reached on diagonal k; trace keeps the diagonals
+	// -d..d as they were before step d, for backtracking
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+	end := -1
+	for d := 0; d <= n+m && d <= maxDiffEdits; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				end = d
+				break
+			}
+		}
+		if end >= 0 {
+			break
+		}
+	}
+
+	if end < 0 {
+		edits := make([]lineEdit, 0, n+m)
+		for _, line := range a {
+			edits = append(edits, lineEdit{Op: '-', Line: line})
+		}
+		for _, line := range b {
+			edits = append(edits, lineEdit{Op: '+', Line: line})
+		}
+		return edits
+	}
+
+	// Walk back from (n, m), collecting the edits in reverse
+	var reversed []lineEdit
+	x, y := n, m
+	for d := end; d > 0; d-- {
+		get := func(k int) int { return trace[d][k+d] }
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && get(k-1) < get(k+1)) {
+			prevK = k + 1
+		}
+		prevX := get(prevK)
+		prevY := prevX - prevK
+		midX, midY := prevX+1, prevY
+		if prevK == k+1 {
+			midX, midY = prevX, prevY+1
+		}
+		for x > midX && y > midY {
+			reversed = append(reversed, lineEdit{Op: ' ', Line: a[x-1]})
+			x--
+			y--
+		}
+		if prevK == k+1 {
+			reversed = append(reversed, lineEdit{Op: '+', Line: b[y-1]})
+		} else {
+			reversed = append(reversed, lineEdit{Op: '-', Line: a[x-1]})
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 && y > 0 {
+		reversed = append(reversed, lineEdit{Op: ' ', Line: a[x-1]})
+		x--
+		y--
+	}
+
+	edits := make([]lineEdit, len(reversed))
+	for i, edit := range reversed {
+		edits[len(reversed)-1-i] = edit
+	}
+	return edits
+}
+
+// WriteUnifiedDiff writes a unified diff of two texts with DiffContext lines of context.
+// oldName and newName label the two sides, conventionally a/<path>, b/<path> or /dev/null.
+// Nothing is written when the texts are equal.
+func WriteUnifiedDiff(w io.Writer, oldName, newName, oldText, newText string) error {
+	if oldText == newText {
+		return nil
+	}
+	edits := diffLines(splitLines(oldText), splitLines(newText))
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "--- %s\n+++ %s\n", oldName, newName)
+
+	// Line numbers before each edit
+	oldLine := make([]int, len(edits)+1)
+	newLine := make([]int, len(edits)+1)
+	for i, edit := range edits {
+		oldLine[i+1], newLine[i+1] = oldLine[i], newLine[i]
+		if edit.Op != '+' {
+			oldLine[i+1]++
+		}
+		if edit.Op != '-' {
+			newLine[i+1]++
+		}
+	}
+
+	for i := 0; i < len(edits); {
+		if edits[i].Op == ' ' {
+			i++
+			continue
+		}
+
+		// Extend the hunk while the next change is close enough to share context
+		start := max(i-DiffContext, 0)
+		last := i
+		for j := i + 1; j < len(edits) && j <= last+2*DiffContext+1; j++ {
+			if edits[j].Op != ' ' {
+				last = j
+			}
+		}
+		end := min(last+DiffContext+1, len(edits))
+
+		oldStart, oldCount := oldLine[start]+1, oldLine[end]-oldLine[start]
+		newStart, newCount := newLine[start]+1, newLine[end]-newLine[start]
+		if oldCount == 0 {
+			oldStart--
+		}
+		if newCount == 0 {
+			newStart--
+		}
+		fmt.Fprintf(bw, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+		for _, edit := range edits[start:end] {
+			bw.WriteByte(edit.Op)
+			bw.WriteString(edit.Line)
+			if !strings.HasSuffix(edit.Line, "\n") {
+				bw.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = end
+	}
+
+	return bw.Flush()
+}
+
+// hunkRange formats one side of a hunk header, omitting a count of 1
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
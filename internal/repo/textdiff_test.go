@@ -0,0 +1,83 @@
+package repo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     string
+	}{
+		{
+			name: "equal",
+			old:  "a\nb\n",
+			new:  "a\nb\n",
+			want: "",
+		},
+		{
+			name: "change with context",
+			old:  "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			new:  "1\n2\n3\n4\nfive\n6\n7\n8\n9\n",
+			want: "--- a/f\n+++ b/f\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			name: "separate hunks",
+			old:  "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			new:  "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve\n",
+			want: "--- a/f\n+++ b/f\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -9,4 +9,4 @@\n 9\n 10\n 11\n-12\n+twelve\n",
+		},
+		{
+			name: "added file",
+			old:  "",
+			new:  "x\ny\n",
+			want: "--- a/f\n+++ b/f\n@@ -0,0 +1,2 @@\n+x\n+y\n",
+		},
+		{
+			name: "missing newline",
+			old:  "a\nb",
+			new:  "a\nb\n",
+			want: "--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			if err := WriteUnifiedDiff(&out, "a/f", "b/f", tt.old, tt.new); err != nil {
+				t.Fatalf("WriteUnifiedDiff failed: %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("Unexpected diff:\n%s\nwant:\n%s", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffLinesIsMinimal(t *testing.T) {
+	a := splitLines("a\nb\nc\nd\ne\nf\n")
+	b := splitLines("a\nx\nc\ne\nf\ny\n")
+
+	edits := diffLines(a, b)
+	changes := 0
+	var oldText, newText strings.Builder
+	for _, edit := range edits {
+		if edit.Op != ' ' {
+			changes++
+		}
+		if edit.Op != '+' {
+			oldText.WriteString(edit.Line)
+		}
+		if edit.Op != '-' {
+			newText.WriteString(edit.Line)
+		}
+	}
+	if oldText.String() != strings.Join(a, "") || newText.String() != strings.Join(b, "") {
+		t.Errorf("Edit script does not reproduce both sides: %+v", edits)
+	}
+	if changes != 4 {
+		t.Errorf("Expected 4 changed lines, got %d: %+v", changes, edits)
+	}
+}
@@ -0,0 +1,180 @@
+package repo
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// treeNode is an in-memory directory used while building tree objects
+type treeNode struct {
+	dirs  map[string]*treeNode
+	files map[string]IndexEntry
+}
+
+func newTreeNode() *treeNode {
+	return &treeNode{
+		dirs:  make(map[string]*treeNode),
+		files: make(map[string]IndexEntry),
+	}
+}
+
+// BuildTree writes nested tree objects for every staged entry and returns the root tree hash.
+// Directories containing a meta.json are recorded as "asset" entries carrying the asset ID and type.
+// Index paths are converted to canonical tree paths through the repository layout.
+func (r *Repository) BuildTree(idx *Index) (string, error) {
+	root := newTreeNode()
+	layout := r.Layout()
+	for _, entry := range idx.Entries {
+		parts := strings.Split(layout.TreePath(entry.Path), "/")
+		node := root
+		for _, dir := range parts[:len(parts)-1] {
+			child, ok := node.dirs[dir]
+			if !ok {
+				child = newTreeNode()
+				node.dirs[dir] = child
+			}
+			node = child
+		}
+		node.files[parts[len(parts)-1]] = entry
+	}
+
+	hash, _, err := r.writeTreeNode(root)
+	return hash, err
+}
+
+// writeTreeNode writes node and its children, returning the tree hash and total payload size
+func (r *Repository) writeTreeNode(node *treeNode) (string, int64, error) {
+	tree := &Tree{Entries: []TreeEntry{}}
+	var total int64
+
+	for name, entry := range node.files {
+		tree.Entries = append(tree.Entries, TreeEntry{
+			Name:   name,
+			Type:   "blob",
+			Object: entry.Object,
+			Size:   entry.Size,
+		})
+		total += entry.Size
+	}
+
+	for name, child := range node.dirs {
+		hash, size, err := r.writeTreeNode(child)
+		if err != nil {
+			return "", 0, err
+		}
+
+		treeEntry := TreeEntry{
+			Name:   name,
+			Type:   "tree",
+			Object: hash,
+			Size:   size,
+		}
+
+		if meta, ok := child.files[MetaFileName]; ok {
+			asset, err := r.readAssetMetaObject(meta.Object)
+			if err != nil {
+				return "", 0, fmt.Errorf("invalid metadata %s: %w", meta.Path, err)
+			}
+			treeEntry.Type = "asset"
+			treeEntry.AssetID = asset.ID
+			treeEntry.AssetType = asset.Type
+		}
+
+		tree.Entries = append(tree.Entries, treeEntry)
+		total += size
+	}
+
+	sort.Slice(tree.Entries, func(i, j int) bool {
+		return tree.Entries[i].Name < tree.Entries[j].Name
+	})
+
+	hash, err := r.writeObject("tree", tree)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to write tree object: %w", err)
+	}
+
+	return hash, total, nil
+}
+
+// readAssetMetaObject parses a meta.json blob from the object store
+func (r *Repository) readAssetMetaObject(hash string) (*Asset, error) {
+	objType, data, err := r.readObject(hash)
+	if err != nil {
+		return nil, err
+	}
+	if objType != "blob" {
+		return nil, fmt.Errorf("object %s is not a blob", hash)
+	}
+	return ParseAssetMeta(data)
+}
+
+// ReadTree reads a tree object
+func (r *Repository) ReadTree(hash string) (*Tree, error) {
+	objType, data, err := r.readObject(hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tree object: %w", err)
+	}
+
+	if objType != "tree" {
+		return nil, fmt.Errorf("object %s is not a tree", hash)
+	}
+
+	var tree Tree
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tree: %w", err)
+	}
+
+	return &tree, nil
+}
+
+// ReadCommit reads a commit object
+func (r *Repository) ReadCommit(hash string) (*Commit, error) {
+	objType, data, err := r.readObject(hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit object: %w", err)
+	}
+
+	if objType != "commit" {
+		return nil, fmt.Errorf("object %s is not a commit", hash)
+	}
+
+	var commit Commit
+	if err := json.Unmarshal(data, &commit); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal commit: %w", err)
+	}
+
+	return &commit, nil
+}
+
+// FlattenTree returns every blob reachable from the tree, keyed by slash-separated path
+func (r *Repository) FlattenTree(hash string) (map[string]TreeEntry, error) {
+	files := make(map[string]TreeEntry)
+	if err := r.flattenTree(hash, "", files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func (r *Repository) flattenTree(hash, prefix string, files map[string]TreeEntry) error {
+	tree, err := r.ReadTree(hash)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range tree.Entries {
+		entryPath := path.Join(prefix, entry.Name)
+		switch entry.Type {
+		case "tree", "asset":
+			if err := r.flattenTree(entry.Object, entryPath, files); err != nil {
+				return err
+			}
+		default:
+			files[entryPath] = entry
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBuildTreeFromIndex(t *testing.T) {
+	tempDir := t.TempDir()
+	repo := NewRepository(tempDir)
+	if err := repo.Init("tree", []string{"string"}); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+
+	assetDir := filepath.Join(tempDir, "assets", "1030002")
+	if err := os.WriteFile(filepath.Join(assetDir, "en.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(assetDir, MetaFileName), []byte(`{"type":"string","id":1030002}`), 0644); err != nil {
+		t.Fatalf("Failed to write meta.json: %v", err)
+	}
+
+	idx := &Index{Version: IndexVersion}
+	for _, p := range []string{"assets/1030002/en.txt", "assets/1030002/meta.json"} {
+		entry, err := repo.StageFile(p)
+		if err != nil {
+			t.Fatalf("Failed to stage %s: %v", p, err)
+		}
+		idx.Add(entry)
+	}
+
+	treeHash, err := repo.BuildTree(idx)
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+
+	root, err := repo.ReadTree(treeHash)
+	if err != nil {
+		t.Fatalf("Failed to read root tree: %v", err)
+	}
+	if len(root.Entries) != 1 || root.Entries[0].Name != "assets" || root.Entries[0].Type != "tree" {
+		t.Fatalf("Unexpected root entries: %+v", root.Entries)
+	}
+
+	assets, err := repo.ReadTree(root.Entries[0].Object)
+	if err != nil {
+		t.Fatalf("Failed to read assets tree: %v", err)
+	}
+	if len(assets.Entries) != 1 {
+		t.Fatalf("Expected 1 asset entry, got %d", len(assets.Entries))
+	}
+
+	asset := assets.Entries[0]
+	if asset.Type != "asset" || asset.AssetID != 1030002 || asset.AssetType != "string" {
+		t.Errorf("Unexpected asset entry: %+v", asset)
+	}
+
+	files, err := repo.FlattenTree(treeHash)
+	if err != nil {
+		t.Fatalf("Failed to flatten tree: %v", err)
+	}
+	if len(files) != 2 {
+		t.Errorf("Expected 2 files, got %d", len(files))
+	}
+	if files["assets/1030002/en.txt"].Size != 5 {
+		t.Errorf("Expected en.txt size 5, got %d", files["assets/1030002/en.txt"].Size)
+	}
+
+	// Identical content produces an identical tree
+	again, err := repo.BuildTree(idx)
+	if err != nil {
+		t.Fatalf("Failed to rebuild tree: %v", err)
+	}
+	if again != treeHash {
+		t.Error("Expected tree hash to be deterministic")
+	}
+}
+
+func TestBuildTreeEmptyIndexMatchesInitialCommit(t *testing.T) {
+	tempDir := t.TempDir()
+	repo := NewRepository(tempDir)
+	if err := repo.Init("tree", []string{"text"}); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+
+	head, err := repo.GetCurrentCommit()
+	if err != nil {
+		t.Fatalf("Failed to get current commit: %v", err)
+	}
+	commit, err := repo.ReadCommit(head)
+	if err != nil {
+		t.Fatalf("Failed to read commit: %v", err)
+	}
+
+	treeHash, err := repo.BuildTree(&Index{Version: IndexVersion})
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+
+	if treeHash != commit.Tree {
+		t.Errorf("Expected empty index to match initial tree %s, got %s", commit.Tree, treeHash)
+	}
+}
@@ -0,0 +1,310 @@
+package repo
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TypesFileName is the name of the asset type registry inside .rdb
+const TypesFileName = "types.json"
+
+// TypesVersion is the current on-disk format version of .rdb/types.json.
+// Version 2 added key paths.
+const TypesVersion = 2
+
+// UnknownType is the slug used for asset IDs missing from the registry
+const UnknownType = "unknown"
+
+// AssetType describes one registered asset type
+type AssetType struct {
+	ID         int             `json:"id"`
+	Slug       string          `json:"slug"`                 // value written to the "type" field of meta.json
+	Name       string          `json:"name"`                 // display name
+	Extensions []string        `json:"extensions,omitempty"` // allowed payload extensions; empty allows any
+	Schema     json.RawMessage `json:"schema,omitempty"`     // optional JSON Schema for meta.json
+	KeyPath    string          `json:"key_path,omitempty"`   // identifies repeated XML elements in diffs and merges; setting it enables XML handling for any type
+}
+
+// AllowsFile reports whether a payload file with the given name may be stored in an asset of this type.
+// meta.json and .rdbignore are always allowed.
+func (t AssetType) AllowsFile(name string) bool {
+	base := path.Base(filepath.ToSlash(name))
+	if len(t.Extensions) == 0 || base == MetaFileName || base == IgnoreFileName {
+		return true
+	}
+	ext := strings.ToLower(path.Ext(base))
+	for _, allowed := range t.Extensions {
+		if strings.ToLower(allowed) == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// TypeRegistry is the set of asset types known to a repository
+type TypeRegistry struct {
+	Version int         `json:"version"`
+	Types   []AssetType `json:"types"`
+}
+
+// DefaultTypes returns the built-in asset types used when a repository has no registry yet
+func DefaultTypes() []AssetType {
+	return []AssetType{
+		{ID: 1000007, Slug: "physx_xml", Name: "PhysX XML", Extensions: []string{".xml"}, KeyPath: DefaultXMLKeyPath},
+		{ID: 1000010, Slug: "file_index", Name: "File Names Index / FME Files"},
+		{ID: 1000083, Slug: "xml_treasure", Name: "XML Treasure Data", Extensions: []string{".xml"}, KeyPath: DefaultXMLKeyPath},
+		{ID: 1000087, Slug: "xml_zone_transition", Name: "XML Zone Transition Points", Extensions: []string{".xml"}, KeyPath: DefaultXMLKeyPath},
+		{ID: 1000090, Slug: "xml_resurrection", Name: "XML Resurrection Points", Extensions: []string{".xml"}, KeyPath: DefaultXMLKeyPath},
+		{ID: 1000623, Slug: "text", Name: "Misc Text Files"},
+		{ID: 1000624, Slug: "flash_image", Name: "Flash Images"},
+		{ID: 1000635, Slug: "usm_video", Name: "USM Video Files", Extensions: []string{".usm"}},
+		{ID: 1000636, Slug: "image", Name: "Images"},
+		{ID: 1010008, Slug: "misc_image", Name: "Miscellaneous Images"},
+		{ID: 1010013, Slug: "map", Name: "Maps"},
+		{ID: 1010042, Slug: "loading_screen", Name: "Loading Screens"},
+		{ID: 1010207, Slug: "particle_effect", Name: "Particle Effects"},
+		{ID: 1010210, Slug: "image", Name: "Image (no name)"},
+		{ID: 1010211, Slug: "image", Name: "Image (no name)"},
+		{ID: 1020001, Slug: "unknown", Name: "Unknown"},
+		{ID: 1020002, Slug: "sound_effect", Name: "Sound Effects"},
+		{ID: 1020003, Slug: "dialog_audio", Name: "Dialog Audio"},
+		{ID: 1020005, Slug: "music", Name: "Music"},
+		{ID: 1020006, Slug: "sound_tone", Name: "Sounds - Tones"},
+		{ID: 1030002, Slug: "string", Name: "Strings", Extensions: []string{".txt"}},
+		{ID: 1066603, Slug: "texture", Name: "Unknown Textures"},
+		{ID: 1070003, Slug: "playfield", Name: "Playfields"},
+	}
+}
+
+// typesPath returns the location of the asset type registry
+func (r *Repository) typesPath() string {
+	return filepath.Join(r.Path, ".rdb", TypesFileName)
+}
+
+// LoadTypes reads the asset type registry, falling back to the built-in types if none exists yet
+func (r *Repository) LoadTypes() (*TypeRegistry, error) {
+	data, err := os.ReadFile(r.typesPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &TypeRegistry{Version: TypesVersion, Types: DefaultTypes()}, nil
+		}
+		return nil, fmt.Errorf("failed to read type registry: %w", err)
+	}
+
+	return decodeTypes(data)
+}
+
+// decodeTypes parses the content of a types.json file
+func decodeTypes(data []byte) (*TypeRegistry, error) {
+	var reg TypeRegistry
+	if err := json.Unmarshal(data, &reg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal type registry: %w", err)
+	}
+	if reg.Version > TypesVersion {
+		return nil, fmt.Errorf("unsupported type registry version %d", reg.Version)
+	}
+	if reg.Version < 2 {
+		// Registries written before key paths existed still treat the built-in XML types as XML
+		for _, builtin := range DefaultTypes() {
+			for i, t := range reg.Types {
+				if t.ID == builtin.ID && t.Slug == builtin.Slug && t.KeyPath == "" {
+					reg.Types[i].KeyPath = builtin.KeyPath
+				}
+			}
+		}
+	}
+	reg.Version = TypesVersion
+	reg.sort()
+
+	return &reg, nil
+}
+
+// SaveTypes writes the asset type registry to disk
+func (r *Repository) SaveTypes(reg *TypeRegistry) error {
+	reg.sort()
+
+	data, err := json.MarshalIndent(reg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal type registry: %w", err)
+	}
+
+	if err := os.WriteFile(r.typesPath(), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write type registry: %w", err)
+	}
+
+	return nil
+}
+
+// Get returns the type registered under id, if any
+func (reg *TypeRegistry) Get(id int) (AssetType, bool) {
+	for _, t := range reg.Types {
+		if t.ID == id {
+			return t, true
+		}
+	}
+	return AssetType{}, false
+}
+
+// Slug returns the slug registered for id, or UnknownType
+func (reg *TypeRegistry) Slug(id int) string {
+	if t, ok := reg.Get(id); ok {
+		return t.Slug
+	}
+	return UnknownType
+}
+
+// Resolve finds the registered type of an asset: by ID when the slug matches, otherwise by slug
+func (reg *TypeRegistry) Resolve(asset *Asset) (AssetType, bool) {
+	if t, ok := reg.Get(asset.ID); ok && (asset.Type == "" || t.Slug == asset.Type) {
+		return t, true
+	}
+	for _, t := range reg.Types {
+		if t.Slug == asset.Type {
+			return t, true
+		}
+	}
+	return AssetType{}, false
+}
+
+// Search returns the types whose slug or display name contains term, ignoring case
+func (reg *TypeRegistry) Search(term string) []AssetType {
+	term = strings.ToLower(term)
+	var matches []AssetType
+	for _, t := range reg.Types {
+		if strings.Contains(strings.ToLower(t.Name), term) || strings.Contains(strings.ToLower(t.Slug), term) {
+			matches = append(matches, t)
+		}
+	}
+	return matches
+}
+
+// Select returns the types matching any of the tokens by ID, slug or display name,
+// together with the tokens that matched nothing. No tokens, or "all", selects every type.
+func (reg *TypeRegistry) Select(tokens []string) ([]AssetType, []string) {
+	selected := make(map[int]bool)
+	var unmatched []string
+	all := true
+	for _, token := range tokens {
+		token = strings.TrimSpace(token)
+		if token == "" {
+			continue
+		}
+		all = false
+		if token == "all" {
+			all = true
+			break
+		}
+
+		var matches []AssetType
+		if id, err := strconv.Atoi(token); err == nil {
+			if t, ok := reg.Get(id); ok {
+				matches = append(matches, t)
+			}
+		} else {
+			for _, t := range reg.Types {
+				if t.Slug == token {
+					matches = append(matches, t)
+				}
+			}
+			if len(matches) == 0 {
+				matches = reg.Search(token)
+			}
+		}
+
+		if len(matches) == 0 {
+			unmatched = append(unmatched, token)
+		}
+		for _, t := range matches {
+			selected[t.ID] = true
+		}
+	}
+
+	if all {
+		return append([]AssetType(nil), reg.Types...), nil
+	}
+
+	var types []AssetType
+	for _, t := range reg.Types {
+		if selected[t.ID] {
+			types = append(types, t)
+		}
+	}
+	return types, unmatched
+}
+
+// Add registers a new type; the ID must not be registered yet
+func (reg *TypeRegistry) Add(t AssetType) error {
+	if t.ID <= 0 {
+		return fmt.Errorf("invalid type ID %d", t.ID)
+	}
+	if t.Slug == "" || strings.ContainsAny(t.Slug, " \t/\\") {
+		return fmt.Errorf("invalid type slug %q", t.Slug)
+	}
+	if _, ok := reg.Get(t.ID); ok {
+		return fmt.Errorf("type %d is already registered", t.ID)
+	}
+	if len(t.Schema) > 0 && !json.Valid(t.Schema) {
+		return fmt.Errorf("schema for type %d is not valid JSON", t.ID)
+	}
+	if err := ValidateXMLKeyPath(t.KeyPath); err != nil {
+		return err
+	}
+
+	var exts []string
+	for _, ext := range t.Extensions {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		exts = append(exts, ext)
+	}
+	t.Extensions = exts
+	if t.Name == "" {
+		t.Name = t.Slug
+	}
+
+	reg.Types = append(reg.Types, t)
+	reg.sort()
+	return nil
+}
+
+// SetKeyPath changes the XML key path of a registered type; an empty path stops
+// its payloads from being diffed and merged as XML
+func (reg *TypeRegistry) SetKeyPath(id int, keyPath string) error {
+	if err := ValidateXMLKeyPath(keyPath); err != nil {
+		return err
+	}
+	for i, t := range reg.Types {
+		if t.ID == id {
+			reg.Types[i].KeyPath = keyPath
+			return nil
+		}
+	}
+	return fmt.Errorf("type %d is not registered", id)
+}
+
+// Remove unregisters the type with the given ID and reports whether it was present
+func (reg *TypeRegistry) Remove(id int) bool {
+	for i, t := range reg.Types {
+		if t.ID == id {
+			reg.Types = append(reg.Types[:i], reg.Types[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (reg *TypeRegistry) sort() {
+	sort.Slice(reg.Types, func(i, j int) bool {
+		return reg.Types[i].ID < reg.Types[j].ID
+	})
+}
@@ -0,0 +1,109 @@
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadTypesDefaults(t *testing.T) {
+	tempDir := t.TempDir()
+	repo := NewRepository(tempDir)
+	if err := os.MkdirAll(filepath.Join(tempDir, ".rdb"), 0755); err != nil {
+		t.Fatalf("Failed to create .rdb: %v", err)
+	}
+
+	registry, err := repo.LoadTypes()
+	if err != nil {
+		t.Fatalf("Failed to load types: %v", err)
+	}
+	if len(registry.Types) != len(DefaultTypes()) {
+		t.Errorf("Expected %d built-in types, got %d", len(DefaultTypes()), len(registry.Types))
+	}
+	if slug := registry.Slug(1030002); slug != "string" {
+		t.Errorf("Expected slug 'string' for 1030002, got %q", slug)
+	}
+	if slug := registry.Slug(42); slug != UnknownType {
+		t.Errorf("Expected %q for unregistered ID, got %q", UnknownType, slug)
+	}
+}
+
+func TestTypeRegistryRoundTrip(t *testing.T) {
+	tempDir := t.TempDir()
+	repo := NewRepository(tempDir)
+	if err := repo.Init("tree", []string{"text"}); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+
+	registry, err := repo.LoadTypes()
+	if err != nil {
+		t.Fatalf("Failed to load types: %v", err)
+	}
+
+	quest := AssetType{ID: 1040001, Slug: "quest_xml", Extensions: []string{"XML", " .json "}}
+	if err := registry.Add(quest); err != nil {
+		t.Fatalf("Failed to add type: %v", err)
+	}
+	if err := registry.Add(quest); err == nil {
+		t.Error("Expected duplicate ID to be rejected")
+	}
+	if err := registry.Add(AssetType{ID: 1040002, Slug: "bad slug"}); err == nil {
+		t.Error("Expected invalid slug to be rejected")
+	}
+	if err := registry.Add(AssetType{ID: 1040003, Slug: "broken", Schema: []byte("{")}); err == nil {
+		t.Error("Expected invalid schema to be rejected")
+	}
+	if !registry.Remove(1010008) {
+		t.Error("Expected built-in type 1010008 to be removed")
+	}
+	if err := repo.SaveTypes(registry); err != nil {
+		t.Fatalf("Failed to save types: %v", err)
+	}
+
+	loaded, err := repo.LoadTypes()
+	if err != nil {
+		t.Fatalf("Failed to reload types: %v", err)
+	}
+	got, ok := loaded.Get(1040001)
+	if !ok {
+		t.Fatal("Expected 1040001 to be registered after reload")
+	}
+	if got.Name != "quest_xml" {
+		t.Errorf("Expected name to default to slug, got %q", got.Name)
+	}
+	if len(got.Extensions) != 2 || got.Extensions[0] != ".xml" || got.Extensions[1] != ".json" {
+		t.Errorf("Expected normalized extensions, got %v", got.Extensions)
+	}
+	if _, ok := loaded.Get(1010008); ok {
+		t.Error("Expected 1010008 to stay removed after reload")
+	}
+	if matches := loaded.Search("QUEST"); len(matches) != 1 || matches[0].ID != 1040001 {
+		t.Errorf("Expected search to find quest_xml, got %v", matches)
+	}
+
+	for name, allowed := range map[string]bool{
+		"assets/1040001/a.xml":      true,
+		"assets/1040001/B.XML":      true,
+		"assets/1040001/a.txt":      false,
+		"assets/1040001/meta.json":  true,
+		"assets/1040001/.rdbignore": true,
+	} {
+		if got.AllowsFile(name) != allowed {
+			t.Errorf("AllowsFile(%q) = %v, want %v", name, !allowed, allowed)
+		}
+	}
+}
+
+func TestTypeRegistryResolve(t *testing.T) {
+	registry := &TypeRegistry{Version: TypesVersion, Types: DefaultTypes()}
+
+	if got, ok := registry.Resolve(&Asset{Type: "image", ID: 1010210}); !ok || got.ID != 1010210 {
+		t.Errorf("Expected asset to resolve by ID, got %v, %v", got, ok)
+	}
+	if got, ok := registry.Resolve(&Asset{Type: "string", ID: 42}); !ok || got.ID != 1030002 {
+		t.Errorf("Expected asset to resolve by slug, got %v, %v", got, ok)
+	}
+	if _, ok := registry.Resolve(&Asset{Type: "custom", ID: 42}); ok {
+		t.Error("Expected unregistered asset not to resolve")
+	}
+}
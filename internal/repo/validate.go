@@ -0,0 +1,183 @@
+package repo
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// baseMetaSchema describes the fields every meta.json must satisfy, whatever its type
+const baseMetaSchema = `{
+  "type": "object",
+  "required": ["type", "id"],
+  "properties": {
+    "type": {"type": "string", "minLength": 1},
+    "id": {"type": "integer", "minimum": 1},
+    "name": {"type": "string"},
+    "status": {"enum": ["draft"]},
+    "tags": {"type": "array", "items": {"type": "string"}},
+    "version": {"type": "integer", "minimum": 0},
+    "attributes": {"type": "object"},
+    "dependencies": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["type", "id"],
+        "properties": {
+          "type": {"type": "string"},
+          "id": {"type": "integer"}
+        }
+      }
+    },
+    "paths": {"type": "array"},
+    "etag": {"type": "string"}
+  }
+}`
+
+// ValidationError is a metadata problem located by file and line
+type ValidationError struct {
+	Path    string `json:"path"`              // working-tree path of the meta.json
+	Line    int    `json:"line,omitempty"`    // 1-based line, 0 if unknown
+	Pointer string `json:"pointer,omitempty"` // JSON pointer of the offending value
+	Message string `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	location := e.Path
+	if e.Line > 0 {
+		location += ":" + strconv.Itoa(e.Line)
+	}
+	if e.Pointer != "" {
+		return fmt.Sprintf("%s: %s: %s", location, e.Pointer, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", location, e.Message)
+}
+
+// ValidationErrors is returned when one or more meta.json files fail validation
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	lines := make([]string, len(e))
+	for i, err := range e {
+		lines[i] = err.Error()
+	}
+	return fmt.Sprintf("invalid metadata:\n  %s", strings.Join(lines, "\n  "))
+}
+
+// ValidateAssetMeta checks the content of the meta.json at the working-tree path p
+// against the base metadata schema and the schema of its registered type, if any
+func (r *Repository) ValidateAssetMeta(registry *TypeRegistry, p string, data []byte) []ValidationError {
+	data = bytes.TrimPrefix(data, utf8BOM)
+
+	var doc interface{}
+	if err := decodeJSON(data, &doc); err != nil {
+		return []ValidationError{{Path: p, Line: jsonErrorLine(data, err), Message: err.Error()}}
+	}
+
+	base, err := CompileSchema([]byte(baseMetaSchema))
+	if err != nil {
+		return []ValidationError{{Path: p, Message: err.Error()}}
+	}
+	violations := base.Validate(doc)
+
+	if len(violations) == 0 {
+		asset, err := ParseAssetMeta(data)
+		if err != nil {
+			return []ValidationError{{Path: p, Line: jsonErrorLine(data, err), Message: err.Error()}}
+		}
+		if assetType, ok := registry.Resolve(asset); ok && len(assetType.Schema) > 0 {
+			schema, err := CompileSchema(assetType.Schema)
+			if err != nil {
+				return []ValidationError{{Path: p, Message: fmt.Sprintf("schema of type %s (%d): %v", assetType.Slug, assetType.ID, err)}}
+			}
+			violations = schema.Validate(doc)
+		}
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+
+	lines := jsonLines(data)
+	errs := make([]ValidationError, 0, len(violations))
+	for _, v := range violations {
+		errs = append(errs, ValidationError{Path: p, Line: lines[v.Pointer], Pointer: v.Pointer, Message: v.Message})
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].Line < errs[j].Line
+	})
+	return errs
+}
+
+// ValidateIndex validates every staged meta.json
+func (r *Repository) ValidateIndex(idx *Index) ([]ValidationError, error) {
+	registry, err := r.LoadTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []ValidationError
+	for _, entry := range idx.Entries {
+		if !r.IsMetaPath(entry.Path) || entry.Conflict != nil {
+			continue
+		}
+		_, data, err := r.readObject(entry.Object)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", entry.Path, err)
+		}
+		errs = append(errs, r.ValidateAssetMeta(registry, entry.Path, data)...)
+	}
+	return errs, nil
+}
+
+// ValidateWorkingTree validates every meta.json under assets/ that is not ignored
+func (r *Repository) ValidateWorkingTree() ([]ValidationError, error) {
+	registry, err := r.LoadTypes()
+	if err != nil {
+		return nil, err
+	}
+	ignore := r.NewIgnoreMatcher()
+
+	var paths []string
+	assetsPath := filepath.Join(r.Path, "assets")
+	err = filepath.WalkDir(assetsPath, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		relPath, err := r.RelPath(p)
+		if err != nil {
+			return err
+		}
+		if p != assetsPath && ignore.Match(relPath, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() && r.IsMetaPath(relPath) {
+			paths = append(paths, relPath)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan working tree: %w", err)
+	}
+	sort.Strings(paths)
+
+	var errs []ValidationError
+	for _, relPath := range paths {
+		data, err := os.ReadFile(filepath.Join(r.Path, filepath.FromSlash(relPath)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", relPath, err)
+		}
+		errs = append(errs, r.ValidateAssetMeta(registry, relPath, data)...)
+	}
+	return errs, nil
+}
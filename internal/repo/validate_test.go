@@ -0,0 +1,96 @@
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateAssetMeta(t *testing.T) {
+	tempDir := t.TempDir()
+	repo := NewRepository(tempDir)
+	if err := repo.Init("tree", []string{"string"}); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+
+	registry, err := repo.LoadTypes()
+	if err != nil {
+		t.Fatalf("Failed to load types: %v", err)
+	}
+	quest := AssetType{
+		ID:     1040001,
+		Slug:   "quest_xml",
+		Schema: []byte(`{"required": ["name"], "properties": {"name": {"pattern": "^Q_"}}}`),
+	}
+	if err := registry.Add(quest); err != nil {
+		t.Fatalf("Failed to add type: %v", err)
+	}
+
+	tests := []struct {
+		doc   string
+		lines []int
+	}{
+		{"{\"type\": \"quest_xml\", \"id\": 1040001, \"name\": \"Q_intro\"}", nil},
+		{"{\n  \"type\": \"quest_xml\",\n  \"id\": 1040001,\n  \"name\": \"intro\"\n}", []int{4}},
+		{"{\n  \"type\": \"quest_xml\",\n  \"id\": 1040001\n}", []int{1}},
+		{"{\n  \"type\": \"string\",\n  \"id\": \"1030002\",\n  \"tags\": [1]\n}", []int{3, 4}},
+		{"{\n  \"type\": \"string\",\n  \"id\": 1030002,\n}", []int{4}},
+		{"{\"type\": \"custom\", \"id\": 42, \"status\": \"done\"}", []int{1}},
+	}
+
+	for _, tt := range tests {
+		errs := repo.ValidateAssetMeta(registry, "assets/x/meta.json", []byte(tt.doc))
+		if len(errs) != len(tt.lines) {
+			t.Errorf("ValidateAssetMeta(%q) = %v, want errors on lines %v", tt.doc, errs, tt.lines)
+			continue
+		}
+		for i, e := range errs {
+			if e.Line != tt.lines[i] || e.Path != "assets/x/meta.json" {
+				t.Errorf("ValidateAssetMeta(%q) error %d = %v, want line %d", tt.doc, i, e, tt.lines[i])
+			}
+		}
+	}
+}
+
+func TestValidateIndexAndWorkingTree(t *testing.T) {
+	tempDir := t.TempDir()
+	repo := NewRepository(tempDir)
+	if err := repo.Init("tree", []string{"string"}); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+
+	commitFiles(t, repo, map[string]string{
+		"assets/1030002/meta.json": `{"type":"string","id":1030002}`,
+		"assets/1030002/en.txt":    "hello",
+	}, "first")
+
+	metaPath := filepath.Join(tempDir, "assets", "1030002", MetaFileName)
+	if err := os.WriteFile(metaPath, []byte(`{"type":"string"}`), 0644); err != nil {
+		t.Fatalf("Failed to write meta.json: %v", err)
+	}
+
+	errs, err := repo.ValidateWorkingTree()
+	if err != nil {
+		t.Fatalf("Failed to validate working tree: %v", err)
+	}
+	if len(errs) != 1 || errs[0].Path != "assets/1030002/meta.json" {
+		t.Errorf("Expected one working-tree error, got %v", errs)
+	}
+
+	idx, err := repo.LoadIndex()
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+	if errs, err := repo.ValidateIndex(idx); err != nil || len(errs) != 0 {
+		t.Errorf("Expected staged metadata to be valid, got %v, %v", errs, err)
+	}
+
+	entry, err := repo.StageFile("assets/1030002/meta.json")
+	if err != nil {
+		t.Fatalf("Failed to stage meta.json: %v", err)
+	}
+	idx.Add(entry)
+	if errs, err := repo.ValidateIndex(idx); err != nil || len(errs) != 1 {
+		t.Errorf("Expected one staged error, got %v, %v", errs, err)
+	}
+}
@@ -0,0 +1,564 @@
+package repo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// DefaultXMLKeyPath is the key path of the built-in XML asset types
+const DefaultXMLKeyPath = "@id"
+
+// xmlCommentName is the name given to comment nodes
+const xmlCommentName = "#comment"
+
+// xmlNode is an element with its whitespace and attribute order normalized:
+// attributes are sorted by name and character data is trimmed and collapsed.
+// Comments are nodes named xmlCommentName holding their text.
+type xmlNode struct {
+	Name     string
+	Attrs    []xml.Attr // sorted by name; Name.Local holds the qualified name
+	Text     string
+	Children []*xmlNode
+}
+
+// xmlDocument is a parsed XML payload
+type xmlDocument struct {
+	Prolog  []string // declaration, directives and comments before the root element, verbatim
+	Root    *xmlNode
+	Indent  string // indentation unit of the original file
+	Newline string
+}
+
+// xmlQualifiedName returns a name with its namespace prefix, if any
+func xmlQualifiedName(name xml.Name) string {
+	if name.Space != "" {
+		return name.Space + ":" + name.Local
+	}
+	return name.Local
+}
+
+// parseXMLDocument parses and normalizes an XML payload. Namespace prefixes are kept
+// as written; content after the root element other than whitespace is an error.
+func parseXMLDocument(data []byte) (*xmlDocument, error) {
+	data = bytes.TrimPrefix(data, utf8BOM)
+	doc := &xmlDocument{Indent: "  ", Newline: "\n"}
+	if bytes.Contains(data, []byte("\r\n")) {
+		doc.Newline = "\r\n"
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	// Legacy encodings are compared byte for byte rather than rejected
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	var stack []*xmlNode
+	var text []string // character data of each open element
+	indentFound := false
+	for {
+		tok, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			node := &xmlNode{Name: xmlQualifiedName(t.Name)}
+			for _, attr := range t.Attr {
+				node.Attrs = append(node.Attrs, xml.Attr{Name: xml.Name{Local: xmlQualifiedName(attr.Name)}, Value: attr.Value})
+			}
+			sort.Slice(node.Attrs, func(i, j int) bool { return node.Attrs[i].Name.Local < node.Attrs[j].Name.Local })
+			if len(stack) == 0 {
+				if doc.Root != nil {
+					return nil, fmt.Errorf("unexpected second root element <%s>", node.Name)
+				}
+				doc.Root = node
+			} else {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, node)
+			}
+			stack = append(stack, node)
+			text = append(text, "")
+		case xml.EndElement:
+			name := xmlQualifiedName(t.Name)
+			if len(stack) == 0 || stack[len(stack)-1].Name != name {
+				return nil, fmt.Errorf("unexpected end element </%s>", name)
+			}
+			stack[len(stack)-1].Text = strings.Join(strings.Fields(text[len(text)-1]), " ")
+			stack = stack[:len(stack)-1]
+			text = text[:len(text)-1]
+		case xml.CharData:
+			if len(stack) == 0 {
+				if len(bytes.TrimSpace(t)) > 0 {
+					return nil, fmt.Errorf("unexpected text outside the root element")
+				}
+				continue
+			}
+			text[len(text)-1] += string(t)
+			// The whitespace before the root's first child is one level of indentation
+			if !indentFound && len(stack) == 1 && len(bytes.TrimSpace(t)) == 0 {
+				if i := bytes.LastIndexByte(t, '\n'); i >= 0 && i < len(t)-1 {
+					doc.Indent = string(t[i+1:])
+					indentFound = true
+				}
+			}
+		case xml.Comment:
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, &xmlNode{Name: xmlCommentName, Text: strings.TrimSpace(string(t))})
+			} else if doc.Root == nil {
+				doc.Prolog = append(doc.Prolog, "<!--"+string(t)+"-->")
+			}
+		case xml.ProcInst:
+			if doc.Root == nil {
+				doc.Prolog = append(doc.Prolog, "<?"+t.Target+" "+string(t.Inst)+"?>")
+			}
+		case xml.Directive:
+			if doc.Root == nil {
+				doc.Prolog = append(doc.Prolog, "<!"+string(t)+">")
+			}
+		}
+	}
+
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("unexpected end of document inside <%s>", stack[len(stack)-1].Name)
+	}
+	if doc.Root == nil {
+		return nil, fmt.Errorf("no root element")
+	}
+	return doc, nil
+}
+
+// attr returns the value of an attribute
+func (n *xmlNode) attr(name string) (string, bool) {
+	for _, attr := range n.Attrs {
+		if attr.Name.Local == name {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+// child returns the first child element with the given name
+func (n *xmlNode) child(name string) *xmlNode {
+	for _, child := range n.Children {
+		if child.Name == name {
+			return child
+		}
+	}
+	return nil
+}
+
+// xmlKeyPaths splits a comma-separated list of key paths
+func xmlKeyPaths(spec string) []string {
+	var paths []string
+	for _, p := range strings.Split(spec, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// ValidateXMLKeyPath checks a comma-separated list of key paths. Each path names child
+// elements separated by slashes and may end in an attribute, as in "@id" or "Key/@value";
+// a path ending in an element selects that element's text.
+func ValidateXMLKeyPath(spec string) error {
+	for _, p := range xmlKeyPaths(spec) {
+		segments := strings.Split(p, "/")
+		for i, segment := range segments {
+			name := strings.TrimPrefix(segment, "@")
+			if name == "" || strings.ContainsAny(name, " \t@[]=\"'") || (name != segment && i != len(segments)-1) {
+				return fmt.Errorf("invalid XML key path %q", p)
+			}
+		}
+	}
+	return nil
+}
+
+// key evaluates the key paths in order and returns the first one that selects a value
+func (n *xmlNode) key(paths []string) (string, string, bool) {
+	for _, p := range paths {
+		node := n
+		segments := strings.Split(p, "/")
+		for i, segment := range segments {
+			if strings.HasPrefix(segment, "@") {
+				if value, ok := node.attr(segment[1:]); ok && i == len(segments)-1 {
+					return p, value, true
+				}
+				break
+			}
+			if node = node.child(segment); node == nil {
+				break
+			}
+			if i == len(segments)-1 {
+				return p, node.Text, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// xmlIdentities names each child so that it can be matched with its counterpart in
+// another version: by key, as in Treasure[@id="12"], or, for elements without a key,
+// by their position among siblings of the same name, as in Point or Point[2]
+func xmlIdentities(children []*xmlNode, paths []string) []string {
+	ids := make([]string, len(children))
+	seen := make(map[string]int)
+	for i, child := range children {
+		id := child.Name
+		if child.Name != xmlCommentName {
+			if p, value, ok := child.key(paths); ok {
+				id = fmt.Sprintf("%s[%s=%q]", child.Name, p, value)
+			}
+		}
+		seen[id]++
+		if n := seen[id]; n > 1 {
+			id = fmt.Sprintf("%s[%d]", id, n)
+		}
+		ids[i] = id
+	}
+	return ids
+}
+
+// xmlIndex maps child identities to children
+func xmlIndex(children []*xmlNode, ids []string) map[string]*xmlNode {
+	index := make(map[string]*xmlNode, len(children))
+	for i, child := range children {
+		index[ids[i]] = child
+	}
+	return index
+}
+
+var (
+	xmlTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	xmlAttrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", "\"", "&quot;", "\n", "&#xA;", "\r", "&#xD;", "\t", "&#x9;")
+)
+
+// writeXMLNode serializes a node. With an empty indent everything is written on one line.
+func writeXMLNode(w *bufio.Writer, n *xmlNode, depth int, indent, newline string) {
+	if indent != "" {
+		w.WriteString(strings.Repeat(indent, depth))
+	}
+	if n.Name == xmlCommentName {
+		w.WriteString("<!-- " + n.Text + " -->")
+		if indent != "" {
+			w.WriteString(newline)
+		}
+		return
+	}
+
+	w.WriteString("<" + n.Name)
+	for _, attr := range n.Attrs {
+		w.WriteString(" " + attr.Name.Local + "=\"" + xmlAttrEscaper.Replace(attr.Value) + "\"")
+	}
+	switch {
+	case n.Text == "" && len(n.Children) == 0:
+		w.WriteString("/>")
+	case len(n.Children) == 0:
+		w.WriteString(">" + xmlTextEscaper.Replace(n.Text) + "</" + n.Name + ">")
+	default:
+		w.WriteString(">")
+		if indent != "" {
+			w.WriteString(newline)
+			if n.Text != "" {
+				w.WriteString(strings.Repeat(indent, depth+1))
+				w.WriteString(xmlTextEscaper.Replace(n.Text) + newline)
+			}
+		} else {
+			w.WriteString(xmlTextEscaper.Replace(n.Text))
+		}
+		for _, child := range n.Children {
+			writeXMLNode(w, child, depth+1, indent, newline)
+		}
+		if indent != "" {
+			w.WriteString(strings.Repeat(indent, depth))
+		}
+		w.WriteString("</" + n.Name + ">")
+	}
+	if indent != "" {
+		w.WriteString(newline)
+	}
+}
+
+// compact returns the node serialized on a single line
+func (n *xmlNode) compact() string {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	writeXMLNode(w, n, 0, "", "")
+	w.Flush()
+	return buf.String()
+}
+
+// encode serializes the document with its original prolog, indentation and line endings.
+// Mixed content is written as the element's text followed by its children.
+func (doc *xmlDocument) encode() []byte {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	for _, line := range doc.Prolog {
+		w.WriteString(line + doc.Newline)
+	}
+	writeXMLNode(w, doc.Root, 0, doc.Indent, doc.Newline)
+	w.Flush()
+	return buf.Bytes()
+}
+
+// xmlChange is one difference between two XML documents
+type xmlChange struct {
+	Op   byte   // '+' added, '-' removed, '~' changed
+	Path string // element path, with /@name for attributes and /text() for text
+	Old  string
+	New  string
+}
+
+// diffXMLNodes collects the differences between two versions of an element. Children
+// are matched by identity, so reordering keyed elements is not a change.
+func diffXMLNodes(p string, old, new *xmlNode, paths []string, changes *[]xmlChange) {
+	names := make(map[string]bool)
+	for _, attr := range old.Attrs {
+		names[attr.Name.Local] = true
+	}
+	for _, attr := range new.Attrs {
+		names[attr.Name.Local] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	for _, name := range sorted {
+		ov, inOld := old.attr(name)
+		nv, inNew := new.attr(name)
+		switch {
+		case !inOld:
+			*changes = append(*changes, xmlChange{Op: '+', Path: p + "/@" + name, New: nv})
+		case !inNew:
+			*changes = append(*changes, xmlChange{Op: '-', Path: p + "/@" + name, Old: ov})
+		case ov != nv:
+			*changes = append(*changes, xmlChange{Op: '~', Path: p + "/@" + name, Old: ov, New: nv})
+		}
+	}
+	if old.Text != new.Text {
+		*changes = append(*changes, xmlChange{Op: '~', Path: p + "/text()", Old: old.Text, New: new.Text})
+	}
+
+	oldIDs := xmlIdentities(old.Children, paths)
+	newIDs := xmlIdentities(new.Children, paths)
+	oldIndex := xmlIndex(old.Children, oldIDs)
+	newIndex := xmlIndex(new.Children, newIDs)
+	for i, child := range new.Children {
+		childPath := p + "/" + newIDs[i]
+		if before, ok := oldIndex[newIDs[i]]; ok {
+			diffXMLNodes(childPath, before, child, paths, changes)
+		} else {
+			*changes = append(*changes, xmlChange{Op: '+', Path: childPath, New: child.compact()})
+		}
+	}
+	for i, child := range old.Children {
+		if _, ok := newIndex[oldIDs[i]]; !ok {
+			*changes = append(*changes, xmlChange{Op: '-', Path: p + "/" + oldIDs[i], Old: child.compact()})
+		}
+	}
+}
+
+// RenderXMLDiff renders an XML payload as a list of changed elements, attributes and
+// text, matching repeated elements by the key path of the asset's type. Whitespace and
+// attribute order are ignored. Added and deleted files and files that do not parse are
+// shown as text diffs.
+func RenderXMLDiff(w io.Writer, r *Repository, asset *AssetDiff, file FileDiff) error {
+	if !file.Old.Exists() || !file.New.Exists() {
+		return RenderTextDiff(w, r, asset, file)
+	}
+	oldData, err := r.ReadDiffFile(file.Old)
+	if err != nil {
+		return err
+	}
+	newData, err := r.ReadDiffFile(file.New)
+	if err != nil {
+		return err
+	}
+	oldDoc, err := parseXMLDocument(oldData)
+	if err != nil {
+		return RenderTextDiff(w, r, asset, file)
+	}
+	newDoc, err := parseXMLDocument(newData)
+	if err != nil {
+		return RenderTextDiff(w, r, asset, file)
+	}
+
+	var changes []xmlChange
+	if oldDoc.Root.Name != newDoc.Root.Name {
+		changes = append(changes, xmlChange{Op: '~', Path: "/", Old: "<" + oldDoc.Root.Name + ">", New: "<" + newDoc.Root.Name + ">"})
+	} else {
+		paths := xmlKeyPaths(asset.KeyPath)
+		diffXMLNodes("/"+newDoc.Root.Name, oldDoc.Root, newDoc.Root, paths, &changes)
+	}
+
+	bw := bufio.NewWriter(w)
+	oldName, newName := diffNames(file)
+	fmt.Fprintf(bw, "--- %s\n+++ %s\n", oldName, newName)
+	if len(changes) == 0 {
+		fmt.Fprintln(bw, "  (formatting only)")
+	}
+	for _, change := range changes {
+		switch change.Op {
+		case '+':
+			fmt.Fprintf(bw, "+ %s: %s\n", change.Path, truncateXML(change.New))
+		case '-':
+			fmt.Fprintf(bw, "- %s: %s\n", change.Path, truncateXML(change.Old))
+		default:
+			fmt.Fprintf(bw, "~ %s: %q -> %q\n", change.Path, change.Old, change.New)
+		}
+	}
+	return bw.Flush()
+}
+
+// truncateXML shortens a serialized element for display
+func truncateXML(s string) string {
+	const limit = 120
+	if len(s) <= limit {
+		return s
+	}
+	return s[:limit-3] + "..."
+}
+
+// MergeXML is the merge driver for XML payloads. Elements are matched by the key path
+// of the asset type, so both sides may add, remove and edit different elements; their
+// attributes and text are merged individually. Elements without a key are matched by
+// position among siblings of the same name. The result keeps our element order, with
+// their additions placed after the sibling they follow on their side, and is written
+// with our prolog, indentation and line endings. Conflicting values keep ours.
+func MergeXML(assetType AssetType, base, ours, theirs []byte) ([]byte, bool, error) {
+	oursDoc, err := parseXMLDocument(ours)
+	if err != nil {
+		return nil, false, err
+	}
+	theirsDoc, err := parseXMLDocument(theirs)
+	if err != nil {
+		return nil, false, err
+	}
+	if oursDoc.Root.Name != theirsDoc.Root.Name {
+		return ours, false, nil
+	}
+
+	// Content added on both sides is merged against an empty base
+	baseRoot := &xmlNode{Name: oursDoc.Root.Name}
+	if base != nil {
+		baseDoc, err := parseXMLDocument(base)
+		if err != nil {
+			return nil, false, err
+		}
+		if baseDoc.Root.Name == baseRoot.Name {
+			baseRoot = baseDoc.Root
+		}
+	}
+
+	root, conflicts := mergeXMLNodes("/"+baseRoot.Name, baseRoot, oursDoc.Root, theirsDoc.Root, xmlKeyPaths(assetType.KeyPath))
+	oursDoc.Root = root
+	return oursDoc.encode(), len(conflicts) == 0, nil
+}
+
+// mergeXMLNodes merges three versions of an element and returns the paths of conflicts
+func mergeXMLNodes(p string, base, ours, theirs *xmlNode, paths []string) (*xmlNode, []string) {
+	merged := &xmlNode{Name: ours.Name}
+	var conflicts []string
+
+	// optional turns a possibly absent string into a value for mergeValue
+	optional := func(value string, ok bool) interface{} {
+		if !ok {
+			return nil
+		}
+		return value
+	}
+
+	names := make(map[string]bool)
+	for _, n := range []*xmlNode{base, ours, theirs} {
+		for _, attr := range n.Attrs {
+			names[attr.Name.Local] = true
+		}
+	}
+	for name := range names {
+		value, ok := mergeValue(optional(base.attr(name)), optional(ours.attr(name)), optional(theirs.attr(name)))
+		if !ok {
+			conflicts = append(conflicts, p+"/@"+name)
+		}
+		if value != nil {
+			merged.Attrs = append(merged.Attrs, xml.Attr{Name: xml.Name{Local: name}, Value: value.(string)})
+		}
+	}
+	sort.Slice(merged.Attrs, func(i, j int) bool { return merged.Attrs[i].Name.Local < merged.Attrs[j].Name.Local })
+
+	text, ok := mergeValue(base.Text, ours.Text, theirs.Text)
+	if !ok {
+		conflicts = append(conflicts, p+"/text()")
+	}
+	merged.Text = text.(string)
+
+	baseIDs := xmlIdentities(base.Children, paths)
+	oursIDs := xmlIdentities(ours.Children, paths)
+	theirsIDs := xmlIdentities(theirs.Children, paths)
+	baseIndex := xmlIndex(base.Children, baseIDs)
+	oursIndex := xmlIndex(ours.Children, oursIDs)
+	theirsIndex := xmlIndex(theirs.Children, theirsIDs)
+
+	// Our children first, in our order
+	var mergedIDs []string
+	for i, child := range ours.Children {
+		id := oursIDs[i]
+		before, inBase := baseIndex[id]
+		other, inTheirs := theirsIndex[id]
+		switch {
+		case inTheirs:
+			if !inBase {
+				before = &xmlNode{Name: child.Name}
+			}
+			mergedChild, childConflicts := mergeXMLNodes(p+"/"+id, before, child, other, paths)
+			conflicts = append(conflicts, childConflicts...)
+			child = mergedChild
+		case !inBase:
+			// Added on our side
+		case child.compact() == before.compact():
+			// Deleted on their side and unchanged on ours
+			continue
+		default:
+			conflicts = append(conflicts, p+"/"+id)
+		}
+		merged.Children = append(merged.Children, child)
+		mergedIDs = append(mergedIDs, id)
+	}
+
+	// Then their additions, each after the sibling it follows on their side
+	insertAt := 0
+	for i, child := range theirs.Children {
+		id := theirsIDs[i]
+		if _, inOurs := oursIndex[id]; inOurs {
+			for j, mergedID := range mergedIDs {
+				if mergedID == id {
+					insertAt = j + 1
+				}
+			}
+			continue
+		}
+		if before, inBase := baseIndex[id]; inBase {
+			if child.compact() != before.compact() {
+				// Changed on their side but deleted on ours
+				conflicts = append(conflicts, p+"/"+id)
+			}
+			continue
+		}
+		merged.Children = append(merged.Children[:insertAt], append([]*xmlNode{child}, merged.Children[insertAt:]...)...)
+		mergedIDs = append(mergedIDs[:insertAt], append([]string{id}, mergedIDs[insertAt:]...)...)
+		insertAt++
+	}
+
+	sort.Strings(conflicts)
+	return merged, conflicts
+}
@@ -0,0 +1,240 @@
+package repo
+
+import (
+	"strings"
+	"testing"
+)
+
+const treasureBase = `<?xml version="1.0" encoding="utf-8"?>
+<Treasures>
+    <Treasure id="1" item="sword" count="1"/>
+    <Treasure id="2" item="shield" count="1"/>
+    <Treasure id="3" item="potion" count="5">
+        <Note>Starter kit</Note>
+    </Treasure>
+</Treasures>
+`
+
+func TestRenderXMLDiffIgnoresFormatting(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+	first := commitFiles(t, repo, map[string]string{"assets/1000083/loot.xml": treasureBase}, "first")
+
+	// Reordered attributes, re-indented and reordered elements, one real change each
+	second := commitFiles(t, repo, map[string]string{"assets/1000083/loot.xml": `<?xml version="1.0" encoding="utf-8"?>
+<Treasures>
+  <Treasure count="5" id="3" item="potion"><Note>
+    Starter   kit
+  </Note></Treasure>
+  <Treasure count="2" item="sword" id="1"/>
+  <Treasure id="4" item="bow" count="1"/>
+</Treasures>
+`}, "second")
+
+	diffs, err := repo.DiffSnapshots(commitSnapshot(t, repo, first), commitSnapshot(t, repo, second), nil)
+	if err != nil || len(diffs) != 1 {
+		t.Fatalf("Unexpected diff: %+v (%v)", diffs, err)
+	}
+	out := renderDiff(t, repo, diffs[0])
+	want := `--- a/assets/1000083/loot.xml
++++ b/assets/1000083/loot.xml
+~ /Treasures/Treasure[@id="1"]/@count: "1" -> "2"
++ /Treasures/Treasure[@id="4"]: <Treasure count="1" id="4" item="bow"/>
+- /Treasures/Treasure[@id="2"]: <Treasure count="1" id="2" item="shield"/>
+`
+	if out != want {
+		t.Errorf("Unexpected XML diff:\n%s\nwant:\n%s", out, want)
+	}
+
+	// Formatting alone is not a change
+	third := commitFiles(t, repo, map[string]string{"assets/1000083/loot.xml": strings.ReplaceAll(treasureBase, "    ", "\t")}, "third")
+	diffs, _ = repo.DiffSnapshots(commitSnapshot(t, repo, first), commitSnapshot(t, repo, third), nil)
+	if out := renderDiff(t, repo, diffs[0]); !strings.Contains(out, "(formatting only)") {
+		t.Errorf("Expected a formatting-only diff, got:\n%s", out)
+	}
+}
+
+func TestMergeXML(t *testing.T) {
+	treasureType := AssetType{KeyPath: DefaultXMLKeyPath}
+	ours := strings.Replace(treasureBase, `<Treasure id="1" item="sword" count="1"/>`, `<Treasure id="1" item="sword" count="3"/>`, 1)
+	ours = strings.Replace(ours, `    <Treasure id="2" item="shield" count="1"/>`+"\n", "", 1)
+	theirs := strings.Replace(treasureBase, `item="potion"`, `item="elixir"`, 1)
+	theirs = strings.Replace(theirs, `<Treasure id="1" item="sword" count="1"/>`, `<Treasure id="1" item="sword" count="1"/>`+"\n"+`    <Treasure id="7" item="bow" count="1"/>`, 1)
+
+	merged, clean, err := MergeXML(treasureType, []byte(treasureBase), []byte(ours), []byte(theirs))
+	if err != nil {
+		t.Fatalf("MergeXML failed: %v", err)
+	}
+	if !clean {
+		t.Error("Expected a clean merge")
+	}
+	want := `<?xml version="1.0" encoding="utf-8"?>
+<Treasures>
+    <Treasure count="3" id="1" item="sword"/>
+    <Treasure count="1" id="7" item="bow"/>
+    <Treasure count="5" id="3" item="elixir">
+        <Note>Starter kit</Note>
+    </Treasure>
+</Treasures>
+`
+	if string(merged) != want {
+		t.Errorf("Unexpected merge:\n%s\nwant:\n%s", merged, want)
+	}
+
+	// Both sides change the same attribute
+	theirs = strings.Replace(treasureBase, `count="1"`, `count="9"`, 1)
+	merged, clean, err = MergeXML(treasureType, []byte(treasureBase), []byte(ours), []byte(theirs))
+	if err != nil || clean {
+		t.Fatalf("Expected a conflict, got clean=%v (%v)", clean, err)
+	}
+	if !strings.Contains(string(merged), `count="3" id="1"`) {
+		t.Errorf("Expected the conflicting value to keep ours:\n%s", merged)
+	}
+
+	// They edit an element we deleted
+	theirs = strings.Replace(treasureBase, `item="shield"`, `item="buckler"`, 1)
+	if _, clean, _ := MergeXML(treasureType, []byte(treasureBase), []byte(ours), []byte(theirs)); clean {
+		t.Error("Expected a modify/delete conflict")
+	}
+
+	if _, _, err := MergeXML(treasureType, []byte(treasureBase), []byte("<Treasures>"), []byte(theirs)); err == nil {
+		t.Error("Expected an error for malformed XML")
+	}
+}
+
+func TestMergeXMLKeyPath(t *testing.T) {
+	base := "<Zones>\r\n\t<Zone><Key name=\"harbor\"/><Target>1</Target></Zone>\r\n\t<Zone><Key name=\"forest\"/><Target>2</Target></Zone>\r\n</Zones>\r\n"
+	// Ours reorders the zones, theirs retargets one of them
+	ours := "<Zones>\r\n\t<Zone><Key name=\"forest\"/><Target>2</Target></Zone>\r\n\t<Zone><Key name=\"harbor\"/><Target>1</Target></Zone>\r\n</Zones>\r\n"
+	theirs := strings.Replace(base, "<Target>2</Target>", "<Target>5</Target>", 1)
+
+	zoneType := AssetType{KeyPath: "@id, Key/@name"}
+	merged, clean, err := MergeXML(zoneType, []byte(base), []byte(ours), []byte(theirs))
+	if err != nil || !clean {
+		t.Fatalf("Expected a clean merge, got clean=%v (%v)", clean, err)
+	}
+	want := "<Zones>\r\n\t<Zone>\r\n\t\t<Key name=\"forest\"/>\r\n\t\t<Target>5</Target>\r\n\t</Zone>\r\n\t<Zone>\r\n\t\t<Key name=\"harbor\"/>\r\n\t\t<Target>1</Target>\r\n\t</Zone>\r\n</Zones>\r\n"
+	if string(merged) != want {
+		t.Errorf("Unexpected merge:\n%q\nwant:\n%q", merged, want)
+	}
+
+	// Matched by position, the reorder and the retarget collide
+	if _, clean, _ := MergeXML(AssetType{}, []byte(base), []byte(ours), []byte(theirs)); clean {
+		t.Error("Expected keyless elements to be matched by position")
+	}
+
+	for spec, valid := range map[string]bool{"": true, "@id": true, "@id,Key/@name": true, "Name": true, "@a/b": false, "Key[1]": false, "a//b": false} {
+		if err := ValidateXMLKeyPath(spec); (err == nil) != valid {
+			t.Errorf("ValidateXMLKeyPath(%q) = %v, want valid=%v", spec, err, valid)
+		}
+	}
+}
+
+func TestMergeTreesUsesXMLDriver(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+	base := commitFiles(t, repo, map[string]string{
+		"assets/1000083/meta.json": `{"type":"xml_treasure","id":1000083}`,
+		"assets/1000083/loot.xml":  treasureBase,
+	}, "base")
+	if err := repo.CreateBranch("event", base); err != nil {
+		t.Fatalf("Failed to create branch: %v", err)
+	}
+	ours := commitFiles(t, repo, map[string]string{
+		"assets/1000083/loot.xml": strings.Replace(treasureBase, `item="sword"`, `item="axe"`, 1),
+	}, "ours")
+	if err := repo.SetHead("event", RefChange{Command: "switch"}); err != nil {
+		t.Fatalf("Failed to switch HEAD: %v", err)
+	}
+	theirs := commitFiles(t, repo, map[string]string{
+		"assets/1000083/loot.xml": strings.Replace(treasureBase, `item="shield"`, `item="buckler"`, 1),
+	}, "theirs")
+
+	baseCommit, _ := repo.ReadCommit(base)
+	oursCommit, _ := repo.ReadCommit(ours)
+	theirsCommit, _ := repo.ReadCommit(theirs)
+	result, err := repo.MergeTrees(baseCommit.Tree, oursCommit.Tree, theirsCommit.Tree)
+	if err != nil {
+		t.Fatalf("Failed to merge trees: %v", err)
+	}
+	if len(result.Conflicts) != 0 || len(result.Merged) != 1 || result.Merged[0] != "assets/1000083/loot.xml" {
+		t.Fatalf("Expected loot.xml to be auto-merged, got %+v", result)
+	}
+	data, err := repo.ReadDiffFile(DiffFile{Object: result.Files["assets/1000083/loot.xml"].Object})
+	if err != nil {
+		t.Fatalf("Failed to read merged file: %v", err)
+	}
+	if !strings.Contains(string(data), `item="axe"`) || !strings.Contains(string(data), `item="buckler"`) {
+		t.Errorf("Expected both changes in the merged file:\n%s", data)
+	}
+}
+
+func TestKeyPathEnablesXMLForCustomTypes(t *testing.T) {
+	repo := NewRepository(t.TempDir())
+	if err := repo.Init(LayoutTree, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+	reg, _ := repo.LoadTypes()
+	if err := reg.Add(AssetType{ID: 1040003, Slug: "loot_xml", KeyPath: "@name"}); err != nil {
+		t.Fatalf("Failed to add type: %v", err)
+	}
+	if err := reg.Add(AssetType{ID: 1040004, Slug: "bad_xml", KeyPath: "@a/b"}); err == nil {
+		t.Error("Expected an invalid key path to be rejected")
+	}
+	if err := repo.SaveTypes(reg); err != nil {
+		t.Fatalf("Failed to save types: %v", err)
+	}
+
+	first := commitFiles(t, repo, map[string]string{"assets/1040003/loot.xml": `<Loot><Drop name="gold" rate="1"/><Drop name="gem" rate="2"/></Loot>`}, "first")
+	second := commitFiles(t, repo, map[string]string{"assets/1040003/loot.xml": `<Loot><Drop rate="3" name="gem"/><Drop name="gold" rate="1"/></Loot>`}, "second")
+
+	diffs, err := repo.DiffSnapshots(commitSnapshot(t, repo, first), commitSnapshot(t, repo, second), nil)
+	if err != nil || len(diffs) != 1 {
+		t.Fatalf("Unexpected diff: %+v (%v)", diffs, err)
+	}
+	if out := renderDiff(t, repo, diffs[0]); !strings.Contains(out, `~ /Loot/Drop[@name="gem"]/@rate: "2" -> "3"`) {
+		t.Errorf("Expected an XML diff keyed on @name, got:\n%s", out)
+	}
+
+	// Clearing the key path turns XML handling off, also for a built-in type
+	reg, _ = repo.LoadTypes()
+	if err := reg.SetKeyPath(1040003, "Key[1]"); err == nil {
+		t.Error("Expected an invalid key path to be rejected")
+	}
+	if err := reg.SetKeyPath(1049999, "@id"); err == nil {
+		t.Error("Expected an unregistered type to be rejected")
+	}
+	for _, id := range []int{1040003, 1000083} {
+		if err := reg.SetKeyPath(id, ""); err != nil {
+			t.Fatalf("Failed to clear key path: %v", err)
+		}
+	}
+	repo.SaveTypes(reg)
+	diffs, _ = repo.DiffSnapshots(commitSnapshot(t, repo, first), commitSnapshot(t, repo, second), nil)
+	if out := renderDiff(t, repo, diffs[0]); strings.Contains(out, "/Loot/Drop") {
+		t.Errorf("Expected a text diff without a key path, got:\n%s", out)
+	}
+	if _, _, ok := repo.mergeDriverFor("assets/1000083/loot.xml", nil, nil, nil); ok {
+		t.Error("Expected no XML driver for a type without a key path")
+	}
+}
+
+func TestTypesV1GainBuiltinKeyPaths(t *testing.T) {
+	reg, err := decodeTypes([]byte(`{"version":1,"types":[{"id":1000083,"slug":"xml_treasure","name":"XML Treasure Data"},{"id":1000087,"slug":"custom_zones","name":"Zones"}]}`))
+	if err != nil {
+		t.Fatalf("Failed to decode types: %v", err)
+	}
+	if reg.Version != TypesVersion {
+		t.Errorf("Expected version %d, got %d", TypesVersion, reg.Version)
+	}
+	if treasure, _ := reg.Get(1000083); treasure.KeyPath != DefaultXMLKeyPath {
+		t.Errorf("Expected the built-in treasure type to get key path %q, got %q", DefaultXMLKeyPath, treasure.KeyPath)
+	}
+	if zones, _ := reg.Get(1000087); zones.KeyPath != "" {
+		t.Errorf("Expected a redefined type to keep no key path, got %q", zones.KeyPath)
+	}
+}